@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"time"
+)
+
+// ABArm collects the games one of the two compared strategies played.
+type ABArm struct {
+	Strategy string    `json:"strategy"`
+	Games    int       `json:"games"`
+	Wins     int       `json:"wins"`
+	Draws    int       `json:"draws"`
+	Losses   int       `json:"losses"`
+	Margins  []float64 `json:"margins"`
+}
+
+// score_margin is how far ahead of the best opponent the team finished.
+func score_margin(scores Scores, team string) float64 {
+	best := math.Inf(-1)
+	for name, score := range scores {
+		if name != team {
+			best = math.Max(best, float64(score))
+		}
+	}
+	if math.IsInf(best, -1) {
+		return 0
+	}
+	return float64(scores[team]) - best
+}
+
+func (a *ABArm) Add(scores Scores, team string) {
+	a.Games++
+	switch game_outcome(scores, team) {
+	case "win":
+		a.Wins++
+	case "draw":
+		a.Draws++
+	default:
+		a.Losses++
+	}
+	a.Margins = append(a.Margins, score_margin(scores, team))
+}
+
+// WinRate counts draws as half a win.
+func (a ABArm) WinRate() float64 {
+	if a.Games == 0 {
+		return 0
+	}
+	return (float64(a.Wins) + 0.5*float64(a.Draws)) / float64(a.Games)
+}
+
+func mean_variance(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(values)-1)
+}
+
+// incomplete_beta is the regularized incomplete beta function I_x(a, b),
+// evaluated with its continued fraction.
+func incomplete_beta(a float64, b float64, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	if x > (a+1)/(a+b+2) {
+		return 1 - incomplete_beta(b, a, 1-x)
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab-la-lb+a*math.Log(x)+b*math.Log(1-x)) / a
+	const tiny = 1e-30
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	result := d
+	for m := 1; m <= 200; m++ {
+		fm := float64(m)
+		for _, numerator := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + numerator*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + numerator/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			result *= d * c
+		}
+		if math.Abs(d*c-1) < 1e-12 {
+			break
+		}
+	}
+	return front * result
+}
+
+// student_t_p is the two-sided p-value of t under df degrees of freedom.
+func student_t_p(t float64, df float64) float64 {
+	return incomplete_beta(df/2, 0.5, df/(df+t*t))
+}
+
+// student_t_quantile inverts student_t_p by bisection.
+func student_t_quantile(p float64, df float64) float64 {
+	low, high := 0.0, 1000.0
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if student_t_p(mid, df) > p {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// ABComparison is B measured against A. Positive differences favour B.
+type ABComparison struct {
+	MarginDiff float64 `json:"margin_diff"`
+	MarginLow  float64 `json:"margin_low"`
+	MarginHigh float64 `json:"margin_high"`
+	MarginP    float64 `json:"margin_p"`
+	WinDiff    float64 `json:"win_diff"`
+	WinP       float64 `json:"win_p"`
+}
+
+// compare_arms runs Welch's t-test on the score margins and a two
+// proportion z-test on the win rates, with a 1-alpha confidence interval
+// for the margin difference.
+func compare_arms(a ABArm, b ABArm, alpha float64) (ABComparison, error) {
+	if a.Games < 2 || b.Games < 2 {
+		return ABComparison{}, errors.New("both strategies need at least two games")
+	}
+	c := ABComparison{WinDiff: b.WinRate() - a.WinRate(), MarginP: 1, WinP: 1}
+	mean_a, var_a := mean_variance(a.Margins)
+	mean_b, var_b := mean_variance(b.Margins)
+	c.MarginDiff = mean_b - mean_a
+	se_a, se_b := var_a/float64(a.Games), var_b/float64(b.Games)
+	if se := math.Sqrt(se_a + se_b); se > 0 {
+		df := (se_a + se_b) * (se_a + se_b) / (se_a*se_a/float64(a.Games-1) + se_b*se_b/float64(b.Games-1))
+		c.MarginP = student_t_p(c.MarginDiff/se, df)
+		half := student_t_quantile(alpha, df) * se
+		c.MarginLow, c.MarginHigh = c.MarginDiff-half, c.MarginDiff+half
+	} else {
+		c.MarginLow, c.MarginHigh = c.MarginDiff, c.MarginDiff
+		if c.MarginDiff != 0 {
+			c.MarginP = 0
+		}
+	}
+	pooled := (a.WinRate()*float64(a.Games) + b.WinRate()*float64(b.Games)) / float64(a.Games+b.Games)
+	if se := math.Sqrt(pooled * (1 - pooled) * (1/float64(a.Games) + 1/float64(b.Games))); se > 0 {
+		c.WinP = math.Erfc(math.Abs(c.WinDiff/se) / math.Sqrt2)
+	}
+	return c, nil
+}
+
+type ABReport struct {
+	Started    time.Time    `json:"started"`
+	Ended      time.Time    `json:"ended"`
+	Alpha      float64      `json:"alpha"`
+	A          ABArm        `json:"a"`
+	B          ABArm        `json:"b"`
+	Comparison ABComparison `json:"comparison"`
+}
+
+func print_ab_report(r ABReport) {
+	fmt.Printf("%-20s %5s %5s %5s %5s %8s %8s\n", "strategy", "games", "wins", "draws", "loss", "winrate", "margin")
+	for _, arm := range []ABArm{r.A, r.B} {
+		mean, _ := mean_variance(arm.Margins)
+		fmt.Printf("%-20s %5d %5d %5d %5d %7.1f%% %8.2f\n", arm.Strategy, arm.Games, arm.Wins, arm.Draws, arm.Losses, 100*arm.WinRate(), mean)
+	}
+	c := r.Comparison
+	fmt.Printf("\nmargin of %s over %s: %+.2f (%.0f%% interval %+.2f to %+.2f), p=%.3f\n",
+		r.B.Strategy, r.A.Strategy, c.MarginDiff, 100*(1-r.Alpha), c.MarginLow, c.MarginHigh, c.MarginP)
+	fmt.Printf("win rate of %s over %s: %+.1f points, p=%.3f\n", r.B.Strategy, r.A.Strategy, 100*c.WinDiff, c.WinP)
+	switch {
+	case c.MarginP < r.Alpha && c.MarginDiff > 0:
+		fmt.Printf("%s plays significantly better\n", r.B.Strategy)
+	case c.MarginP < r.Alpha:
+		fmt.Printf("%s plays significantly better\n", r.A.Strategy)
+	default:
+		fmt.Printf("no significant difference at %.0f%%, play more games\n", 100*r.Alpha)
+	}
+}
+
+// ab_command alternates two strategies game by game, or compares them over
+// the games already in the results ledger, and tests whether one of them
+// plays better. Games joined late are played but not counted.
+func ab_command(args []string) {
+	flags := flag.NewFlagSet("ab", flag.ExitOnError)
+	strategy_a := flags.String("a", "greedy", "baseline strategy")
+	strategy_b := flags.String("b", "utility", "strategy compared against the baseline")
+	games := flags.Int("games", 10, "complete games to play with each strategy")
+	alpha := flags.Float64("alpha", 0.05, "significance level of the tests")
+	stats_dir := flags.String("stats-dir", "stats", "directory for per-game stats, the results ledger and the comparison report")
+	stats_format := flags.String("stats-format", "json,csv", "comma separated list of stats formats (json, csv)")
+	from_ledger := flags.Bool("ledger", false, "compare the games of both strategies in the results ledger instead of playing")
+	seed := add_seed_flag(flags)
+	add_connection_flags(flags)
+	config_flags := add_config_flags(flags)
+	flags.Parse(args)
+	Random.Seed(*seed)
+	logf(VerbosityNormal, "random seed %d", Random.Current())
+	if err := config_flags.Apply(); err != nil {
+		log.Fatalln(err)
+	}
+
+	report := ABReport{Started: time.Now(), Alpha: *alpha, A: ABArm{Strategy: *strategy_a}, B: ABArm{Strategy: *strategy_b}}
+	if *from_ledger {
+		results, err := read_results(*stats_dir)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, result := range results {
+			switch result.Strategy {
+			case *strategy_a:
+				report.A.Add(result.Scores, result.Team)
+			case *strategy_b:
+				report.B.Add(result.Scores, result.Team)
+			}
+		}
+	} else {
+		normalize_server_url()
+		if err := resolve_password(); err != nil {
+			log.Fatalln(err)
+		}
+		rotation, err := lookup_strategies(*strategy_a + "," + *strategy_b)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		arms := []*ABArm{&report.A, &report.B}
+		next := 0
+		options := BotOptions{StatsDir: *stats_dir, StatsFormat: *stats_format}
+		play(rotation[next], options, func(stats *GameStats) (Strategy, bool) {
+			if stats.Partial() {
+				log.Printf("game joined at tick %d, not counted", stats.FirstTick)
+				return rotation[next], true
+			}
+			arms[next].Add(stats.FinalScores, stats.Team)
+			log.Printf("%s game %d/%d finished: %s", arms[next].Strategy, arms[next].Games, *games, game_outcome(stats.FinalScores, stats.Team))
+			if report.A.Games >= *games && report.B.Games >= *games {
+				return nil, false
+			}
+			next = 1 - next
+			return rotation[next], true
+		})
+	}
+	report.Ended = time.Now()
+	comparison, err := compare_arms(report.A, report.B, *alpha)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	report.Comparison = comparison
+	print_ab_report(report)
+	if !*from_ledger && *stats_dir != "" {
+		if err := os.MkdirAll(*stats_dir, 0755); err == nil {
+			err = write_json_report(report, *stats_dir, "ab", report.Ended)
+		}
+		if err != nil {
+			log.Printf("writing comparison report failed: %v", err)
+		}
+	}
+}
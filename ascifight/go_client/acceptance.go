@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// AcceptanceMonitor follows the share of our orders the server accepts. All
+// orders pass validate_orders before they are sent, so rejections are rare,
+// and a sharp drop usually means our model of the rules no longer matches
+// the server's.
+//
+// The rate of the last Window ticks is compared with a baseline that follows
+// the earlier ticks slowly. An alert is raised once the recent rate falls
+// Drop below the baseline and cleared when it recovers halfway.
+type AcceptanceMonitor struct {
+	Window int
+	Drop   float64
+	// MinOrders is how many orders the window needs before it can alert,
+	// so that a single rejected order of a quiet tick goes unnoticed.
+	MinOrders int
+	// OnAlert is called with the message of every alert and recovery.
+	OnAlert func(message string)
+
+	submitted []int
+	rejected  []int
+	baseline  float64
+	observed  int
+	alerting  bool
+	alerts    int
+}
+
+// baseline_weight is how much every tick leaving the window moves the
+// baseline.
+const baseline_weight = 0.05
+
+func new_acceptance_monitor(window int, drop float64) *AcceptanceMonitor {
+	return &AcceptanceMonitor{Window: window, Drop: drop, MinOrders: 5}
+}
+
+// Observe adds the orders of a tick.
+func (m *AcceptanceMonitor) Observe(tick int, submitted int, rejected int) {
+	if submitted == 0 || m.Window <= 0 {
+		return
+	}
+	m.submitted = append(m.submitted, submitted)
+	m.rejected = append(m.rejected, rejected)
+	if len(m.submitted) > m.Window {
+		// the oldest tick leaves the window for the baseline, unless it is
+		// part of the drop we are alerting on
+		if !m.alerting {
+			rate := 1 - float64(m.rejected[0])/float64(m.submitted[0])
+			if m.observed == 0 {
+				m.baseline = rate
+			} else {
+				m.baseline += baseline_weight * (rate - m.baseline)
+			}
+			m.observed++
+		}
+		m.submitted, m.rejected = m.submitted[1:], m.rejected[1:]
+	}
+	if m.observed < m.Window {
+		return
+	}
+	rate, orders := m.Rate()
+	switch {
+	case !m.alerting && orders >= m.MinOrders && rate < m.baseline-m.Drop:
+		m.alerting = true
+		m.alerts++
+		m.alert(fmt.Sprintf("order acceptance dropped to %.0f%% over the last %d ticks at tick %d, from %.0f%% before; the rules of the server may differ from ours",
+			rate*100, len(m.submitted), tick, m.baseline*100))
+	case m.alerting && rate >= m.baseline-m.Drop/2:
+		m.alerting = false
+		m.alert(fmt.Sprintf("order acceptance recovered to %.0f%% at tick %d", rate*100, tick))
+	}
+}
+
+func (m *AcceptanceMonitor) alert(message string) {
+	log.Printf("warning: %s", message)
+	if m.OnAlert != nil {
+		m.OnAlert(message)
+	}
+}
+
+// Rate is the share of accepted orders in the window and the number of
+// orders it is based on.
+func (m *AcceptanceMonitor) Rate() (float64, int) {
+	submitted, rejected := 0, 0
+	for i := range m.submitted {
+		submitted += m.submitted[i]
+		rejected += m.rejected[i]
+	}
+	if submitted == 0 {
+		return 1, 0
+	}
+	return 1 - float64(rejected)/float64(submitted), submitted
+}
+
+// Baseline is the rate the window is compared with, false while there are
+// too few ticks for one.
+func (m *AcceptanceMonitor) Baseline() (float64, bool) {
+	return m.baseline, m.observed >= m.Window
+}
+
+func (m *AcceptanceMonitor) Alerting() bool { return m.alerting }
+
+func (m *AcceptanceMonitor) Alerts() int { return m.alerts }
+
+// Reset forgets the game, the next one may be played with other rules.
+func (m *AcceptanceMonitor) Reset() {
+	m.submitted, m.rejected = nil, nil
+	m.baseline, m.observed, m.alerting = 0, 0, false
+}
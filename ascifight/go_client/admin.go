@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// AdminAction is a game control route for organizers. Arguments given on
+// the command line are sent as the named query parameters.
+type AdminAction struct {
+	Path        string
+	Params      []string
+	Description string
+}
+
+// admin_actions are the routes the admin subcommand knows. Servers are not
+// required to offer them, so the OpenAPI description of the server is
+// checked before any of them is called.
+var admin_actions = map[string]AdminAction{
+	"reset":  {"/admin/reset", nil, "end the running game and start a new one"},
+	"pause":  {"/admin/pause", nil, "stop resolving ticks"},
+	"resume": {"/admin/resume", nil, "resolve ticks again after a pause"},
+	"seed":   {"/admin/seed", []string{"seed"}, "set the random seed of the next game"},
+}
+
+// server_routes are the paths the server's OpenAPI description lists.
+func server_routes() (map[string]bool, error) {
+	var data []byte
+	if err := api_request(context.Background(), "GET", "openapi.json", nil, nil, false, &data); err != nil {
+		return nil, fmt.Errorf("querying the server routes: %w", err)
+	}
+	var info ServerInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("decoding the server routes: %w", err)
+	}
+	routes := make(map[string]bool, len(info.Paths))
+	for path := range info.Paths {
+		routes[path] = true
+	}
+	return routes, nil
+}
+
+// admin_routes lists the paths under /admin the server describes.
+func admin_routes() ([]string, error) {
+	all, err := server_routes()
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]string, 0)
+	for path := range all {
+		if strings.HasPrefix(path, "/admin/") {
+			routes = append(routes, path)
+		}
+	}
+	sort.Strings(routes)
+	return routes, nil
+}
+
+func admin_usage(flags *flag.FlagSet) {
+	fmt.Fprintln(flags.Output(), "usage: admin [flags] <action> [arguments]\n\nactions:")
+	names := make([]string, 0, len(admin_actions))
+	for name := range admin_actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(flags.Output(), "  %-20s %s\n", "routes", "list the admin routes the server offers")
+	for _, name := range names {
+		action := admin_actions[name]
+		usage := name
+		for _, param := range action.Params {
+			usage += " <" + param + ">"
+		}
+		fmt.Fprintf(flags.Output(), "  %-20s %s\n", usage, action.Description)
+	}
+	fmt.Fprintln(flags.Output(), "\nflags:")
+	flags.PrintDefaults()
+}
+
+func admin_command(args []string) {
+	flags := flag.NewFlagSet("admin", flag.ExitOnError)
+	flags.StringVar(&ServerUrl, "server", ServerUrl, "base url of the game server")
+	flags.StringVar(&Team, "user", "admin", "name of the admin account")
+	flags.StringVar(&Password, "password", os.Getenv("ASCIFIGHT_ADMIN_PASSWORD"), "password of the admin account, defaults to $ASCIFIGHT_ADMIN_PASSWORD")
+	flags.Usage = func() { admin_usage(flags) }
+	flags.Parse(args)
+	normalize_server_url()
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	routes, err := admin_routes()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	name := flags.Arg(0)
+	if name == "routes" {
+		if len(routes) == 0 {
+			fmt.Println("the server offers no admin routes")
+		}
+		for _, route := range routes {
+			fmt.Println(route)
+		}
+		return
+	}
+	action, ok := admin_actions[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown admin action %q\n", name)
+		flags.Usage()
+		os.Exit(2)
+	}
+	offered := false
+	for _, route := range routes {
+		offered = offered || route == action.Path
+	}
+	if !offered {
+		fmt.Fprintf(os.Stderr, "the server at %s does not offer %s\n", Servers.Active(), action.Path)
+		os.Exit(1)
+	}
+	if flags.NArg()-1 != len(action.Params) {
+		fmt.Fprintf(os.Stderr, "%s expects %d arguments: %s\n", name, len(action.Params), strings.Join(action.Params, " "))
+		os.Exit(2)
+	}
+	query := url.Values{}
+	for i, param := range action.Params {
+		query.Set(param, flags.Arg(i+1))
+	}
+	var response []byte
+	if err := api_request(context.Background(), "POST", strings.TrimPrefix(action.Path, "/"), query, nil, true, &response); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(strings.TrimSpace(string(response)))
+}
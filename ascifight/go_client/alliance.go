@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// alliance_decay is how much of its fighting against the leader a team
+	// is remembered for from one tick to the next.
+	alliance_decay = 0.9
+	// alliance_reach is how far our attackers go out of their way to stop a
+	// carrier of the leader.
+	alliance_reach = 6
+)
+
+// AllianceBoard keeps soft alliances in games of three or more teams: the
+// teams that fight the current leader are worth leaving alone, and the
+// captures of the leader are worth denying even when it is not our flag it
+// carries. Nothing is agreed with the other teams, it is only how our
+// actors pick their fights. Params.UtilityAlliance weighs it, 0 leaves the
+// game a free-for-all.
+type AllianceBoard struct {
+	mutex  sync.Mutex
+	tick   int
+	leader string
+	// hostility is how much each other enemy team fought the leader
+	// lately, from 0 to 1.
+	hostility map[string]float64
+}
+
+func new_alliance_board() *AllianceBoard {
+	return &AllianceBoard{hostility: make(map[string]float64)}
+}
+
+// Alliances are observed with every state.
+var Alliances = new_alliance_board()
+
+// Observe finds the leader of state and how much every other enemy team
+// fights it: carries its flag, stands next to its actors or closer to its
+// bases than to its own.
+func (b *AllianceBoard) Observe(state GameState) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if state.Tick < b.tick {
+		b.leader, b.hostility = "", make(map[string]float64)
+	}
+	b.tick = state.Tick
+	leader := ""
+	best := 0.5
+	for team, rival := range assess_rivals(state) {
+		// level teams have no leader
+		if rival.Standing > best {
+			leader, best = team, rival.Standing
+		}
+	}
+	if leader != b.leader {
+		if leader != "" {
+			logf(VerbosityNormal, "%s leads from tick %d", leader, state.Tick)
+		}
+		b.leader, b.hostility = leader, make(map[string]float64)
+	}
+	if leader == "" {
+		return
+	}
+	leaders := make([]Actor, 0)
+	for _, actor := range state.Actors {
+		if actor.Team == leader {
+			leaders = append(leaders, actor)
+		}
+	}
+	for _, team := range state.Teams {
+		if team == Team || team == leader {
+			continue
+		}
+		engaged := 0.0
+		for _, actor := range state.Actors {
+			if actor.Team != team {
+				continue
+			}
+			home, has_home := closest_base(state.Bases, team, actor.Coordinates)
+			theirs, has_theirs := closest_base(state.Bases, leader, actor.Coordinates)
+			fighting := actor.Flag == leader || (has_home && has_theirs && distance(actor.Coordinates, theirs.Coordinates) < distance(actor.Coordinates, home.Coordinates))
+			for _, other := range leaders {
+				fighting = fighting || distance(actor.Coordinates, other.Coordinates) <= 2
+			}
+			if fighting {
+				engaged = 1
+			}
+		}
+		b.hostility[team] = alliance_decay*b.hostility[team] + (1-alliance_decay)*engaged
+	}
+}
+
+func (b *AllianceBoard) Leader() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.leader
+}
+
+// Ally is how much team fought the leader lately, 0 without a leader or
+// for the leader itself.
+func (b *AllianceBoard) Ally(team string) float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.hostility[team]
+}
+
+// leader_carriers are the actors of the leader carrying a flag towards a
+// capture.
+func leader_carriers(state GameState) []Actor {
+	carriers := make([]Actor, 0)
+	if leader := Alliances.Leader(); leader != "" && Params.UtilityAlliance > 0 {
+		for _, actor := range state.Actors {
+			if actor.Team == leader && actor.Flag != "" {
+				carriers = append(carriers, actor)
+			}
+		}
+	}
+	return carriers
+}
+
+// must_deny_leader sends the attacker of ours closest to a carrier of the
+// leader after it, while it is within alliance_reach.
+func must_deny_leader(ctx *BehaviorContext) bool {
+	_, ok := leader_to_deny(ctx)
+	return ok
+}
+
+func leader_to_deny(ctx *BehaviorContext) (Actor, bool) {
+	if capabilities(ctx.Actor).Attack == 0 {
+		return Actor{}, false
+	}
+	for _, carrier := range leader_carriers(ctx.State) {
+		d := distance(ctx.Actor.Coordinates, carrier.Coordinates)
+		if d > alliance_reach {
+			continue
+		}
+		closest := true
+		for _, other := range ctx.MyActors {
+			if other.Ident != ctx.Actor.Ident && other.Flag == "" && capabilities(other).Attack > 0 && distance(other.Coordinates, carrier.Coordinates) < d {
+				closest = false
+			}
+		}
+		if closest {
+			return carrier, true
+		}
+	}
+	return Actor{}, false
+}
+
+func deny_leader_action(ctx *BehaviorContext) Status {
+	carrier, ok := leader_to_deny(ctx)
+	if !ok {
+		return Failure
+	}
+	why := fmt.Sprintf("denying the capture of leading %s actor %d", carrier.Team, carrier.Ident)
+	ctx.Orders = seek_target(ctx.Actor, carrier, "attack", why, ctx.Orders)
+	return Success
+}
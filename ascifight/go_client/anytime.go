@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// planning_reserve is the share of the time left until the deadline that an
+// anytime search leaves for turning its best plan into orders.
+const planning_reserve = 0.25
+
+// min_planning_reserve keeps some time back even when the deadline is close.
+const min_planning_reserve = 5 * time.Millisecond
+
+// planning_context is done early enough before ctx for an anytime search
+// that stops then to still return its orders before the deadline.
+func planning_context(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	remaining := time.Until(deadline)
+	reserve := time.Duration(float64(remaining) * planning_reserve)
+	if reserve < min_planning_reserve {
+		reserve = min_planning_reserve
+	}
+	return context.WithDeadline(ctx, deadline.Add(-reserve))
+}
+
+// AnytimeSearch counts the nodes of a search and tells it when to stop:
+// once its context is done or the node budget is used up. Checking the
+// context is comparatively slow, so it is only done every few nodes.
+type AnytimeSearch struct {
+	ctx     context.Context
+	budget  int
+	nodes   int
+	stopped bool
+}
+
+func new_anytime_search(ctx context.Context, budget int) *AnytimeSearch {
+	return &AnytimeSearch{ctx: ctx, budget: budget}
+}
+
+func (s *AnytimeSearch) Stop() bool {
+	if s.stopped {
+		return true
+	}
+	s.nodes++
+	if s.budget > 0 && s.nodes > s.budget {
+		s.stopped = true
+	} else if s.nodes%1024 == 0 && s.ctx.Err() != nil {
+		s.stopped = true
+	}
+	return s.stopped
+}
+
+// Complete reports whether the search ran to its end.
+func (s *AnytimeSearch) Complete() bool {
+	return !s.stopped
+}
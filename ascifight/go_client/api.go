@@ -0,0 +1,188 @@
+package main
+
+//go:generate go run ./cmd/apigen -spec openapi.json -out api_gen.go
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// response_body returns the body of resp, decompressing it when the server
+// answered our Accept-Encoding with gzip. Setting the header ourselves turns
+// off the transport's transparent decompression, so it is done here.
+func response_body(resp *http.Response) (io.ReadCloser, error) {
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp.Body, nil
+	}
+	return gzip.NewReader(resp.Body)
+}
+
+// api_call performs one call against the server and returns the response
+// with its decompressed body, using the Client bound to ctx or else the
+// DefaultClient. Responses other than 200 and 304 Not Modified are turned
+// into an *APIError.
+func api_call(ctx context.Context, method string, path string, query url.Values, body any, auth bool, header http.Header) (*http.Response, []byte, error) {
+	return client_from(ctx).call(ctx, method, path, query, body, auth, header)
+}
+
+// call sends the request again as the retry policy of the client allows.
+func (c *Client) call(ctx context.Context, method string, path string, query url.Values, body any, auth bool, header http.Header) (*http.Response, []byte, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, nil, err
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		resp, payload, err := c.attempt(ctx, method, path, query, data, auth, header, attempt)
+		if attempt >= c.retry.Attempts || !c.retry.retryable(method, resp, err) {
+			return resp, payload, err
+		}
+		select {
+		case <-time.After(c.retry.delay(attempt)):
+		case <-ctx.Done():
+			return resp, payload, err
+		}
+	}
+}
+
+// attempt performs one try of a call. Every try carries a fresh
+// X-Request-ID so the server logs can be matched with ours.
+func (c *Client) attempt(ctx context.Context, method string, path string, query url.Values, body []byte, auth bool, header http.Header, attempt int) (*http.Response, []byte, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	server := c.endpoints().Active()
+	target := server + game_path(path)
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if auth {
+		c.authenticator().Authenticate(req)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("User-Agent", user_agent())
+	request_id := random_id(8)
+	req.Header.Set("X-Request-ID", request_id)
+	info := RequestInfo{Method: method, URL: target, RequestID: request_id, Attempt: attempt, Started: time.Now()}
+	resp, data, err := c.send(req, path, server)
+	if resp != nil {
+		info.Status = resp.StatusCode
+	}
+	info.Duration, info.Err = time.Since(info.Started), err
+	for _, hook := range c.hooks {
+		hook(info)
+	}
+	return resp, data, err
+}
+
+func (c *Client) send(req *http.Request, path string, server string) (*http.Response, []byte, error) {
+	ctx := req.Context()
+	request_id := req.Header.Get("X-Request-ID")
+	servers, breaker := c.endpoints(), c.circuit()
+	if err := breaker.Allow(); err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.round_trip()(req)
+	if err != nil {
+		// our own deadline says nothing about the server, and a server
+		// we could fail over from does not count against the others
+		if ctx.Err() == nil && !servers.Failed(server, err) {
+			breaker.Failed(err)
+		}
+		return nil, nil, fmt.Errorf("request %s: %w", request_id, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		breaker.Failed(fmt.Errorf("%s returned %d", path, resp.StatusCode))
+	} else {
+		breaker.Succeeded()
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return resp, nil, nil
+	default:
+		e := decode_api_error(resp)
+		e.RequestID = request_id
+		return resp, nil, e
+	}
+	payload, err := response_body(resp)
+	if err != nil {
+		return resp, nil, err
+	}
+	defer payload.Close()
+	data, err := io.ReadAll(payload)
+	return resp, data, err
+}
+
+// api_request decodes the JSON response of a call into v, or stores the raw
+// body when v is a *[]byte. It backs the generated bindings in api_gen.go.
+func api_request(ctx context.Context, method string, path string, query url.Values, body any, auth bool, v any) error {
+	_, data, err := api_call(ctx, method, path, query, body, auth, nil)
+	if err != nil {
+		return err
+	}
+	if raw, ok := v.(*[]byte); ok {
+		*raw = data
+		return nil
+	}
+	if v == nil {
+		return nil
+	}
+	return decode_response(path, data, v)
+}
+
+// game_path routes the state and order calls to the game selected with
+// -game. Server wide routes like the OpenAPI description stay as they are.
+func game_path(path string) string {
+	if GameID == "" || !(strings.HasPrefix(path, "states/") || strings.HasPrefix(path, "orders/")) {
+		return path
+	}
+	return "games/" + url.PathEscape(GameID) + "/" + path
+}
+
+// check_game makes sure the team plays in the selected game before the bot
+// starts sending orders to it.
+func check_game() error {
+	state, err := DefaultClient.GameState(context.Background())
+	if err != nil {
+		var api_error *APIError
+		if errors.As(err, &api_error) && api_error.Status == http.StatusNotFound {
+			return fmt.Errorf("the server does not host game %s", GameID)
+		}
+		return fmt.Errorf("fetching game %s: %w", GameID, err)
+	}
+	for _, team := range state.Teams {
+		if team == Team {
+			return nil
+		}
+	}
+	return fmt.Errorf("team %s does not play in game %s, its teams are %s", Team, GameID, strings.Join(state.Teams, ", "))
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Client is the game server as the bot sees it. Its methods take a context
+// and return errors, leaving it to the caller whether to retry, skip the
+// tick or give up. Settings left unset fall back to the package wide ones
+// of the connection flags.
+type Client struct {
+	servers *Endpoints
+	auth    Authenticator
+	http    *http.Client
+	breaker *Breaker
+	// timeout bounds every try of a request, 0 leaves it to the context.
+	timeout    time.Duration
+	retry      RetryPolicy
+	hooks      []RequestHook
+	middleware []Middleware
+}
+
+// DefaultClient talks to the server selected with the connection flags.
+var DefaultClient = &Client{}
+
+// ClientOption customizes a Client made with NewClient.
+type ClientOption func(c *Client)
+
+// NewClient returns a client for the server at base_url, which may be a
+// comma separated list to fail over between like -server. Without options
+// it authenticates like the connection flags and has a circuit breaker of
+// its own.
+func NewClient(base_url string, options ...ClientOption) *Client {
+	servers := &Endpoints{}
+	servers.Set(base_url)
+	c := &Client{servers: servers, breaker: new_breaker(CircuitBreaker.Threshold, CircuitBreaker.Cooldown)}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithTimeout bounds every try of a request to d.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithAuth authenticates the requests with a, e.g. Credentials for a team
+// other than the one of the connection flags.
+func WithAuth(a Authenticator) ClientOption {
+	return func(c *Client) { c.auth = a }
+}
+
+// WithRetry sets how failed requests are tried again.
+func WithRetry(policy RetryPolicy) ClientOption {
+	return func(c *Client) { c.retry = policy }
+}
+
+// WithTransport sends the requests through rt, for proxies, custom TLS or
+// recording them in tests.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) { c.http = &http.Client{Transport: rt} }
+}
+
+// WithHTTPClient sends the requests with h.
+func WithHTTPClient(h *http.Client) ClientOption {
+	return func(c *Client) { c.http = h }
+}
+
+// WithBreaker shares b between clients, nil turns the breaker off.
+func WithBreaker(b *Breaker) ClientOption {
+	return func(c *Client) {
+		if b == nil {
+			b = new_breaker(0, 0)
+		}
+		c.breaker = b
+	}
+}
+
+// WithRequestHook calls hook after every try of a request, e.g. to log or
+// count them.
+func WithRequestHook(hook RequestHook) ClientOption {
+	return func(c *Client) { c.hooks = append(c.hooks, hook) }
+}
+
+// Credentials authenticate as a given team with its password.
+type Credentials struct {
+	Team     string
+	Password string
+}
+
+func (Credentials) Name() string { return "basic" }
+
+func (a Credentials) Authenticate(req *http.Request) {
+	req.SetBasicAuth(a.Team, a.Password)
+}
+
+// RetryPolicy tries GET requests again that got no answer or a server
+// error, waiting Backoff and then twice as long before every further try.
+// Orders are never sent twice, the server might have executed the first.
+type RetryPolicy struct {
+	// Attempts is the number of tries in total, 0 or 1 for no retries.
+	Attempts int
+	Backoff  time.Duration
+}
+
+func (p RetryPolicy) retryable(method string, resp *http.Response, err error) bool {
+	if method != http.MethodGet || err == nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	return resp == nil || resp.StatusCode >= 500
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	return p.Backoff << (attempt - 1)
+}
+
+// RequestInfo describes one try of a request for a RequestHook.
+type RequestInfo struct {
+	Method    string
+	URL       string
+	RequestID string
+	Attempt   int
+	Started   time.Time
+	Duration  time.Duration
+	// Status is 0 when no answer arrived.
+	Status int
+	Err    error
+}
+
+type RequestHook func(info RequestInfo)
+
+type client_key struct{}
+
+// bind makes the requests sent with ctx go through c, also those of the
+// generated bindings.
+func (c *Client) bind(ctx context.Context) context.Context {
+	if c == DefaultClient {
+		return ctx
+	}
+	return context.WithValue(ctx, client_key{}, c)
+}
+
+func client_from(ctx context.Context) *Client {
+	if c, ok := ctx.Value(client_key{}).(*Client); ok {
+		return c
+	}
+	return DefaultClient
+}
+
+func (c *Client) endpoints() *Endpoints {
+	if c.servers != nil {
+		return c.servers
+	}
+	return Servers
+}
+
+func (c *Client) authenticator() Authenticator {
+	if c.auth != nil {
+		return c.auth
+	}
+	return Auth
+}
+
+func (c *Client) http_client() *http.Client {
+	if c.http != nil {
+		return c.http
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) circuit() *Breaker {
+	if c.breaker != nil {
+		return c.breaker
+	}
+	return CircuitBreaker
+}
+
+func (c *Client) state(ctx context.Context, route string, v any) error {
+	return api_request(c.bind(ctx), "GET", "states/"+route, nil, nil, false, v)
+}
+
+func (c *Client) GameState(ctx context.Context) (GameState, error) {
+	var state GameState
+	err := c.state(ctx, "game_state", &state)
+	return state, err
+}
+
+func (c *Client) Timing(ctx context.Context) (Timing, error) {
+	var t Timing
+	err := c.state(ctx, "timing", &t)
+	return t, err
+}
+
+func (c *Client) Rules(ctx context.Context) (Rules, error) {
+	var r Rules
+	err := c.state(ctx, "game_rules", &r)
+	return r, err
+}
+
+func (c *Client) Scores(ctx context.Context) (AllScores, error) {
+	var scores AllScores
+	err := c.state(ctx, "scores", &scores)
+	return scores, err
+}
+
+var order_bindings = map[string]func(ctx context.Context, actor int, direction APIDirections) (map[string]string, error){
+	"move":    api_move_order_context,
+	"grabput": api_grabput_order_context,
+	"attack":  api_attack_order_context,
+	"destroy": api_destroy_order_context,
+	"build":   api_build_order_context,
+}
+
+// SubmitOrder posts a single order. Rejections by the server come back as
+// *APIError, see apierror.go for the kinds to branch on.
+func (c *Client) SubmitOrder(ctx context.Context, order Order) error {
+	binding, ok := order_bindings[order.order_type]
+	if !ok {
+		return fmt.Errorf("%w: unknown order type %q", ErrInvalidOrder, order.order_type)
+	}
+	_, err := binding(c.bind(ctx), order.actor, APIDirections(order.direction))
+	return err
+}
+
+// SubmitOrders posts the orders until ctx expires and returns the rejected
+// ones. Orders that could not be sent in time count as rejected. They go in
+// a single request where the server offers a batch route. The error is set
+// when the server could not be reached at all, the orders not sent by then
+// are among the rejected.
+func (c *Client) SubmitOrders(ctx context.Context, orders []Order) ([]Order, error) {
+	ctx = c.bind(ctx)
+	if use_batch(orders) {
+		if rejected, ok, err := submit_orders_batch(ctx, orders); ok {
+			return rejected, err
+		}
+	}
+	rejected := make([]Order, 0)
+	for i, order := range orders {
+		if ctx.Err() != nil {
+			log.Printf("tick deadline reached, dropping %d orders", len(orders)-i)
+			return append(rejected, orders[i:]...), nil
+		}
+		if err := OrderLimiter.Wait(ctx); err != nil {
+			log.Printf("tick deadline reached waiting for the rate limit, dropping %d orders", len(orders)-i)
+			return append(rejected, orders[i:]...), nil
+		}
+		logf(VerbosityDebug, "submitting order: %v", order)
+		err := c.SubmitOrder(ctx, order)
+		var api_error *APIError
+		// a rate limited order is sent again once the server lets us
+		for retry := 0; retry < rate_limit_retries && errors.As(err, &api_error) && errors.Is(err, ErrRateLimited); retry++ {
+			OrderLimiter.Pause(max_duration(api_error.RetryAfter, rate_limit_pause))
+			if OrderLimiter.Wait(ctx) != nil {
+				break
+			}
+			err = c.SubmitOrder(ctx, order)
+		}
+		switch {
+		case err == nil:
+		case errors.As(err, &api_error):
+			log.Printf("order rejected: %v", err)
+			rejected = append(rejected, order)
+			if errors.Is(err, ErrUnauthorized) {
+				// every further order would be rejected the same way
+				return append(rejected, orders[i+1:]...), nil
+			}
+		case ctx.Err() != nil:
+			log.Printf("order not submitted: %v", err)
+			rejected = append(rejected, order)
+		case errors.Is(err, ErrCircuitOpen):
+			log.Printf("dropping %d orders: %v", len(orders)-i, err)
+			return append(rejected, orders[i:]...), nil
+		default:
+			return append(rejected, orders[i:]...), err
+		}
+	}
+	return rejected, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error kinds an APIError unwraps to, for use with errors.Is.
+var (
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrInvalidOrder   = errors.New("invalid order")
+	ErrGameNotRunning = errors.New("game not running")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrServer         = errors.New("server error")
+)
+
+// ValidationError is one entry of a FastAPI 422 response.
+type ValidationError = APIValidationError
+
+func (v ValidationError) String() string {
+	parts := make([]string, 0, len(v.Loc))
+	for _, part := range v.Loc {
+		parts = append(parts, fmt.Sprint(part))
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(parts, "."), v.Msg)
+}
+
+// APIError is a non-200 response of the server with its decoded body.
+type APIError struct {
+	Status     int
+	URL        string
+	Detail     string
+	Validation []ValidationError
+	// RequestID is the X-Request-ID the request was sent with.
+	RequestID string
+	// RetryAfter is how long a rate limited client is asked to wait.
+	RetryAfter time.Duration
+	kind       error
+}
+
+func (e *APIError) Error() string {
+	detail := e.Detail
+	if len(e.Validation) > 0 {
+		parts := make([]string, 0, len(e.Validation))
+		for _, v := range e.Validation {
+			parts = append(parts, v.String())
+		}
+		detail = strings.Join(parts, "; ")
+	}
+	if detail == "" {
+		detail = http.StatusText(e.Status)
+	}
+	message := fmt.Sprintf("%s: %s returned %d: %s", e.kind, e.URL, e.Status, detail)
+	if e.RequestID != "" {
+		message += " (request " + e.RequestID + ")"
+	}
+	return message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+func error_kind(status int, detail string) error {
+	lower := strings.ToLower(detail)
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrUnauthorized
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case strings.Contains(lower, "not running") || strings.Contains(lower, "no game"):
+		return ErrGameNotRunning
+	case status == http.StatusConflict || status == http.StatusServiceUnavailable || status == http.StatusTooEarly:
+		return ErrGameNotRunning
+	case status == http.StatusUnprocessableEntity || status == http.StatusBadRequest || status == http.StatusNotFound:
+		return ErrInvalidOrder
+	default:
+		return ErrServer
+	}
+}
+
+// decode_api_error turns an unsuccessful response into an APIError. FastAPI
+// sends either {"detail": "message"} or {"detail": [validation errors]}.
+func decode_api_error(resp *http.Response) *APIError {
+	e := &APIError{Status: resp.StatusCode, URL: resp.Request.URL.String()}
+	var body []byte
+	if reader, err := response_body(resp); err == nil {
+		body, _ = io.ReadAll(io.LimitReader(reader, 64*1024))
+	}
+	var decoded struct {
+		Detail json.RawMessage `json:"detail"`
+	}
+	if json.Unmarshal(body, &decoded) == nil && len(decoded.Detail) > 0 {
+		if json.Unmarshal(decoded.Detail, &e.Detail) != nil {
+			json.Unmarshal(decoded.Detail, &e.Validation)
+		}
+	} else {
+		e.Detail = strings.TrimSpace(string(body))
+	}
+	e.kind = error_kind(e.Status, e.Detail)
+	e.RetryAfter = retry_after(resp.Header.Get("Retry-After"))
+	return e
+}
+
+// retry_after parses a Retry-After header, given in seconds or as a date.
+func retry_after(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(header, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type ArenaBot struct {
+	Team     string `json:"team"`
+	Password string `json:"-"`
+	Strategy string `json:"strategy"`
+	Params   string `json:"params,omitempty"`
+}
+
+type HeadToHead struct {
+	Strategy string `json:"strategy"`
+	Opponent string `json:"opponent"`
+	Wins     int    `json:"wins"`
+	Draws    int    `json:"draws"`
+	Losses   int    `json:"losses"`
+}
+
+type ArenaReport struct {
+	Started     time.Time    `json:"started"`
+	Ended       time.Time    `json:"ended"`
+	Bots        []ArenaBot   `json:"bots"`
+	Games       []Scores     `json:"games"`
+	HeadToHeads []HeadToHead `json:"head_to_heads"`
+}
+
+// parse_arena_bots reads a comma separated list of team:password:strategy.
+func parse_arena_bots(spec string) ([]ArenaBot, error) {
+	bots := make([]ArenaBot, 0)
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("bot %q is not of the form team:password:strategy", entry)
+		}
+		if _, err := lookup_strategy(parts[2]); err != nil {
+			return nil, err
+		}
+		bots = append(bots, ArenaBot{Team: parts[0], Password: parts[1], Strategy: parts[2]})
+	}
+	if len(bots) < 2 {
+		return nil, fmt.Errorf("an arena needs at least two bots")
+	}
+	return bots, nil
+}
+
+var unsafe_file_chars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+func safe_file_name(name string) string {
+	return unsafe_file_chars.ReplaceAllString(name, "_")
+}
+
+type ManagedProcess struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+// start_process runs name in dir with env added to our own environment,
+// logging its output to log_path.
+func start_process(name string, args []string, env []string, dir string, log_path string) (*ManagedProcess, error) {
+	log_file, err := os.Create(log_path)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = log_file
+	cmd.Stderr = log_file
+	if err := cmd.Start(); err != nil {
+		log_file.Close()
+		return nil, err
+	}
+	process := &ManagedProcess{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		cmd.Wait()
+		log_file.Close()
+		close(process.done)
+	}()
+	return process, nil
+}
+
+// Stop asks the process to shut down and kills it if it does not exit in time.
+func (p *ManagedProcess) Stop() {
+	p.cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		p.cmd.Process.Kill()
+		<-p.done
+	}
+}
+
+func wait_for_server(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := DefaultClient.Timing(context.Background())
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("server did not come up within %v: %w", timeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// watch_games follows the server without playing and returns the final
+// scores of the next number of completed games. A game already running
+// when it starts was partly played by whoever played before, so only games
+// watched from their first tick count.
+func watch_games(games int) []Scores {
+	results := make([]Scores, 0, games)
+	current_tick := 0
+	watched := false
+	var last GameState
+	for len(results) < games {
+		t, err := DefaultClient.Timing(context.Background())
+		if err != nil {
+			log.Printf("fetching timing failed: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if t.Tick == current_tick {
+			sleep_duration := time.Duration(t.TimeToNextExecution * float64(time.Second))
+			if sleep_duration < 100*time.Millisecond {
+				sleep_duration = 100 * time.Millisecond
+			}
+			time.Sleep(sleep_duration)
+			continue
+		}
+		switch {
+		case t.Tick < current_tick && watched:
+			results = append(results, last.Scores)
+			log.Printf("arena game %d/%d finished: %v", len(results), games, last.Scores)
+		case t.Tick < current_tick:
+			log.Printf("arena game finished that was already running, not counting it: %v", last.Scores)
+			watched = true
+		case current_tick == 0:
+			watched = t.Tick <= 1
+		}
+		current_tick = t.Tick
+		if state, err := DefaultClient.GameState(context.Background()); err != nil {
+			log.Printf("fetching game state failed: %v", err)
+		} else {
+			last = state
+		}
+	}
+	return results
+}
+
+func build_head_to_heads(bots []ArenaBot, games []Scores) []HeadToHead {
+	index := make(map[string]int)
+	result := make([]HeadToHead, 0)
+	for i := range bots {
+		for j := i + 1; j < len(bots); j++ {
+			key := bots[i].Team + "\x00" + bots[j].Team
+			index[key] = len(result)
+			result = append(result, HeadToHead{
+				Strategy: bots[i].Strategy + " (" + bots[i].Team + ")",
+				Opponent: bots[j].Strategy + " (" + bots[j].Team + ")",
+			})
+		}
+	}
+	for _, scores := range games {
+		for i := range bots {
+			for j := i + 1; j < len(bots); j++ {
+				h := &result[index[bots[i].Team+"\x00"+bots[j].Team]]
+				switch outcome_against(scores, bots[i].Team, bots[j].Team) {
+				case 1:
+					h.Wins++
+				case 0:
+					h.Losses++
+				default:
+					h.Draws++
+				}
+			}
+		}
+	}
+	return result
+}
+
+func print_arena_report(report ArenaReport) {
+	fmt.Printf("%d arena games\n\n", len(report.Games))
+	fmt.Printf("%-30s %-30s %5s %5s %5s\n", "strategy", "opponent", "wins", "draws", "loss")
+	for _, h := range report.HeadToHeads {
+		fmt.Printf("%-30s %-30s %5d %5d %5d\n", h.Strategy, h.Opponent, h.Wins, h.Draws, h.Losses)
+	}
+}
+
+func start_arena_server(server_cmd string, server_dir string, dir string, startup time.Duration) (*ManagedProcess, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	command := strings.Fields(server_cmd)
+	if len(command) == 0 {
+		return nil, fmt.Errorf("empty server command")
+	}
+	server, err := start_process(command[0], command[1:], nil, server_dir, filepath.Join(dir, "server.log"))
+	if err != nil {
+		return nil, fmt.Errorf("starting server failed: %w", err)
+	}
+	if err := wait_for_server(startup); err != nil {
+		server.Stop()
+		return nil, err
+	}
+	return server, nil
+}
+
+// run_arena_games starts one bot process per entry against the running
+// server, follows the given number of games and stops the bots again.
+func run_arena_games(bots []ArenaBot, games int, dir string) (ArenaReport, error) {
+	report := ArenaReport{Started: time.Now(), Bots: bots}
+	self, err := os.Executable()
+	if err != nil {
+		return report, err
+	}
+	for _, bot := range bots {
+		name := safe_file_name(bot.Team)
+		bot_args := []string{
+			"-server", ServerUrl,
+			"-team", bot.Team,
+			"-password-source", "env",
+			"-strategy", bot.Strategy,
+			"-stats-dir", filepath.Join(dir, name),
+		}
+		if bot.Params != "" {
+			bot_args = append(bot_args, "-params", bot.Params)
+		}
+		env := []string{password_env + "=" + bot.Password}
+		process, err := start_process(self, bot_args, env, ".", filepath.Join(dir, name+".log"))
+		if err != nil {
+			return report, fmt.Errorf("starting bot for %s failed: %w", bot.Team, err)
+		}
+		defer process.Stop()
+	}
+	report.Games = watch_games(games)
+	report.Ended = time.Now()
+	report.HeadToHeads = build_head_to_heads(bots, report.Games)
+	return report, nil
+}
+
+func write_json_report(v any, dir string, prefix string, t time.Time) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s.json", prefix, t.Format("20060102_150405"))
+	return os.WriteFile(filepath.Join(dir, name), data, 0644)
+}
+
+func arena_command(args []string) {
+	flags := flag.NewFlagSet("arena", flag.ExitOnError)
+	server_cmd := flags.String("server-cmd", "uvicorn ascifight.main:app --port 8000", "command that starts the game server")
+	server_dir := flags.String("server-dir", ".", "working directory for the server command")
+	bot_spec := flags.String("bots", "Team 1:1:greedy,Team 2:2:greedy", "comma separated team:password:strategy of the competing bots")
+	games := flags.Int("games", 5, "number of games to play")
+	dir := flags.String("dir", "arena", "directory for logs, per-bot stats and the arena report")
+	startup := flags.Duration("startup-timeout", 30*time.Second, "how long to wait for the server to come up")
+	flags.StringVar(&ServerUrl, "server", ServerUrl, "base url the started server listens on")
+	flags.Parse(args)
+	normalize_server_url()
+
+	bots, err := parse_arena_bots(*bot_spec)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	server, err := start_arena_server(*server_cmd, *server_dir, *dir, *startup)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer server.Stop()
+
+	report, err := run_arena_games(bots, *games, *dir)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	print_arena_report(report)
+	if err := write_json_report(report, *dir, "arena", report.Ended); err != nil {
+		log.Printf("writing arena report failed: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Authenticator adds the credentials of the team to a request.
+type Authenticator interface {
+	Name() string
+	Authenticate(req *http.Request)
+}
+
+// BasicAuth sends the team name and password, what the server expects by
+// default.
+type BasicAuth struct{}
+
+func (BasicAuth) Name() string { return "basic" }
+
+func (BasicAuth) Authenticate(req *http.Request) {
+	req.SetBasicAuth(Team, Password)
+}
+
+// TokenAuth sends an API token in a header. In the Authorization header it
+// is sent as a bearer token.
+type TokenAuth struct{}
+
+func (TokenAuth) Name() string { return "token" }
+
+func (TokenAuth) Authenticate(req *http.Request) {
+	if strings.EqualFold(TokenHeader, "Authorization") {
+		req.Header.Set("Authorization", "Bearer "+Token)
+		return
+	}
+	req.Header.Set(TokenHeader, Token)
+}
+
+var (
+	Auth Authenticator = BasicAuth{}
+	// Token and TokenHeader are read on every request, so -token may be
+	// given before or after -auth.
+	Token       = os.Getenv("ASCIFIGHT_TOKEN")
+	TokenHeader = "X-API-Token"
+)
+
+var auth_schemes = map[string]Authenticator{
+	"basic": BasicAuth{},
+	"token": TokenAuth{},
+}
+
+func set_auth(scheme string) error {
+	auth, ok := auth_schemes[scheme]
+	if !ok {
+		return fmt.Errorf("unknown auth scheme %q, use basic or token", scheme)
+	}
+	Auth = auth
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// PressureLevel is how far the state fetches fall behind the ticks.
+type PressureLevel int
+
+const (
+	PressureNone PressureLevel = iota
+	// PressureSlow skips the optional fetches and plans less.
+	PressureSlow
+	// PressureOverloaded also plays on with the last state when the
+	// current one does not arrive in time.
+	PressureOverloaded
+)
+
+func (l PressureLevel) String() string {
+	switch l {
+	case PressureSlow:
+		return "slow"
+	case PressureOverloaded:
+		return "overloaded"
+	}
+	return "normal"
+}
+
+// pressure_weight is how much every tick moves the averages.
+const pressure_weight = 0.3
+
+// Backpressure compares how long the state fetches take with the tick
+// interval. A server that takes longer to answer than the ticks last would
+// otherwise have the bot stack up blocking requests and miss every tick,
+// so it degrades gracefully instead: it skips the requests it can do
+// without, reuses the last state marked stale and cuts the planning budget.
+type Backpressure struct {
+	mutex sync.Mutex
+	// Threshold is the share of the tick interval the fetches may take
+	// before the bot counts as slow, 0 to never degrade.
+	Threshold float64
+	fetch     time.Duration
+	interval  time.Duration
+	last_tick int
+	tick_at   time.Time
+	level     PressureLevel
+}
+
+var Pressure = new_backpressure(0.5)
+
+func new_backpressure(threshold float64) *Backpressure {
+	return &Backpressure{Threshold: threshold}
+}
+
+func average(mean time.Duration, sample time.Duration) time.Duration {
+	if mean == 0 {
+		return sample
+	}
+	return mean + time.Duration(pressure_weight*float64(sample-mean))
+}
+
+// Observe adds how long the fetches at the start of tick took.
+func (b *Backpressure) Observe(tick int, fetch time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	if b.last_tick > 0 && tick > b.last_tick {
+		b.interval = average(b.interval, now.Sub(b.tick_at)/time.Duration(tick-b.last_tick))
+	}
+	if tick != b.last_tick {
+		b.last_tick, b.tick_at = tick, now
+	}
+	b.fetch = average(b.fetch, fetch)
+	level := PressureNone
+	switch {
+	case b.Threshold <= 0 || b.interval == 0:
+	case b.fetch >= b.interval:
+		level = PressureOverloaded
+	case float64(b.fetch) >= b.Threshold*float64(b.interval):
+		level = PressureSlow
+	}
+	if level != b.level {
+		if level > PressureNone {
+			log.Printf("warning: state fetches take %v of a %v tick, server is %s, degrading", b.fetch.Round(time.Millisecond), b.interval.Round(time.Millisecond), level)
+		} else {
+			log.Printf("state fetches take %v of a %v tick again, no longer degrading", b.fetch.Round(time.Millisecond), b.interval.Round(time.Millisecond))
+		}
+		b.level = level
+	}
+}
+
+func (b *Backpressure) Level() PressureLevel {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.level
+}
+
+// SkipOptional reports whether requests the bot can do without, like the
+// events of a tick, are left out.
+func (b *Backpressure) SkipOptional() bool {
+	return b.Level() >= PressureSlow
+}
+
+// FetchTimeout bounds the state fetch of a tick. While overloaded a state
+// that takes longer than a tick is not waited for, the last one is played
+// on.
+func (b *Backpressure) FetchTimeout() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.level == PressureOverloaded && b.interval < fetch_timeout {
+		return b.interval
+	}
+	return fetch_timeout
+}
+
+// Nodes cuts the node budget of a search to what the time left over by
+// the fetches allows.
+func (b *Backpressure) Nodes(nodes int) int {
+	switch b.Level() {
+	case PressureSlow:
+		return nodes / 4
+	case PressureOverloaded:
+		return nodes / 16
+	}
+	return nodes
+}
+
+// Reset forgets the measurements, e.g. for a new game.
+func (b *Backpressure) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.fetch, b.interval, b.last_tick, b.tick_at, b.level = 0, 0, 0, time.Time{}, PressureNone
+}
+
+// stale_state is the last state standing in for the one of tick t that
+// did not arrive in time.
+func stale_state(previous GameState, t Timing) GameState {
+	state := previous
+	state.Stale += t.Tick - previous.Tick
+	state.Tick = t.Tick
+	state.TimeOfNextExecution = t.TimeOfNextExecution
+	return state
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	// bait_guard_radius is how close to its flag an enemy attacker has to be
+	// to count as a defender worth drawing away.
+	bait_guard_radius = 3
+	// bait_feint_distance is how close the feint comes to the flag. Once it
+	// is there and drew the defender, the grab goes in.
+	bait_feint_distance = 2
+	// bait_stage_distance is how far from the flag the grab waits for the
+	// feint, out of reach of the defenders.
+	bait_stage_distance = 4
+	// bait_timeout is how many ticks a bait and switch is given before it is
+	// called off.
+	bait_timeout = 40
+)
+
+// Intent is the part one actor plays in a bait and switch.
+type Intent struct {
+	// Role is "feint" or "grab".
+	Role string
+	// Flag is the team of the flag and FlagAt where it lies.
+	Flag    string
+	FlagAt  Coordinates
+	Partner int
+	// Target is where the feint heads for and the grab waits.
+	Target Coordinates
+	// Go is set on the grab once the feint drew the defenders.
+	Go    bool
+	Since int
+}
+
+// IntentBoard holds the bait and switch intents of our actors. The
+// coordinator plans them once per tick before the controllers are asked,
+// and each controller reads the intent of its actor to propose its part, so
+// the two actors of a pair act on the same picture.
+type IntentBoard struct {
+	mutex   sync.Mutex
+	tick    int
+	intents map[int]Intent
+}
+
+func new_intent_board() *IntentBoard {
+	return &IntentBoard{intents: make(map[int]Intent)}
+}
+
+// Intents are shared by the actor controllers of the actors strategy.
+var Intents = new_intent_board()
+
+func (b *IntentBoard) Get(ident int) (Intent, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	intent, ok := b.intents[ident]
+	return intent, ok
+}
+
+// drop calls off the bait and switch of ident, for both actors of the pair.
+func (b *IntentBoard) drop(ident int) {
+	if intent, ok := b.intents[ident]; ok {
+		delete(b.intents, intent.Partner)
+	}
+	delete(b.intents, ident)
+}
+
+// Plan updates the intents to state: it calls off pairs that lost an actor,
+// grabbed their flag or ran out of time, lets the grab go once the feint
+// came close and pairs up actors for flags that are guarded.
+func (b *IntentBoard) Plan(state GameState) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if state.Tick < b.tick {
+		b.intents = make(map[int]Intent)
+	}
+	b.tick = state.Tick
+	ours := make(map[int]Actor)
+	for _, actor := range filter_objects(state.Actors, true) {
+		ours[actor.Ident] = actor
+	}
+	// a carried flag is no longer guarded where it was
+	flags := lying_flags(state, false)
+	for ident, intent := range b.intents {
+		_, present := ours[ident]
+		_, flag := flags[flag_key(intent.Flag, intent.FlagAt)]
+		if !present || !flag || state.Tick-intent.Since > bait_timeout || Params.UtilityBait <= 0 {
+			b.drop(ident)
+		}
+	}
+	for ident, intent := range b.intents {
+		if intent.Role != "feint" {
+			continue
+		}
+		flag := flags[flag_key(intent.Flag, intent.FlagAt)]
+		if distance(ours[ident].Coordinates, flag.Coordinates) > bait_feint_distance {
+			continue
+		}
+		// the defender turned to the feint or left the flag
+		defender, guarded := nearest_defender(state, flag)
+		grab := ours[intent.Partner]
+		if !guarded || distance(defender.Coordinates, ours[ident].Coordinates) < distance(defender.Coordinates, grab.Coordinates) {
+			partner := b.intents[intent.Partner]
+			partner.Go = true
+			b.intents[intent.Partner] = partner
+		}
+	}
+	if Params.UtilityBait <= 0 {
+		return
+	}
+	baited := make(map[string]bool)
+	for _, intent := range b.intents {
+		baited[flag_key(intent.Flag, intent.FlagAt)] = true
+	}
+	for _, flag := range filter_objects(state.Flags, false) {
+		key := flag_key(flag.Team, flag.Coordinates)
+		if _, ok := flags[key]; !ok || baited[key] {
+			continue
+		}
+		defender, guarded := nearest_defender(state, flag)
+		if !guarded {
+			continue
+		}
+		free := make([]Actor, 0)
+		for _, actor := range filter_objects(state.Actors, true) {
+			if _, busy := b.intents[actor.Ident]; !busy && actor.Flag == "" {
+				free = append(free, actor)
+			}
+		}
+		feint, grab, ok := opposite_pair(free, flag.Coordinates, defender.Coordinates)
+		if !ok {
+			continue
+		}
+		b.intents[feint.Ident] = Intent{Role: "feint", Flag: flag.Team, FlagAt: flag.Coordinates, Partner: grab.Ident,
+			Target: approach(flag.Coordinates, feint.Coordinates, bait_feint_distance), Since: state.Tick}
+		b.intents[grab.Ident] = Intent{Role: "grab", Flag: flag.Team, FlagAt: flag.Coordinates, Partner: feint.Ident,
+			Target: approach(flag.Coordinates, grab.Coordinates, bait_stage_distance), Since: state.Tick}
+		logf(VerbosityNormal, "bait and switch on the flag of %s: actor %d feints, actor %d grabs", flag.Team, feint.Ident, grab.Ident)
+	}
+}
+
+// nearest_defender is the attacker of the flag's team closest to it, if
+// one is within bait_guard_radius.
+func nearest_defender(state GameState, flag Flag) (Actor, bool) {
+	best, found := Actor{}, false
+	for _, enemy := range filter_objects(state.Actors, false) {
+		d := distance(enemy.Coordinates, flag.Coordinates)
+		if enemy.Team != flag.Team || capabilities(enemy).Attack == 0 || d > bait_guard_radius {
+			continue
+		}
+		if !found || d < distance(best.Coordinates, flag.Coordinates) {
+			best, found = enemy, true
+		}
+	}
+	return best, found
+}
+
+// opposite_pair picks the two actors that approach flag from the most
+// opposite sides, at least at a right angle. The one closer to defender
+// feints, the other has to be able to grab.
+func opposite_pair(actors []Actor, flag Coordinates, defender Coordinates) (Actor, Actor, bool) {
+	var feint, grab Actor
+	best, found := 0.0, false
+	for i, a := range actors {
+		for _, b := range actors[i+1:] {
+			// the cosine of the angle between the approaches
+			ax, ay := float64(a.Coordinates.X-flag.X), float64(a.Coordinates.Y-flag.Y)
+			bx, by := float64(b.Coordinates.X-flag.X), float64(b.Coordinates.Y-flag.Y)
+			length := math.Hypot(ax, ay) * math.Hypot(bx, by)
+			if length == 0 {
+				continue
+			}
+			cosine := (ax*bx + ay*by) / length
+			if cosine > 0 || (found && cosine >= best) {
+				continue
+			}
+			f, g := a, b
+			if distance(b.Coordinates, defender) < distance(a.Coordinates, defender) {
+				f, g = b, a
+			}
+			if capabilities(g).Grab == 0 {
+				if capabilities(f).Grab == 0 {
+					continue
+				}
+				f, g = g, f
+			}
+			feint, grab, best, found = f, g, cosine, true
+		}
+	}
+	return feint, grab, found
+}
+
+// approach is the cell steps from flag on the way to from, or from itself
+// when it is closer.
+func approach(flag Coordinates, from Coordinates, steps int) Coordinates {
+	at := flag
+	for i := 0; i < steps && at != from; i++ {
+		at = predicted_position(at, find_path(at, from))
+	}
+	return at
+}
+
+// bait_options adds the part of the actor in a bait and switch to the
+// options plan proposes. The feint walks up to the flag, the grab waits at
+// its staging cell until the feint is close and then goes for the flag.
+func bait_options(plan ActorPlanner) ActorPlanner {
+	return func(actor Actor, state GameState, base Base) []UtilityOption {
+		options := plan(actor, state, base)
+		intent, ok := Intents.Get(actor.Ident)
+		if !ok || actor.Flag != "" {
+			return options
+		}
+		key := flag_key(intent.Flag, intent.FlagAt)
+		var option UtilityOption
+		switch {
+		case intent.Role == "feint":
+			option = new_option("camp", fmt.Sprintf("feint at flag of %s for actor %d", intent.Flag, intent.Partner),
+				"feint:"+key, intent.Target, "")
+			option.consider("proximity", Params.UtilityProximity, proximity(actor.Coordinates, intent.Target))
+		case !intent.Go:
+			option = new_option("camp", fmt.Sprintf("wait for the feint of actor %d at flag of %s", intent.Partner, intent.Flag),
+				"stage:"+key, intent.Target, "")
+			option.consider("proximity", Params.UtilityProximity, proximity(actor.Coordinates, intent.Target))
+		default:
+			flag, ok := lying_flags(state, false)[key]
+			if !ok {
+				return options
+			}
+			option = new_option("grab", fmt.Sprintf("grab flag of %s behind the feint of actor %d", intent.Flag, intent.Partner),
+				flag_claim(flag), flag.Coordinates, "grabput")
+			option.consider("grab", Params.UtilityGrab, capabilities(actor).Grab)
+			option.consider("proximity", Params.UtilityProximity, proximity(actor.Coordinates, flag.Coordinates))
+		}
+		option.consider("bait", Params.UtilityBait, 1)
+		return append(options, option)
+	}
+}
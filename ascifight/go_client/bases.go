@@ -0,0 +1,71 @@
+package main
+
+import "sort"
+
+// nearest_base is the base of ours next to which a walk from c ends soonest,
+// the zero Base when we have none. A team may own several bases and a flag
+// scores at any of them.
+func nearest_base(state GameState, c Coordinates) Base {
+	bases := filter_objects(state.Bases, true)
+	if len(bases) <= 1 {
+		if len(bases) == 0 {
+			return Base{}
+		}
+		return bases[0]
+	}
+	size := board_size(state, GameRules.MapSize())
+	best, steps := bases[0], -1
+	for _, base := range bases {
+		if d := walk_distance(state, size, c, base.Coordinates); steps < 0 || d < steps {
+			best, steps = base, d
+		}
+	}
+	return best
+}
+
+// split_defenders spreads defenders over our bases as evenly as they allow,
+// the ones closest to a base first, and tells the base of each.
+func split_defenders(state GameState, defenders []Actor) map[int]Base {
+	bases := filter_objects(state.Bases, true)
+	split := make(map[int]Base, len(defenders))
+	if len(bases) == 0 {
+		return split
+	}
+	type pair struct {
+		actor Actor
+		base  int
+	}
+	pairs := make([]pair, 0, len(defenders)*len(bases))
+	for _, actor := range defenders {
+		for i := range bases {
+			pairs = append(pairs, pair{actor, i})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return distance(pairs[i].actor.Coordinates, bases[pairs[i].base].Coordinates) <
+			distance(pairs[j].actor.Coordinates, bases[pairs[j].base].Coordinates)
+	})
+	counts := make([]int, len(bases))
+	// every base gets its even share first, the rest go where they are closest
+	for _, share := range []int{len(defenders) / len(bases), len(defenders)/len(bases) + 1} {
+		for _, p := range pairs {
+			if _, done := split[p.actor.Ident]; !done && counts[p.base] < share {
+				split[p.actor.Ident] = bases[p.base]
+				counts[p.base]++
+			}
+		}
+	}
+	return split
+}
+
+// closest_base is the base of team closest to c as the crow flies, false
+// when team has none.
+func closest_base(bases []Base, team string, c Coordinates) (Base, bool) {
+	best, found := Base{}, false
+	for _, base := range bases {
+		if base.Team == team && (!found || distance(c, base.Coordinates) < distance(c, best.Coordinates)) {
+			best, found = base, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitDefenders(t *testing.T) {
+	base := func(team string, x int, y int) Base {
+		return Base{OwnedObjectImpl{Team: team, Coordinates: Coordinates{x, y}}}
+	}
+	a, b := base(Team, 0, 0), base(Team, 9, 9)
+	enemy := base("Team 2", 3, 0)
+	tests := []struct {
+		name      string
+		bases     []Base
+		defenders []Actor
+		// split is the base coordinates of every defender
+		split map[int]Coordinates
+	}{
+		{
+			name:      "no base of ours",
+			bases:     []Base{enemy},
+			defenders: []Actor{our_actor(0, 1, 0)},
+			split:     map[int]Coordinates{},
+		},
+		{
+			name:      "a single base gets all",
+			bases:     []Base{a, enemy},
+			defenders: []Actor{our_actor(0, 1, 0), our_actor(1, 8, 8)},
+			split:     map[int]Coordinates{0: a.Coordinates, 1: a.Coordinates},
+		},
+		{
+			name:      "defenders go to their closest base",
+			bases:     []Base{a, b},
+			defenders: []Actor{our_actor(0, 8, 8), our_actor(1, 1, 0)},
+			split:     map[int]Coordinates{0: b.Coordinates, 1: a.Coordinates},
+		},
+		{
+			name:      "the closer one stays when both are near the same base",
+			bases:     []Base{a, b},
+			defenders: []Actor{our_actor(0, 2, 0), our_actor(1, 1, 0)},
+			split:     map[int]Coordinates{0: b.Coordinates, 1: a.Coordinates},
+		},
+		{
+			name:      "the odd defender goes where it is closest",
+			bases:     []Base{a, b},
+			defenders: []Actor{our_actor(0, 1, 0), our_actor(1, 2, 0), our_actor(2, 3, 0)},
+			split:     map[int]Coordinates{0: a.Coordinates, 1: a.Coordinates, 2: b.Coordinates},
+		},
+		{
+			name:      "the enemy base is not guarded",
+			bases:     []Base{a, b, enemy},
+			defenders: []Actor{our_actor(0, 3, 1), our_actor(1, 8, 9)},
+			split:     map[int]Coordinates{0: a.Coordinates, 1: b.Coordinates},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := GameState{Teams: []string{Team, "Team 2"}, Bases: test.bases, Actors: test.defenders}
+			split := make(map[int]Coordinates)
+			for ident, base := range split_defenders(state, test.defenders) {
+				split[ident] = base.Coordinates
+			}
+			if !reflect.DeepEqual(split, test.split) {
+				t.Errorf("split %v, want %v", split, test.split)
+			}
+		})
+	}
+}
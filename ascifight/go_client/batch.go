@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// batch_route takes all orders of a tick in one request. The server does not
+// offer it yet, it is used once check_server_version found it.
+const batch_route = "/orders/batch"
+
+var (
+	// BatchOrders allows the batch route when the server offers it.
+	BatchOrders   = true
+	batch_offered = false
+)
+
+type BatchOrder struct {
+	Type      string `json:"type"`
+	Actor     int    `json:"actor"`
+	Direction string `json:"direction"`
+}
+
+type BatchRequest struct {
+	Orders []BatchOrder `json:"orders"`
+}
+
+// BatchResult is the outcome of one order of a batch, in the order they
+// were sent.
+type BatchResult struct {
+	Actor    int    `json:"actor"`
+	Accepted bool   `json:"accepted"`
+	Detail   string `json:"detail"`
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+func use_batch(orders []Order) bool {
+	return BatchOrders && batch_offered && len(orders) > 1
+}
+
+// submit_batch posts all orders in a single request and returns the
+// rejected ones. Orders the response says nothing about count as rejected.
+func submit_batch(ctx context.Context, orders []Order) ([]Order, error) {
+	request := BatchRequest{Orders: make([]BatchOrder, 0, len(orders))}
+	for _, order := range orders {
+		logf(VerbosityDebug, "submitting order: %v", order)
+		request.Orders = append(request.Orders, BatchOrder{order.order_type, order.actor, order.direction})
+	}
+	var response BatchResponse
+	err := api_request(ctx, "POST", batch_route[1:], nil, request, true, &response)
+	var api_error *APIError
+	// a rate limited batch is sent again once the server lets us
+	for retry := 0; retry < rate_limit_retries && errors.As(err, &api_error) && errors.Is(err, ErrRateLimited); retry++ {
+		OrderLimiter.Pause(max_duration(api_error.RetryAfter, rate_limit_pause))
+		if OrderLimiter.Wait(ctx) != nil {
+			break
+		}
+		err = api_request(ctx, "POST", batch_route[1:], nil, request, true, &response)
+	}
+	if err != nil {
+		return orders, err
+	}
+	rejected := make([]Order, 0)
+	for i, order := range orders {
+		switch {
+		case i >= len(response.Results):
+			log.Printf("order rejected: no result for %v in the batch response", order)
+			rejected = append(rejected, order)
+		case response.Results[i].Actor != order.actor:
+			log.Printf("order rejected: batch result %d is for actor %d, not %d", i, response.Results[i].Actor, order.actor)
+			rejected = append(rejected, order)
+		case !response.Results[i].Accepted:
+			log.Printf("order rejected: %v: %s", order, response.Results[i].Detail)
+			rejected = append(rejected, order)
+		}
+	}
+	return rejected, nil
+}
+
+// submit_orders_batch falls back to one request per order when the batch
+// route turns out to be gone. The error is set when the server could not be
+// reached, all orders are rejected then.
+func submit_orders_batch(ctx context.Context, orders []Order) ([]Order, bool, error) {
+	if err := OrderLimiter.Wait(ctx); err != nil {
+		log.Printf("tick deadline reached waiting for the rate limit, dropping %d orders", len(orders))
+		return orders, true, nil
+	}
+	rejected, err := submit_batch(ctx, orders)
+	var api_error *APIError
+	switch {
+	case err == nil:
+	case errors.As(err, &api_error) && (api_error.Status == 404 || api_error.Status == 405):
+		log.Printf("the batch order route is gone, submitting orders one by one: %v", err)
+		batch_offered = false
+		return nil, false, nil
+	case errors.As(err, &api_error):
+		log.Printf("batch of %d orders rejected: %v", len(orders), err)
+	case errors.Is(err, ErrCircuitOpen) || ctx.Err() != nil:
+		log.Printf("dropping %d orders: %v", len(orders), err)
+	default:
+		return orders, true, fmt.Errorf("submitting %d orders: %w", len(orders), err)
+	}
+	return rejected, true, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+type Status int
+
+const (
+	Success Status = iota
+	Failure
+	Running
+)
+
+func (s Status) String() string {
+	switch s {
+	case Success:
+		return "success"
+	case Failure:
+		return "failure"
+	default:
+		return "running"
+	}
+}
+
+// BehaviorContext is what a behavior tree sees while deciding for one actor.
+// Leaves append their orders to Orders.
+type BehaviorContext struct {
+	State      GameState
+	Actor      Actor
+	Index      int
+	MyActors   []Actor
+	MyBase     Base
+	Orders     []Order
+	Blackboard map[string]any
+}
+
+type Node interface {
+	Tick(ctx *BehaviorContext) Status
+}
+
+// Selector runs its children in order until one does not fail.
+type Selector []Node
+
+func (s Selector) Tick(ctx *BehaviorContext) Status {
+	for _, child := range s {
+		if status := child.Tick(ctx); status != Failure {
+			return status
+		}
+	}
+	return Failure
+}
+
+// Sequence runs its children in order until one does not succeed.
+type Sequence []Node
+
+func (s Sequence) Tick(ctx *BehaviorContext) Status {
+	for _, child := range s {
+		if status := child.Tick(ctx); status != Success {
+			return status
+		}
+	}
+	return Success
+}
+
+type Condition func(ctx *BehaviorContext) bool
+
+func (c Condition) Tick(ctx *BehaviorContext) Status {
+	if c(ctx) {
+		return Success
+	}
+	return Failure
+}
+
+type Action func(ctx *BehaviorContext) Status
+
+func (a Action) Tick(ctx *BehaviorContext) Status {
+	return a(ctx)
+}
+
+// Inverter flips success and failure of its child.
+type Inverter struct {
+	Child Node
+}
+
+func (i Inverter) Tick(ctx *BehaviorContext) Status {
+	switch i.Child.Tick(ctx) {
+	case Success:
+		return Failure
+	case Failure:
+		return Success
+	default:
+		return Running
+	}
+}
+
+// run_behavior ticks the tree once for each of our actors and collects
+// the resulting orders.
+func run_behavior(tree Node, state GameState) []Order {
+	my_actors := filter_objects(state.Actors, true)
+	ctx := &BehaviorContext{
+		State:      state,
+		MyActors:   my_actors,
+		Orders:     make([]Order, 0),
+		Blackboard: make(map[string]any),
+	}
+	for i, actor := range my_actors {
+		ctx.Actor = actor
+		ctx.Index = i
+		// the base closest to the actor, where a flag it carries scores soonest
+		ctx.MyBase = nearest_base(state, actor.Coordinates)
+		tree.Tick(ctx)
+	}
+	return ctx.Orders
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// leaf returns status and records its name, so that a test can tell which
+// children a composite node ticked.
+func leaf(name string, status Status, ticked *[]string) Node {
+	return Action(func(ctx *BehaviorContext) Status {
+		*ticked = append(*ticked, name)
+		return status
+	})
+}
+
+func TestCompositeNodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		build  func(ticked *[]string) Node
+		status Status
+		ticked []string
+	}{
+		{
+			name: "selector stops at the first success",
+			build: func(ticked *[]string) Node {
+				return Selector{leaf("a", Failure, ticked), leaf("b", Success, ticked), leaf("c", Success, ticked)}
+			},
+			status: Success,
+			ticked: []string{"a", "b"},
+		},
+		{
+			name: "selector stops at a running child",
+			build: func(ticked *[]string) Node {
+				return Selector{leaf("a", Running, ticked), leaf("b", Success, ticked)}
+			},
+			status: Running,
+			ticked: []string{"a"},
+		},
+		{
+			name: "selector fails when all children fail",
+			build: func(ticked *[]string) Node {
+				return Selector{leaf("a", Failure, ticked), leaf("b", Failure, ticked)}
+			},
+			status: Failure,
+			ticked: []string{"a", "b"},
+		},
+		{
+			name:   "empty selector fails",
+			build:  func(ticked *[]string) Node { return Selector{} },
+			status: Failure,
+		},
+		{
+			name: "sequence stops at the first failure",
+			build: func(ticked *[]string) Node {
+				return Sequence{leaf("a", Success, ticked), leaf("b", Failure, ticked), leaf("c", Success, ticked)}
+			},
+			status: Failure,
+			ticked: []string{"a", "b"},
+		},
+		{
+			name: "sequence stops at a running child",
+			build: func(ticked *[]string) Node {
+				return Sequence{leaf("a", Success, ticked), leaf("b", Running, ticked), leaf("c", Success, ticked)}
+			},
+			status: Running,
+			ticked: []string{"a", "b"},
+		},
+		{
+			name: "sequence succeeds when all children succeed",
+			build: func(ticked *[]string) Node {
+				return Sequence{leaf("a", Success, ticked), leaf("b", Success, ticked)}
+			},
+			status: Success,
+			ticked: []string{"a", "b"},
+		},
+		{
+			name:   "empty sequence succeeds",
+			build:  func(ticked *[]string) Node { return Sequence{} },
+			status: Success,
+		},
+		{
+			name: "nested selector inside a sequence",
+			build: func(ticked *[]string) Node {
+				return Sequence{Selector{leaf("a", Failure, ticked), leaf("b", Success, ticked)}, leaf("c", Success, ticked)}
+			},
+			status: Success,
+			ticked: []string{"a", "b", "c"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ticked []string
+			status := test.build(&ticked).Tick(&BehaviorContext{})
+			if status != test.status {
+				t.Errorf("status %s, want %s", status, test.status)
+			}
+			if !reflect.DeepEqual(ticked, test.ticked) {
+				t.Errorf("ticked %v, want %v", ticked, test.ticked)
+			}
+		})
+	}
+}
+
+func TestLeafNodes(t *testing.T) {
+	always := Condition(func(ctx *BehaviorContext) bool { return true })
+	never := Condition(func(ctx *BehaviorContext) bool { return false })
+	running := Action(func(ctx *BehaviorContext) Status { return Running })
+	tests := []struct {
+		name   string
+		node   Node
+		status Status
+	}{
+		{"true condition succeeds", always, Success},
+		{"false condition fails", never, Failure},
+		{"action returns its status", running, Running},
+		{"inverter turns success into failure", Inverter{always}, Failure},
+		{"inverter turns failure into success", Inverter{never}, Success},
+		{"inverter keeps running", Inverter{running}, Running},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if status := test.node.Tick(&BehaviorContext{}); status != test.status {
+				t.Errorf("status %s, want %s", status, test.status)
+			}
+		})
+	}
+}
+
+func TestRunBehaviorTicksEachOfOurActors(t *testing.T) {
+	state := GameState{
+		Teams: []string{Team, "Team 2"},
+		Actors: []Actor{
+			{Type: "Runner", Ident: 0, OwnedObjectImpl: OwnedObjectImpl{Team: Team}},
+			{Type: "Runner", Ident: 0, OwnedObjectImpl: OwnedObjectImpl{Team: "Team 2"}},
+			{Type: "Runner", Ident: 1, OwnedObjectImpl: OwnedObjectImpl{Team: Team}},
+		},
+	}
+	var ticked []int
+	tree := Action(func(ctx *BehaviorContext) Status {
+		ticked = append(ticked, ctx.Actor.Ident)
+		if ctx.Index != len(ticked)-1 {
+			t.Errorf("actor %d ticked with index %d", ctx.Actor.Ident, ctx.Index)
+		}
+		ctx.Orders = append(ctx.Orders, Order{})
+		return Success
+	})
+	orders := run_behavior(tree, state)
+	if !reflect.DeepEqual(ticked, []int{0, 1}) {
+		t.Errorf("ticked actors %v, want [0 1]", ticked)
+	}
+	if len(orders) != 2 {
+		t.Errorf("%d orders, want 2", len(orders))
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BenchScenario is a set of states of one board size the strategies decide
+// on in turn.
+type BenchScenario struct {
+	Name   string
+	Size   int
+	States []GameState
+}
+
+// read_bench_states loads recorded states, either a JSON array or one state
+// per line like the state dumps in the bot log.
+func read_bench_states(path string) ([]GameState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]GameState, 0)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &states)
+		return states, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if i := bytes.Index(line, []byte("state: ")); i >= 0 {
+			line = line[i+len("state: "):]
+		}
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var state GameState
+		if err := json.Unmarshal(line, &state); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		states = append(states, state)
+	}
+	return states, scanner.Err()
+}
+
+// recorded_scenarios groups recorded states by the size of their board,
+// which is taken from the states unless map_size is given.
+func recorded_scenarios(path string, map_size int) ([]BenchScenario, error) {
+	states, err := read_bench_states(path)
+	if err != nil {
+		return nil, err
+	}
+	by_size := make(map[int]*BenchScenario)
+	sizes := make([]int, 0)
+	for _, state := range states {
+		size := map_size
+		if size <= 0 {
+			size = board_size(state, 0)
+		}
+		scenario, ok := by_size[size]
+		if !ok {
+			scenario = &BenchScenario{Name: fmt.Sprintf("recorded %dx%d", size, size), Size: size}
+			by_size[size] = scenario
+			sizes = append(sizes, size)
+		}
+		scenario.States = append(scenario.States, state)
+	}
+	sort.Ints(sizes)
+	scenarios := make([]BenchScenario, 0, len(sizes))
+	for _, size := range sizes {
+		scenarios = append(scenarios, *by_size[size])
+	}
+	return scenarios, nil
+}
+
+// synthetic_state places bases and flags of the teams around the board with
+// their actors nearby, and sprinkles walls over the rest.
+func synthetic_state(r *rand.Rand, size int, teams int, actors int, tick int) GameState {
+	state := GameState{Tick: tick, Scores: make(Scores)}
+	types := make([]string, 0, len(default_capabilities))
+	for name := range default_capabilities {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	taken := make(map[Coordinates]bool)
+	margin := max_int(1, size/8)
+	corners := []Coordinates{{X: margin, Y: margin}, {X: size - 1 - margin, Y: size - 1 - margin}, {X: size - 1 - margin, Y: margin}, {X: margin, Y: size - 1 - margin}}
+	for t := 0; t < teams; t++ {
+		team := fmt.Sprintf("Team %d", t+1)
+		state.Teams = append(state.Teams, team)
+		state.Scores[team] = 0
+		base := corners[t%len(corners)]
+		if t >= len(corners) {
+			base = Coordinates{X: r.Intn(size), Y: r.Intn(size)}
+		}
+		taken[base] = true
+		state.Bases = append(state.Bases, Base{OwnedObjectImpl{Team: team, Coordinates: base}})
+		state.Flags = append(state.Flags, Flag{OwnedObjectImpl{Team: team, Coordinates: base}})
+		for i := 0; i < actors; i++ {
+			c := base
+			// spread further out when the cells around the base are taken
+			for spread := margin; taken[c]; spread++ {
+				c = Coordinates{X: min_int(size-1, max_int(0, base.X+r.Intn(2*spread+1)-spread)), Y: min_int(size-1, max_int(0, base.Y+r.Intn(2*spread+1)-spread))}
+			}
+			taken[c] = true
+			state.Actors = append(state.Actors, Actor{Type: types[(t+i)%len(types)], Ident: i, OwnedObjectImpl: OwnedObjectImpl{Team: team, Coordinates: c}})
+		}
+	}
+	for i := 0; i < size*size/10; i++ {
+		c := Coordinates{X: r.Intn(size), Y: r.Intn(size)}
+		if !taken[c] {
+			taken[c] = true
+			state.Walls = append(state.Walls, c)
+		}
+	}
+	return state
+}
+
+// synthetic_scenarios generates count states per board size. Every base
+// and actor needs a cell of its own, so a board too small for them is an
+// error rather than a search for a free cell that never ends.
+func synthetic_scenarios(seed int64, sizes []int, teams int, actors int, count int) ([]BenchScenario, error) {
+	r := rand.New(rand.NewSource(seed))
+	scenarios := make([]BenchScenario, 0, len(sizes))
+	for _, size := range sizes {
+		if teams*(actors+1) > size*size {
+			return nil, fmt.Errorf("%d teams of %d actors do not fit on a %dx%d board", teams, actors, size, size)
+		}
+		scenario := BenchScenario{Name: fmt.Sprintf("synthetic %dx%d", size, size), Size: size}
+		for i := 0; i < count; i++ {
+			scenario.States = append(scenario.States, synthetic_state(r, size, teams, actors, i+1))
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+// BenchResult is the decision latency of one strategy in one scenario.
+type BenchResult struct {
+	Strategy   string              `json:"strategy"`
+	Scenario   string              `json:"scenario"`
+	States     int                 `json:"states"`
+	Orders     float64             `json:"average_orders"`
+	Decisions  DecisionPercentiles `json:"decisions"`
+	OverBudget int                 `json:"over_budget"`
+}
+
+// bench_strategy lets the strategy decide on every state of the scenario
+// as if they were consecutive ticks of a game.
+func bench_strategy(strategy Strategy, scenario BenchScenario, max_ticks int, budget time.Duration) BenchResult {
+	GameRules.Set(Rules{MapSize: scenario.Size, MaxTicks: max_ticks, ActorProperties: bench_properties()})
+	result := BenchResult{Strategy: strategy.Name(), Scenario: scenario.Name, States: len(scenario.States)}
+	times := make([]time.Duration, 0, len(scenario.States))
+	orders := 0
+	for _, state := range scenario.States {
+		start := time.Now()
+		orders += len(decide(context.Background(), strategy, state))
+		took := time.Since(start)
+		times = append(times, took)
+		if took > budget {
+			result.OverBudget++
+		}
+	}
+	result.Decisions = decision_percentiles(times)
+	if len(scenario.States) > 0 {
+		result.Orders = float64(orders) / float64(len(scenario.States))
+	}
+	return result
+}
+
+func bench_properties() []ActorProperty {
+	properties := make([]ActorProperty, 0, len(default_capabilities))
+	for name, c := range default_capabilities {
+		properties = append(properties, ActorProperty{Type: name, Grab: c.Grab, Attack: c.Attack, Build: c.Build, Destroy: c.Destroy})
+	}
+	return properties
+}
+
+func parse_sizes(list string) ([]int, error) {
+	sizes := make([]int, 0)
+	for _, part := range strings.Split(list, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || size < 3 {
+			return nil, fmt.Errorf("invalid board size %q", part)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// bench_command measures how long the strategies take to decide, on
+// recorded states or on synthetic boards of growing size, to tell whether
+// they fit into the tick budget before they play live.
+func bench_command(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	strategy_list := flags.String("strategies", strings.Join(strategy_names(), ","), "comma separated strategies to measure")
+	states_file := flags.String("states", "", "recorded states to decide on, a JSON array or one state per line, instead of synthetic ones")
+	size_list := flags.String("sizes", "15,30,60", "comma separated board sizes of the synthetic states")
+	teams := flags.Int("teams", 2, "teams on the synthetic boards")
+	actors := flags.Int("actors", 6, "actors per team on the synthetic boards")
+	count := flags.Int("count", 50, "synthetic states per board size")
+	seed := flags.Int64("seed", 1, "seed of the synthetic states and the strategies")
+	budget := flags.Duration("budget", time.Second, "decision time a tick allows, decisions over it are counted")
+	map_size := flags.Int("map-size", 0, "board size of the recorded states, guessed from their contents if 0")
+	max_ticks := flags.Int("max-ticks", 200, "game length the strategies plan with")
+	flags.StringVar(&Team, "team", "Team 1", "team the strategies play for")
+	config_flags := add_config_flags(flags)
+	flags.Parse(args)
+	if err := config_flags.Apply(); err != nil {
+		log.Fatalln(err)
+	}
+
+	strategies, err := lookup_strategies(*strategy_list)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var scenarios []BenchScenario
+	if *states_file != "" {
+		scenarios, err = recorded_scenarios(*states_file, *map_size)
+	} else {
+		var sizes []int
+		if sizes, err = parse_sizes(*size_list); err == nil {
+			scenarios, err = synthetic_scenarios(*seed, sizes, *teams, *actors, *count)
+		}
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Printf("%-12s %-20s %6s %6s %9s %9s %9s %9s %5s\n", "strategy", "scenario", "states", "orders", "p50 ms", "p90 ms", "p99 ms", "max ms", "over")
+	for _, strategy := range strategies {
+		for _, scenario := range scenarios {
+			Random.Seed(*seed)
+			r := bench_strategy(strategy, scenario, *max_ticks, *budget)
+			fmt.Printf("%-12s %-20s %6d %6.1f %9.3f %9.3f %9.3f %9.3f %5d\n", r.Strategy, r.Scenario, r.States, r.Orders,
+				r.Decisions.P50, r.Decisions.P90, r.Decisions.P99, r.Decisions.Max, r.OverBudget)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func team_index(state GameState, team string) int {
+	for i, name := range state.Teams {
+		if name == team {
+			return i
+		}
+	}
+	return len(state.Teams)
+}
+
+func board_size(state GameState, size int) int {
+	if size > 0 {
+		return size
+	}
+	for _, actor := range state.Actors {
+		size = max_int(size, max_int(actor.Coordinates.X, actor.Coordinates.Y)+1)
+	}
+	for _, base := range state.Bases {
+		size = max_int(size, max_int(base.Coordinates.X, base.Coordinates.Y)+1)
+	}
+	for _, wall := range state.Walls {
+		size = max_int(size, max_int(wall.X, wall.Y)+1)
+	}
+	return size
+}
+
+func max_int(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min_int(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// actor_letters are the letters the actor types are drawn with. Guardians
+// and Builders are W and M, so that no two types share a letter and no
+// actor reads like a base or a flag.
+var actor_letters = map[string]string{
+	"Generalist": "G",
+	"Runner":     "R",
+	"Attacker":   "A",
+	"Guardian":   "W",
+	"Builder":    "M",
+	"Destroyer":  "D",
+}
+
+// actor_letter is upper case for our actors and lower case for enemies.
+func actor_letter(actor Actor) string {
+	letter, ok := actor_letters[actor.Type]
+	if !ok {
+		return "?"
+	}
+	if actor.Team == Team {
+		return letter
+	}
+	return strings.ToLower(letter)
+}
+
+// board_style turns the objects on the board into two character cells.
+type board_style struct {
+	empty string
+	wall  string
+	base  func(team int) string
+	flag  func(team int) string
+	actor func(team int, letter string, ident int) string
+}
+
+var plain_style = board_style{
+	empty: " .",
+	wall:  "##",
+	base:  func(team int) string { return fmt.Sprintf("B%d", team) },
+	flag:  func(team int) string { return fmt.Sprintf("F%d", team) },
+	actor: func(team int, letter string, ident int) string { return fmt.Sprintf("%s%d", letter, ident%10) },
+}
+
+// color_style draws with the server's symbols and team colors.
+var color_style = board_style{
+	empty: " .",
+	wall:  wall_icon + wall_icon,
+	base:  func(team int) string { return ansi_team_color(team, " "+base_icon) },
+	flag:  func(team int) string { return ansi_team_color(team, " "+flag_icon) },
+	actor: func(team int, letter string, ident int) string {
+		return ansi_team_color(team, fmt.Sprintf("%s%d", letter, ident%10))
+	},
+}
+
+// board_cells lays out the board as two character cells, indexed [y][x].
+// Our actors are shown with an upper case type letter, enemies in lower
+// case, followed by the actor ident. Bases and flags carry the team number.
+func board_cells(state GameState, size int) [][]string {
+	return styled_cells(state, size, plain_style)
+}
+
+func styled_cells(state GameState, size int, style board_style) [][]string {
+	size = board_size(state, size)
+	cells := make([][]string, size)
+	for y := range cells {
+		cells[y] = make([]string, size)
+		for x := range cells[y] {
+			cells[y][x] = style.empty
+		}
+	}
+	inside := func(c Coordinates) bool {
+		return c.X >= 0 && c.Y >= 0 && c.X < size && c.Y < size
+	}
+	for _, wall := range state.Walls {
+		c := Coordinates{wall.X, wall.Y}
+		if inside(c) {
+			cells[c.Y][c.X] = style.wall
+		}
+	}
+	for _, flag := range state.Flags {
+		if inside(flag.Coordinates) {
+			cells[flag.Coordinates.Y][flag.Coordinates.X] = style.flag(team_index(state, flag.Team))
+		}
+	}
+	for _, base := range state.Bases {
+		if inside(base.Coordinates) {
+			cells[base.Coordinates.Y][base.Coordinates.X] = style.base(team_index(state, base.Team))
+		}
+	}
+	for _, actor := range state.Actors {
+		if !inside(actor.Coordinates) {
+			continue
+		}
+		cells[actor.Coordinates.Y][actor.Coordinates.X] = style.actor(team_index(state, actor.Team), actor_letter(actor), actor.Ident)
+	}
+	return cells
+}
+
+// render_board draws the board with y growing upwards, like the server does.
+func render_board(state GameState, size int) string {
+	style := plain_style
+	if UseColor {
+		style = color_style
+	}
+	cells := styled_cells(state, size, style)
+	var b strings.Builder
+	for y := len(cells) - 1; y >= 0; y-- {
+		fmt.Fprintf(&b, "%2d ", y)
+		b.WriteString(strings.Join(cells[y], " "))
+		b.WriteString("\n")
+	}
+	b.WriteString("   ")
+	for x := range cells {
+		fmt.Fprintf(&b, "%2d ", x)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func render_scores(state GameState) string {
+	parts := make([]string, 0, len(state.Teams))
+	for i, team := range state.Teams {
+		parts = append(parts, ansi_team_color(i, fmt.Sprintf("[%d] %s: %d", i, team, state.Scores[team])))
+	}
+	return strings.Join(parts, "  ")
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+type BotOptions struct {
+	StatsDir    string
+	StatsFormat string
+	DryRun      bool
+	// ReuseUnchanged submits the previous orders again instead of running
+	// the strategy while nothing on the board changed.
+	ReuseUnchanged bool
+	// PollOffset is how long after the announced execution time the loop
+	// first asks for the new tick.
+	PollOffset time.Duration
+	// DeadlineMargin is how long before the next execution the work for a
+	// tick has to be finished.
+	DeadlineMargin time.Duration
+	// PhaseRoles shifts the roles of our actors with the phase of the game.
+	PhaseRoles bool
+	// CounterTactics adapts the parameters to the classified style of the
+	// opponents.
+	CounterTactics bool
+	Stepper        *Stepper
+	Dashboard      *Dashboard
+	Snapshots      *SnapshotRecorder
+	Recorder       *GameRecorder
+	Acceptance     *AcceptanceMonitor
+	Journal        *OrderJournal
+	Watchdog       *Watchdog
+	Notifier       *Notifier
+	Health         *Health
+	Tracer         *Tracer
+}
+
+// fetch_retry is how long the loop waits after the server could not be
+// reached before trying again.
+const fetch_retry = time.Second
+
+// retry_delay waits out an open circuit breaker instead of polling it.
+func retry_delay(err error) time.Duration {
+	if remaining := CircuitBreaker.Remaining(); errors.Is(err, ErrCircuitOpen) && remaining > fetch_retry {
+		return remaining
+	}
+	return fetch_retry
+}
+
+// deciding is held while a strategy runs. A strategy that overran its
+// deadline keeps running in the background and must not be started again
+// before it returned.
+var deciding sync.Mutex
+
+// decide runs the strategy inside a recover boundary. A panicking strategy
+// is logged together with the state it choked on and submits no orders for
+// that tick, instead of taking the bot down for the rest of the game.
+// Strategies that do not take a context are abandoned once ctx is done.
+func decide(ctx context.Context, strategy Strategy, state GameState) []Order {
+	if !deciding.TryLock() {
+		log.Printf("strategy %s is still busy with an earlier tick, no orders at tick %d", strategy.Name(), state.Tick)
+		return nil
+	}
+	apply_params()
+	result := make(chan []Order, 1)
+	go func() {
+		defer deciding.Unlock()
+		params_readers.RLock()
+		defer params_readers.RUnlock()
+		defer func() {
+			if r := recover(); r != nil {
+				snapshot, _ := json.Marshal(state)
+				log.Printf("strategy %s panicked at tick %d: %v\n%s\nstate: %s", strategy.Name(), state.Tick, r, debug.Stack(), snapshot)
+				result <- nil
+			}
+		}()
+		Random.Tick(state.Tick)
+		if s, ok := strategy.(ContextStrategy); ok {
+			result <- s.GenerateOrdersContext(ctx, state)
+		} else {
+			result <- strategy.GenerateOrders(state)
+		}
+	}()
+	select {
+	case orders := <-result:
+		return orders
+	case <-ctx.Done():
+		log.Printf("strategy %s missed the deadline of tick %d", strategy.Name(), state.Tick)
+		return nil
+	}
+}
+
+// tick_context is cancelled shortly before the next execution, so that no
+// stage of a tick overruns into the next one.
+func tick_context(parent context.Context, t Timing, margin time.Duration) (context.Context, context.CancelFunc) {
+	remaining := time.Duration(t.TimeToNextExecution*float64(time.Second)) - margin
+	return context.WithTimeout(parent, remaining)
+}
+
+func log_rules() {
+	r, err := GameRules.Get()
+	if err != nil {
+		log.Printf("fetching game rules failed: %v", err)
+		return
+	}
+	logf(VerbosityNormal, "game rules: %dx%d board, %d ticks, %d actor types", r.MapSize, r.MapSize, r.MaxTicks, len(r.ActorProperties))
+}
+
+// observe_state updates what the strategies remember across ticks, and
+// adapts the parameters to the opponents when counter is set and to the
+// phase of the game when phased is.
+func observe_state(state GameState, counter bool, phased bool, baseline StrategyParams) {
+	World.Observe(state, GameRules.MapSize())
+	Enemies.Observe(state)
+	Alliances.Observe(state)
+	style, changed := Opponents.Observe(state)
+	if changed && counter {
+		logf(VerbosityNormal, "playing against %s opponents from tick %d", style, state.Tick)
+	}
+	phase, shifted := Phases.Observe(state)
+	if shifted && phased {
+		logf(VerbosityNormal, "%s phase from tick %d", phase, state.Tick)
+	}
+	if (changed && counter) || (shifted && phased) {
+		p := baseline
+		if counter {
+			p = counter_params(p, style)
+		}
+		if phased {
+			p = phase_params(p, phase)
+		}
+		set_params(p)
+	}
+}
+
+// verify_tick checks the orders sent after previous against state and
+// feeds the outcomes back into the path search.
+func verify_tick(previous GameState, state GameState, orders []Order, rejected []Order) {
+	outcomes := verify_orders(previous, state, orders, rejected)
+	failed := 0
+	for _, o := range outcomes {
+		if !o.Ok() {
+			failed++
+			logf(VerbosityDebug, "order outcome %v", o)
+		}
+	}
+	if failed > 0 {
+		logf(VerbosityNormal, "%d of %d orders of tick %d did not work out", failed, len(outcomes), previous.Tick)
+	}
+	Outcomes.Record(outcomes)
+}
+
+// play runs the tick loop with the given strategy, restarting it when the
+// watchdog of options finds it stalled. Whenever a game ends, on_game_end
+// receives its stats and decides which strategy plays the next game, or
+// stops the loop by returning false.
+func play(strategy Strategy, options BotOptions, on_game_end func(stats *GameStats) (Strategy, bool)) {
+	if options.Watchdog == nil {
+		play_loop(context.Background(), strategy, options, on_game_end)
+		return
+	}
+	supervise(strategy, options, on_game_end)
+}
+
+// play_loop plays until on_game_end ends it or loop_ctx is cancelled.
+func play_loop(loop_ctx context.Context, strategy Strategy, options BotOptions, on_game_end func(stats *GameStats) (Strategy, bool)) {
+	current_tick := 0
+	stats := new_game_stats(strategy.Name())
+	var previous *GameState
+	var last_orders []Order
+	var last_rejected []Order
+	// work is what the latest tick took, to explain ticks missed after it
+	var work TickWork
+	cache := &StateCache{}
+	poller := new_poller(options.PollOffset)
+	// counter tactics are applied on top of the parameters we started with
+	baseline := Params
+	// the journal only holds orders of a crashed run before the first tick
+	recover_journal := options.Journal != nil
+	GameRules.Invalidate()
+	log_rules()
+	for loop_ctx.Err() == nil {
+		if options.Watchdog != nil {
+			options.Watchdog.Beat(current_tick)
+		}
+		f, err := fetch_tick(loop_ctx, cache, poller.Due(), !GameRules.Loaded())
+		if err != nil {
+			log.Printf("fetching timing failed: %v", err)
+			work.FetchErrors++
+			if options.Health != nil {
+				options.Health.Failed(err)
+			}
+			time.Sleep(retry_delay(err))
+			continue
+		}
+		if options.Health != nil {
+			options.Health.Fetched()
+		}
+		if f.Rules != nil {
+			GameRules.Set(*f.Rules)
+		}
+		t := f.Timing
+		if t.Tick == current_tick {
+			time.Sleep(poller.Wait(t))
+		} else {
+			poller.Ticked()
+			ctx, cancel := tick_context(loop_ctx, t, options.DeadlineMargin)
+			if options.Stepper != nil {
+				// a human reviews every tick, the deadline would only drop the orders
+				cancel()
+				ctx, cancel = context.WithCancel(loop_ctx)
+			}
+			tick_span := options.Tracer.Start("tick", nil)
+			tick_span.Set("tick", t.Tick)
+			tick_span.Set("strategy", strategy.Name())
+			fetch_span := options.Tracer.Start("fetch", tick_span)
+			fetch_span.StartedAt(f.Started)
+			state, not_modified, err := f.State, f.NotModified, f.StateErr
+			Pressure.Observe(t.Tick, time.Since(f.Started))
+			switch {
+			case !f.WithState:
+				// the tick came earlier than expected
+				state, not_modified, err = cache.Fetch(ctx, t)
+			case err == nil && state.Tick != t.Tick && Pressure.SkipOptional():
+				// the state was served just before the server resolved the
+				// tick, fetching it again would cost the whole tick
+				state = stale_state(state, t)
+			case err == nil && state.Tick != t.Tick:
+				// the state was served just before the server resolved the tick
+				fetch_span.Set("refetched", true)
+				state, not_modified, err = cache.Fetch(ctx, t)
+			case err != nil && previous != nil && Pressure.Level() == PressureOverloaded && t.Tick > current_tick:
+				log.Printf("fetching game state failed: %v, playing on with the state of tick %d", err, previous.Tick-previous.Stale)
+				state, err = stale_state(*previous, t), nil
+			}
+			fetch_span.Set("not_modified", not_modified)
+			fetch_span.Set("stale", state.Stale)
+			fetch_span.End()
+			if err != nil {
+				cancel()
+				tick_span.Set("error", err.Error())
+				tick_span.End()
+				log.Printf("fetching game state failed: %v", err)
+				work.FetchErrors++
+				if options.Health != nil {
+					options.Health.Failed(err)
+				}
+				time.Sleep(retry_delay(err))
+				continue
+			}
+			fetch_time := time.Since(f.Started)
+			if t.Tick < current_tick {
+				finish_game(stats, options, true)
+				if options.Notifier != nil {
+					options.Notifier.Finish(stats)
+				}
+				next, ok := on_game_end(stats)
+				if !ok {
+					cancel()
+					return
+				}
+				strategy = next
+				stats = new_game_stats(strategy.Name())
+				previous = nil
+				last_orders = nil
+				last_rejected = nil
+				Outcomes.Reset()
+				Pressure.Reset()
+				if options.Acceptance != nil {
+					options.Acceptance.Reset()
+				}
+				// the new game may be played with different rules
+				GameRules.Invalidate()
+				log_rules()
+			}
+			if previous != nil && t.Tick > current_tick+1 {
+				missed := t.Tick - current_tick - 1
+				cause := missed_cause(work, options.Stepper != nil)
+				log.Printf("warning: missed %d ticks after tick %d: %s", missed, current_tick, cause)
+				stats.RecordMissed(current_tick, missed, cause)
+				if options.Health != nil {
+					options.Health.Missed(missed)
+				}
+			}
+			work = TickWork{Tick: t.Tick, Budget: time.Duration(t.TimeToNextExecution * float64(time.Second)), Fetch: fetch_time}
+			current_tick = t.Tick
+			if stats.FirstTick == 0 {
+				stats.FirstTick = t.Tick
+			}
+			if err := validate_state(state, GameRules.MapSize()); err != nil {
+				log.Printf("skipping tick %d: %v", t.Tick, err)
+				cancel()
+				tick_span.Set("error", err.Error())
+				tick_span.End()
+				continue
+			}
+			if previous == nil || !same_scores(previous.Scores, state.Scores) {
+				log.Printf("tick %d scores: %s", state.Tick, colored_scores(state))
+			}
+			if options.Recorder != nil && !options.Recorder.Started() {
+				rules, _ := GameRules.Get()
+				header := RecordingHeader{Started: time.Now(), Seed: Random.Current(), Strategy: strategy.Name(), Team: Team, Rules: rules,
+					Params: Params, Baseline: baseline, FogOptimism: World.Optimism, CounterTactics: options.CounterTactics,
+					PhaseRoles:     options.PhaseRoles,
+					ReuseUnchanged: options.ReuseUnchanged, DryRun: options.DryRun}
+				if err := options.Recorder.Start(header); err != nil {
+					log.Printf("starting game recording failed: %v", err)
+				}
+			}
+			if previous == nil && state.Tick > 1 {
+				reconcile_memory(state, options.CounterTactics, options.PhaseRoles, baseline)
+			} else {
+				observe_state(state, options.CounterTactics, options.PhaseRoles, baseline)
+			}
+			if options.Notifier != nil {
+				options.Notifier.Observe(state)
+			}
+			var events []GameEvent
+			if events_offered && !Pressure.SkipOptional() {
+				if events, err = fetch_events(ctx, state.Tick); err != nil {
+					log.Printf("%v, inferring them from the state", err)
+				}
+				TickEvents.Set(state.Tick, events)
+				for _, e := range events {
+					logf(VerbosityDebug, "event %v", e)
+				}
+			}
+			// a stale board says nothing about how the last orders went
+			if previous != nil && state.Stale == 0 {
+				stats.Observe(*previous, state, last_orders, events)
+			}
+			if previous != nil && state.Stale == 0 && !options.DryRun {
+				verify_tick(*previous, state, last_orders, last_rejected)
+			}
+			unchanged := not_modified
+			if previous != nil && !unchanged {
+				unchanged = diff_states(*previous, state).Empty()
+			}
+			decide_span := options.Tracer.Start("decide", tick_span)
+			decide_span.Set("unchanged", unchanged)
+			start := time.Now()
+			var orders []Order
+			recovered := false
+			if recover_journal {
+				recover_journal = false
+				if orders, recovered, err = options.Journal.Pending(state); err != nil {
+					log.Printf("reading order journal failed: %v", err)
+				}
+			}
+			reused := options.ReuseUnchanged && unchanged && previous != nil
+			switch {
+			case recovered:
+				log.Printf("recovered %d unsubmitted orders of tick %d from the journal", len(orders), state.Tick)
+			case reused:
+				logf(VerbosityDebug, "board unchanged at tick %d, reusing %d orders", state.Tick, len(last_orders))
+				orders = last_orders
+			default:
+				orders = decide(ctx, strategy, state)
+			}
+			orders = validate_orders(orders, state)
+			prioritize_orders(orders)
+			decision_time := time.Since(start)
+			if loop_ctx.Err() != nil {
+				// the watchdog gave up on this loop while it was deciding
+				cancel()
+				return
+			}
+			work.Decide, work.DecideLate = decision_time, ctx.Err() != nil
+			decide_span.Set("orders", len(orders))
+			decide_span.End()
+			stats.RecordDecision(decision_time)
+			decided := orders
+			if options.Stepper != nil {
+				submit, quit := options.Stepper.Review(state, orders)
+				if quit {
+					cancel()
+					finish_game(stats, options, false)
+					return
+				}
+				if !submit {
+					log.Printf("skipped %d orders at tick %d", len(orders), state.Tick)
+					orders = nil
+				}
+			}
+			submit_span := options.Tracer.Start("submit", tick_span)
+			submit_span.Set("orders", len(orders))
+			submit_span.Set("dry_run", options.DryRun)
+			start = time.Now()
+			if options.DryRun {
+				for _, order := range orders {
+					logf(VerbosityDebug, "dry run, not submitting order: %v", order)
+				}
+			} else {
+				if options.Journal != nil {
+					if err := options.Journal.Write(state, orders); err != nil {
+						log.Printf("writing order journal failed: %v", err)
+					}
+				}
+				rejected, err := DefaultClient.SubmitOrders(ctx, orders)
+				if err != nil {
+					log.Printf("submitting orders failed: %v", err)
+				}
+				if options.Journal != nil {
+					if err := options.Journal.Clear(); err != nil {
+						log.Printf("clearing order journal failed: %v", err)
+					}
+				}
+				stats.RecordOrders(orders, rejected)
+				last_rejected = rejected
+				if options.Acceptance != nil {
+					options.Acceptance.Observe(state.Tick, len(orders), len(rejected))
+					if options.Health != nil {
+						options.Health.Accepted(options.Acceptance)
+					}
+				}
+				submit_span.Set("rejected", len(rejected))
+			}
+			submit_time := time.Since(start)
+			work.Submit, work.SubmitLate = submit_time, ctx.Err() != nil && !work.DecideLate
+			submit_span.End()
+			cancel()
+			if options.Recorder != nil {
+				tick := RecordedTick{Tick: state.Tick, State: state, Orders: recorded_orders(decided), Skipped: orders == nil && decided != nil,
+					Rejected: recorded_orders(last_rejected), Reused: reused, Events: events}
+				if err := options.Recorder.Record(tick); err != nil {
+					log.Printf("writing game recording failed: %v", err)
+				}
+			}
+			if options.Snapshots != nil {
+				if err := options.Snapshots.Record(state); err != nil {
+					log.Printf("writing board snapshot failed: %v", err)
+				}
+			}
+			if options.Dashboard != nil {
+				options.Dashboard.Publish(strategy.Name(), state, orders, fetch_time, decision_time, submit_time)
+			}
+			if options.Health != nil {
+				options.Health.Ticked(state.Tick)
+			}
+			tick_span.End()
+			logf(VerbosityTrace, "state recieved: %v", state)
+			previous = &state
+			last_orders = orders
+		}
+
+	}
+}
+
+// finish_game writes what is kept of a game. A game left halfway is not
+// counted in the ratings.
+func finish_game(stats *GameStats, options BotOptions, counted bool) {
+	stats.Ended = time.Now()
+	if err := write_stats(stats, options.StatsDir, options.StatsFormat); err != nil {
+		log.Printf("writing stats failed: %v", err)
+	}
+	report := new_game_report(stats)
+	log.Printf("game over\n%s", report.Text())
+	if err := write_report(report, options.StatsDir); err != nil {
+		log.Printf("writing game report failed: %v", err)
+	}
+	// dry runs did not influence the game and stay out of the ratings
+	if counted && !options.DryRun {
+		if err := append_result(options.StatsDir, new_game_result(stats)); err != nil {
+			log.Printf("writing results ledger failed: %v", err)
+		}
+	}
+	if options.Snapshots != nil {
+		if err := options.Snapshots.Finish(); err != nil {
+			log.Printf("writing game animation failed: %v", err)
+		}
+	}
+	if options.Recorder != nil {
+		if err := options.Recorder.Finish(); err != nil {
+			log.Printf("writing game recording failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of sending a request while the breaker
+// holds requests back.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Breaker stops all calls to the server after Threshold consecutive server
+// errors or failed connections. It stays open for Cooldown, doubling with
+// every failed probe up to MaxCooldown, and closes on the first success.
+type Breaker struct {
+	Threshold   int
+	Cooldown    time.Duration
+	MaxCooldown time.Duration
+	mutex       sync.Mutex
+	failures    int
+	cooldown    time.Duration
+	open_until  time.Time
+}
+
+func new_breaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown, MaxCooldown: time.Minute}
+}
+
+// CircuitBreaker guards every call made by api_call.
+var CircuitBreaker = new_breaker(5, 2*time.Second)
+
+// Allow reports an error while the breaker is open. Once the cooldown has
+// passed a single probe request is let through.
+func (b *Breaker) Allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.Threshold <= 0 || b.failures < b.Threshold {
+		return nil
+	}
+	if remaining := time.Until(b.open_until); remaining > 0 {
+		return fmt.Errorf("%w: server unavailable, retrying in %v", ErrCircuitOpen, remaining.Round(time.Millisecond))
+	}
+	// the probe keeps the breaker open for everyone else until it returned
+	b.open_until = time.Now().Add(b.cooldown)
+	return nil
+}
+
+// Remaining is how long the breaker stays open.
+func (b *Breaker) Remaining() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.failures < b.Threshold {
+		return 0
+	}
+	return time.Until(b.open_until)
+}
+
+func (b *Breaker) Succeeded() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.Threshold > 0 && b.failures >= b.Threshold {
+		log.Printf("server is answering again, circuit closed")
+	}
+	b.failures = 0
+	b.cooldown = 0
+}
+
+func (b *Breaker) Failed(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.failures++
+	if b.Threshold <= 0 || b.failures < b.Threshold {
+		return
+	}
+	if b.cooldown == 0 {
+		b.cooldown = b.Cooldown
+	} else if b.cooldown *= 2; b.cooldown > b.MaxCooldown {
+		b.cooldown = b.MaxCooldown
+	}
+	b.open_until = time.Now().Add(b.cooldown)
+	log.Printf("circuit open after %d failed requests, pausing requests for %v: %v", b.failures, b.cooldown, err)
+}
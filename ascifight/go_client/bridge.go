@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BridgeStrategy forwards every decision to another process speaking
+// line delimited JSON-RPC 2.0, either over its stdin/stdout or a socket.
+// Each tick the client calls the method "generate_orders" with a
+// PluginInput as params and expects a list of PluginOrder as result.
+type BridgeStrategy struct {
+	spec      string
+	timeout   time.Duration
+	mutex     sync.Mutex
+	writer    io.WriteCloser
+	responses chan RpcResponse
+	next_id   int
+	process   *exec.Cmd
+}
+
+type RpcRequest struct {
+	JsonRpc string `json:"jsonrpc"`
+	Id      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type RpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type RpcResponse struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      int             `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *RpcError       `json:"error"`
+}
+
+// new_bridge_strategy connects to tcp:<address> or unix:<path>, anything
+// else is started as a command.
+func new_bridge_strategy(spec string) (*BridgeStrategy, error) {
+	b := &BridgeStrategy{spec: spec, timeout: 2 * time.Second, responses: make(chan RpcResponse, 16)}
+	var reader io.Reader
+	if network, address, ok := strings.Cut(spec, ":"); ok && (network == "tcp" || network == "unix") {
+		conn, err := net.Dial(network, address)
+		if err != nil {
+			return nil, err
+		}
+		b.writer = conn
+		reader = conn
+	} else {
+		command := strings.Fields(spec)
+		if len(command) == 0 {
+			return nil, fmt.Errorf("empty bridge command")
+		}
+		b.process = exec.Command(command[0], command[1:]...)
+		b.process.Stderr = os.Stderr
+		stdin, err := b.process.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := b.process.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := b.process.Start(); err != nil {
+			return nil, err
+		}
+		b.writer = stdin
+		reader = stdout
+	}
+	go b.read_responses(reader)
+	return b, nil
+}
+
+func (b *BridgeStrategy) read_responses(reader io.Reader) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var response RpcResponse
+		if err := json.Unmarshal(scanner.Bytes(), &response); err != nil {
+			log.Printf("bridge %s sent an invalid response: %v", b.spec, err)
+			continue
+		}
+		b.responses <- response
+	}
+	close(b.responses)
+}
+
+func (b *BridgeStrategy) Name() string {
+	return "bridge:" + b.spec
+}
+
+func (b *BridgeStrategy) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	b.next_id++
+	request := RpcRequest{JsonRpc: "2.0", Id: b.next_id, Method: method, Params: params}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := b.writer.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+	deadline := time.After(b.timeout)
+	for {
+		select {
+		case response, ok := <-b.responses:
+			if !ok {
+				return nil, fmt.Errorf("bridge %s closed the connection", b.spec)
+			}
+			// answers to calls that already timed out are dropped
+			if response.Id != request.Id {
+				continue
+			}
+			if response.Error != nil {
+				return nil, fmt.Errorf("bridge error %d: %s", response.Error.Code, response.Error.Message)
+			}
+			return response.Result, nil
+		case <-deadline:
+			return nil, fmt.Errorf("bridge %s did not answer within %v", b.spec, b.timeout)
+		case <-ctx.Done():
+			return nil, fmt.Errorf("bridge %s did not answer in time: %w", b.spec, ctx.Err())
+		}
+	}
+}
+
+func (b *BridgeStrategy) GenerateOrders(state GameState) []Order {
+	return b.GenerateOrdersContext(context.Background(), state)
+}
+
+func (b *BridgeStrategy) GenerateOrdersContext(ctx context.Context, state GameState) []Order {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	result, err := b.call(ctx, "generate_orders", PluginInput{Team: Team, State: state})
+	if err != nil {
+		log.Printf("bridge strategy failed: %v", err)
+		return nil
+	}
+	var decoded []PluginOrder
+	if err := json.Unmarshal(result, &decoded); err != nil {
+		log.Printf("bridge %s returned invalid orders: %v", b.spec, err)
+		return nil
+	}
+	return plugin_orders(decoded)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+// CapabilityRegistry is what every actor type can do, as the rules of the
+// running game tell. It is rebuilt whenever GameRules loads new rules, so
+// strategies follow a server whose actor types differ from the ones it
+// ships with. Types the rules do not list, and all types while the rules
+// are unknown, fall back to default_capabilities.
+type CapabilityRegistry struct {
+	mutex sync.Mutex
+	types map[string]ActorCapabilities
+}
+
+func new_capability_registry() *CapabilityRegistry {
+	return &CapabilityRegistry{types: make(map[string]ActorCapabilities)}
+}
+
+var Capabilities = new_capability_registry()
+
+// Rebuild replaces the registry with the actor properties of new rules and
+// logs where they differ from the defaults.
+func (r *CapabilityRegistry) Rebuild(properties []ActorProperty) {
+	types := make(map[string]ActorCapabilities, len(properties))
+	for _, p := range properties {
+		types[p.Type] = ActorCapabilities{Grab: p.Grab, Attack: p.Attack, Build: p.Build, Destroy: p.Destroy}
+	}
+	r.mutex.Lock()
+	previous := r.types
+	r.types = types
+	r.mutex.Unlock()
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := types[name]
+		if old, ok := previous[name]; ok && old == c {
+			continue
+		}
+		if d, ok := default_capabilities[name]; !ok || d != c {
+			log.Printf("actor type %s from the rules: grab %.2f, attack %.2f, build %.2f, destroy %.2f", name, c.Grab, c.Attack, c.Build, c.Destroy)
+		}
+	}
+}
+
+// Get returns the capabilities of an actor type.
+func (r *CapabilityRegistry) Get(actor_type string) ActorCapabilities {
+	if c, ok := r.Rules(actor_type); ok {
+		return c
+	}
+	if c, ok := default_capabilities[actor_type]; ok {
+		return c
+	}
+	return ActorCapabilities{Grab: 1, Attack: 1}
+}
+
+// Rules returns the capabilities of an actor type only if the rules list
+// it.
+func (r *CapabilityRegistry) Rules(actor_type string) (ActorCapabilities, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, ok := r.types[actor_type]
+	return c, ok
+}
@@ -1,20 +1,48 @@
 package main
 
 import (
-	"net/http"
 	"log"
-	"encoding/json"
-	"time"
 	"fmt"
 	"sort"
+	"flag"
+	"math"
+	"os"
+	"strings"
+	"time"
 )
 
-const (
+var (
 	ServerUrl = "http://127.0.0.1:8000/"
 	Team = "Team 1"
 	Password = "1"
+	// GameID selects one of several games hosted by the same server, the
+	// default game when empty.
+	GameID = ""
 )
 
+func add_connection_flags(flags *flag.FlagSet) {
+	flags.StringVar(&ServerUrl, "server", ServerUrl, "base url of the game server, or a comma separated list to fail over to when it is unreachable")
+	flags.StringVar(&Team, "team", Team, "name of the team to play")
+	flags.StringVar(&Password, "password", Password, "password of the team")
+	flags.StringVar(&PasswordSource, "password-source", PasswordSource, "where the password comes from: flag for -password, env for $ASCIFIGHT_PASSWORD, prompt to ask at startup, or keychain for the OS credential store")
+	flags.Func("auth", "how requests are authenticated: basic with -team and -password, or token with -token (default basic)", set_auth)
+	flags.StringVar(&Token, "token", Token, "API token of the team for -auth token, defaults to $ASCIFIGHT_TOKEN")
+	flags.StringVar(&TokenHeader, "token-header", TokenHeader, "header carrying the API token, sent as a bearer token when it is Authorization")
+	flags.StringVar(&GameID, "game", GameID, "id of the game to play on a server hosting several, included in all state and order URLs")
+	flags.BoolVar(&BatchOrders, "batch-orders", BatchOrders, "submit all orders of a tick in one request when the server offers a batch route")
+	flags.Float64Var(&OrderLimiter.Rate, "order-rate", OrderLimiter.Rate, "orders submitted per second at most, 0 for no limit")
+	flags.IntVar(&OrderLimiter.Burst, "order-burst", OrderLimiter.Burst, "orders submitted at once before -order-rate applies")
+	flags.BoolVar(&StrictSchema, "strict-schema", StrictSchema, "fail on unknown fields in server responses instead of warning")
+	flags.IntVar(&CircuitBreaker.Threshold, "breaker-threshold", CircuitBreaker.Threshold, "consecutive server errors before requests are paused, 0 disables the circuit breaker")
+	flags.DurationVar(&CircuitBreaker.Cooldown, "breaker-cooldown", CircuitBreaker.Cooldown, "initial pause after the circuit breaker tripped")
+}
+
+// normalize_server_url splits -server into the failover endpoints.
+func normalize_server_url() {
+	Servers.Set(ServerUrl)
+	ServerUrl = strings.Join(Servers.List(), ",")
+}
+
 type GameState struct {
 	Teams               []string `json:"teams"`
 	Actors              []Actor  `json:"actors"`
@@ -24,6 +52,12 @@ type GameState struct {
 	Scores              Scores   `json:"scores"`
 	Tick                int      `json:"tick"`
 	TimeOfNextExecution string   `json:"time_of_next_execution"`
+	// Visible are the cells the team can see, absent while the server
+	// shows the whole board.
+	Visible []Coordinates `json:"visible,omitempty"`
+	// Stale counts the ticks the board is behind Tick when the last state
+	// was played on because the server was too slow, see Backpressure.
+	Stale int `json:"stale,omitempty"`
 }
 
 
@@ -73,36 +107,68 @@ func filter_objects[t OwnedObject](objs []t, my_team bool) []t {
 	return filtered
 }
 
-type Wall struct {
-	X int `json:"x"`
-	Y int `json:"y"`
-}
+type Wall = APICoordinates
 
 type Scores map[string]int
 
-type Coordinates struct {
-	X int `json:"x"`
-	Y int `json:"y"`
-}
+type Coordinates = APICoordinates
 
 type Order struct {
 	order_type string
 	actor int
 	direction string
+	reason string
+	priority int
+}
+
+// Order priorities, higher ones are submitted first so that they still make
+// it when the submission window is tight.
+const (
+	PriorityOpportunistic = iota
+	PriorityIntercept
+	PriorityCarrier
+)
+
+// order_priority ranks the orders of flag carriers above those chasing or
+// attacking enemies, and those above everything else.
+func order_priority(actor Actor, action string) int {
+	switch {
+	case actor.Flag != "":
+		return PriorityCarrier
+	case action == "attack":
+		return PriorityIntercept
+	default:
+		return PriorityOpportunistic
+	}
 }
 
-func (o Order) ToUrl() string {
-	format := ServerUrl + "orders/%s/%d?direction=%s"
-	url := fmt.Sprintf(format, o.order_type, o.actor, o.direction)
-	return url
+// prioritize_orders sorts the orders by priority, keeping the order the
+// strategy produced within the same priority.
+func prioritize_orders(orders []Order) {
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].priority > orders[j].priority })
+}
+
+func (o Order) String() string {
+	text := fmt.Sprintf("actor %d %s %s", o.actor, o.order_type, o.direction)
+	if o.reason != "" {
+		text += ": " + o.reason
+	}
+	return text
 }
 
-type Timing struct {
-    Tick                 int     `json:"tick"`
-    TimeToNextExecution  float64 `json:"time_to_next_execution"`
-    TimeOfNextExecution  string  `json:"time_of_next_execution"`
+func describe_position(what string, position Coordinates, dist int) string {
+	return fmt.Sprintf("%s at (%d,%d), dist %d", what, position.X, position.Y, dist)
 }
 
+// The plain response types come from the generated bindings in api_gen.go.
+// The board objects above stay hand-written because the strategies rely on
+// their shared OwnedObject methods.
+type Timing = APITimingResponse
+
+type ActorProperty = APIActorProperty
+
+type Rules = APIRulesResponse
+
 func abs_diff(x int, y int) int {
 	if x > y {
 		return x - y
@@ -131,6 +197,16 @@ func find_path(position Coordinates, target Coordinates) string {
 	}
 }
 
+// find_path_heading is find_path for an actor that last moved in heading: it
+// keeps going that way while that still brings it closer, so that it walks
+// one corner instead of a staircase.
+func find_path_heading(position Coordinates, target Coordinates, heading string) string {
+	if heading != "" && distance(predicted_position(position, heading), target) < distance(position, target) {
+		return heading
+	}
+	return find_path(position, target)
+}
+
 func predicted_position(position Coordinates, dir string) Coordinates {
 	switch(dir) {
 	case "left":
@@ -145,97 +221,304 @@ func predicted_position(position Coordinates, dir string) Coordinates {
 	return position
 }
 
-func seek_target[t OwnedObject](actor Actor, target t, action string, orders []Order) []Order {
+func seek_target[t OwnedObject](actor Actor, target t, action string, why string, orders []Order) []Order {
 	direction := find_path(actor.Coordinates, target.GetCoordinates())
 	dist := distance(actor.Coordinates, target.GetCoordinates())
 	order_type := "move"
 	if dist == 1 {
 		order_type = action
+	} else {
+		direction = Plans.Direction(actor, target.GetCoordinates())
+		if steered, ok := Plans.Steer(actor, target.GetCoordinates(), direction); ok {
+			direction = steered
+		} else {
+			return orders
+		}
 	}
-	orders = append(orders, Order{order_type, actor.Ident, direction})
-	if dist == 2 {
+	reason := describe_position(why, target.GetCoordinates(), dist)
+	priority := order_priority(actor, action)
+	orders = append(orders, Order{order_type, actor.Ident, direction, reason, priority})
+	if dist == 2 && Params.FollowupAction > 0 {
 		new_position := predicted_position(actor.Coordinates, direction)
 		new_direction := find_path(new_position, target.GetCoordinates())
-		orders = append(orders, Order{action, actor.Ident, new_direction})
+		orders = append(orders, Order{action, actor.Ident, new_direction, reason + ", in reach after the move", priority})
 	}
 	return orders
 }
 
 
-func generate_orders(state GameState) []Order {
-	orders := make([]Order, 0)
-	my_actors := filter_objects(state.Actors, true)
-	enemy_flags := filter_objects(state.Flags, false)
-	my_base := filter_objects(state.Bases, true)[0]
-	for _, actor := range(my_actors) {
-		if actor.Flag == "" {
-			sort.Slice(enemy_flags, func (i, j int) bool {return distance(actor.Coordinates, enemy_flags[i].Coordinates) < distance(actor.Coordinates, enemy_flags[j].Coordinates)})
-			nearest_flag := enemy_flags[0]
-			orders = seek_target(actor, nearest_flag, "grabput", orders)
-		} else {
-			orders = seek_target(actor, my_base, "grabput", orders)
+func is_carrying_flag(ctx *BehaviorContext) bool {
+	return ctx.Actor.Flag != ""
+}
+
+func is_defender(ctx *BehaviorContext) bool {
+	return ctx.Index < defender_count(ctx.State, len(ctx.MyActors))
+}
+
+// defender_count is how many of our actors the greedy strategy keeps back,
+// the first ones in the state.
+func defender_count(state GameState, actors int) int {
+	if current_endgame(state).AllIn {
+		// a tie is broken by capturing, not by guarding
+		return 0
+	}
+	return int(math.Round(Params.DefenderShare * float64(actors)))
+}
+
+func guard_base_action(ctx *BehaviorContext) Status {
+	ctx.Orders = guard_base(ctx.Actor, ctx.MyBase, ctx.State, ctx.Orders)
+	return Success
+}
+
+func return_flag_action(ctx *BehaviorContext) Status {
+	ctx.Orders = seek_target(ctx.Actor, ctx.MyBase, "grabput", "carrying "+ctx.Actor.Flag+" flag to our base", ctx.Orders)
+	return Success
+}
+
+func seek_enemy_flag_action(ctx *BehaviorContext) Status {
+	actor := ctx.Actor
+	endgame := current_endgame(ctx.State)
+	enemy_flags := make([]Flag, 0)
+	for _, flag := range filter_objects(ctx.State.Flags, false) {
+		if endgame.RunFeasible(actor, flag.Coordinates, nearest_base(ctx.State, flag.Coordinates).Coordinates) {
+			enemy_flags = append(enemy_flags, flag)
 		}
 	}
-	return orders
+	if len(enemy_flags) == 0 {
+		return Failure
+	}
+	// the run expected to score soonest, not merely the closest flag, and
+	// flags still lying before the ones we already carry. With three or
+	// more teams the flags of the leader seem closer and the weakest's
+	// farther.
+	rivals := assess_rivals(ctx.State)
+	keys := make(map[Coordinates]float64, len(enemy_flags))
+	for _, flag := range enemy_flags {
+		home := nearest_base(ctx.State, flag.Coordinates)
+		ticks := estimate_capture(ctx.State, actor, flag.Coordinates, home.Coordinates).TicksToScore()
+		keys[flag.Coordinates] = jitter(ticks * math.Max(0.1, 1-Params.UtilityRival*rival_bias(rivals, flag.Team)/2))
+		if other, carried := carrier(ctx.State, flag); carried && other.Team == Team {
+			keys[flag.Coordinates] += float64(board_size(ctx.State, GameRules.MapSize()) * 4)
+		}
+	}
+	sort.Slice(enemy_flags, func (i, j int) bool {return keys[enemy_flags[i].Coordinates] < keys[enemy_flags[j].Coordinates]})
+	ctx.Orders = seek_target(actor, enemy_flags[0], "grabput", "nearest enemy flag of "+enemy_flags[0].Team, ctx.Orders)
+	return Success
 }
 
-func submit_orders(orders []Order) {
-	for _, order := range orders {
-		url := order.ToUrl()
-		log.Printf("submitting order: %v", order)
-		req, err := http.NewRequest("POST", url, nil)
-		req.SetBasicAuth(Team, Password)
-		client := &http.Client{}
-		//resp, err := http.Post(url, "application/json", nil)
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Fatalln(err)
+var greedy_tree = Selector{
+	Sequence{Condition(must_deny_capture), Inverter{Condition(is_carrying_flag)}, Action(deny_capture_action)},
+	Sequence{Condition(is_defender), Inverter{Condition(is_carrying_flag)}, Action(guard_base_action)},
+	Sequence{Condition(is_carrying_flag), Action(return_flag_action)},
+	Sequence{Condition(must_deny_leader), Action(deny_leader_action)},
+	Sequence{Condition(can_build), Action(build_wall_action)},
+	Sequence{Condition(can_destroy), Action(demolish_action)},
+	Action(seek_enemy_flag_action),
+	Sequence{Condition(is_closing), Action(guard_base_action)},
+}
+
+func generate_orders(state GameState) []Order {
+	Plans.Observe(state)
+	actors := filter_objects(state.Actors, true)
+	defenders := make([]Actor, 0)
+	for i, actor := range actors {
+		if i < defender_count(state, len(actors)) && actor.Flag == "" {
+			defenders = append(defenders, actor)
 		}
-		log.Printf("%d", resp.StatusCode)
-		resp.Body.Close()
 	}
+	Zones.Assign(state, defenders)
+	return run_behavior(greedy_tree, state)
 }
 
-func get_state(t string, v any) {
-	url := ServerUrl + "states/" + t
-	resp, err := http.Get(url)
-	if err != nil {
-		log.Fatalln(err)
+// guard_base keeps a defender next to our base and sends it after enemies
+// that come within the guard radius. Defenders with a zone guard that
+// instead, and the ones split off to another of our bases guard that one.
+func guard_base(actor Actor, base Base, state GameState, orders []Order) []Order {
+	if zone, intruder, engage, zoned := Zones.Guard(actor, state); zoned {
+		return guard_zone(actor, zone, intruder, engage, orders)
 	}
-	defer resp.Body.Close()
-	decoder := json.NewDecoder(resp.Body)
-	err = decoder.Decode(&v)
-	if err != nil {
-		log.Fatalln(err)
+	if assigned, ok := Zones.Base(actor); ok {
+		base = assigned
+	}
+	intruders := make([]Actor, 0)
+	for _, enemy := range filter_objects(state.Actors, false) {
+		if float64(distance(enemy.Coordinates, base.Coordinates)) <= Params.GuardRadius {
+			intruders = append(intruders, enemy)
+		}
+	}
+	if len(intruders) > 0 {
+		sort.Slice(intruders, func (i, j int) bool {return distance(actor.Coordinates, intruders[i].Coordinates) < distance(actor.Coordinates, intruders[j].Coordinates)})
+		why := fmt.Sprintf("intruder %s actor %d near our base", intruders[0].Team, intruders[0].Ident)
+		return seek_target(actor, intruders[0], "attack", why, orders)
+	}
+	if distance(actor.Coordinates, base.Coordinates) > 1 {
+		reason := describe_position("returning to guard our base", base.Coordinates, distance(actor.Coordinates, base.Coordinates))
+		orders = append(orders, Order{"move", actor.Ident, Plans.Direction(actor, base.Coordinates), reason, PriorityOpportunistic})
 	}
+	return orders
 }
 
-func game_state() (GameState) {
-	var state GameState
-	get_state("game_state", &state)
-	return state
+var subcommands = map[string]func(args []string){
+	"stats":       stats_command,
+	"tournament":  tournament_command,
+	"arena":       arena_command,
+	"tune":        tune_command,
+	"manual":      manual_command,
+	"console":     console_command,
+	"watch":       watch_command,
+	"admin":       admin_command,
+	"register":    register_command,
+	"keychain":    keychain_command,
+	"ab":          ab_command,
+	"bench":       bench_command,
+	"replay":      replay_command,
+	"integration": integration_command,
+	"scenario":    scenario_command,
+	"golden":      golden_command,
 }
 
-func timing() (Timing) {
-	var t Timing
-	get_state("timing", &t)
-	return t
+func main() {
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+	run_bot(os.Args[1:])
 }
 
-func main() {
-	current_tick := 0
-	for {
-		t := timing()
-		if t.Tick == current_tick {
-			sleep_duration := time.Duration(t.TimeToNextExecution * float64(time.Second))
-			time.Sleep(sleep_duration)
-		} else {
-			current_tick = t.Tick
-			state := game_state()
-			orders := generate_orders(state)
-			submit_orders(orders)
-			log.Printf("state recieved: %v", state)
+func run_bot(args []string) {
+	flags := flag.NewFlagSet("bot", flag.ExitOnError)
+	stats_dir := flags.String("stats-dir", "stats", "directory for per-game stats files and the results ledger, empty to disable")
+	stats_format := flags.String("stats-format", "json,csv", "comma separated list of stats formats (json, csv)")
+	strategy_name := flags.String("strategy", "greedy", "strategy to play with")
+	strategy_plugin := flags.String("strategy-plugin", "", "Go plugin exporting a Strategy, overrides -strategy")
+	dry_run := flags.Bool("dry-run", false, "compute orders every tick but only log them")
+	step := flags.Bool("step", false, "start paused and review the orders of every tick before they are submitted")
+	pausable := flags.Bool("pausable", false, "read p from stdin to pause the bot and review orders tick by tick")
+	dashboard := flags.String("dashboard", "", "address to serve the live dashboard on, e.g. :8080")
+	snapshots := flags.String("snapshots", "", "directory for per-tick PNG board snapshots and an animated GIF per game")
+	journal := flags.String("journal", "", "file keeping the orders of a tick until they are submitted, so that a bot restarted after a crash submits them in time")
+	record := flags.String("record", "", "directory to record every game to, for the replay subcommand")
+	webhook := flags.String("webhook", "", "Discord or Slack compatible webhook URL notified about game start, captures, lead changes and game end")
+	health_address := flags.String("health", "", "address to serve /healthz and /readyz on, e.g. :8081")
+	health_stale := flags.Duration("health-stale", 30*time.Second, "time without a successful state fetch after which /healthz reports failure")
+	pprof_address := flags.String("pprof", "", "debug address to serve net/http/pprof profiles on, e.g. localhost:6060")
+	otlp_endpoint := flags.String("otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTLP/HTTP collector to export tick traces to, e.g. http://localhost:4318")
+	version_check := flags.String("version-check", "strict", "refuse to run against an incompatible server (strict), only warn (warn) or skip the check (off)")
+	reuse_unchanged := flags.Bool("reuse-unchanged", false, "resubmit the previous orders without running the strategy while the board is unchanged")
+	poll_offset := flags.Duration("poll-offset", 10*time.Millisecond, "delay after the announced tick execution before polling for the new tick")
+	counter_tactics := flags.Bool("counter-tactics", true, "classify the opponents' play and adapt the strategy parameters to counter it")
+	phase_roles := flags.Bool("phase-roles", true, "shift the roles of our actors between attack and defense with the phase of the game")
+	seed := add_seed_flag(flags)
+	flags.Float64Var(&World.Optimism, "fog-optimism", World.Optimism, "how the path search treats cells never seen, from 0 (blocked) to 1 (free)")
+	deadline_margin := flags.Duration("deadline-margin", 50*time.Millisecond, "time before the next tick execution by which fetching, deciding and submitting must be done")
+	acceptance_window := flags.Int("acceptance-window", 10, "ticks over which the share of accepted orders is compared with the game so far, 0 to not watch it")
+	acceptance_drop := flags.Float64("acceptance-drop", 0.3, "drop of the accepted share of orders that raises an alert, as a fraction")
+	flags.Float64Var(&Pressure.Threshold, "backpressure", Pressure.Threshold, "share of the tick interval the state fetches may take before the bot skips optional requests and plans less, 0 to never degrade")
+	watchdog := flags.Int("watchdog", 5, "restart the tick loop when it stalled for this many tick intervals, 0 to never")
+	watchdog_min := flags.Duration("watchdog-min", 15*time.Second, "shortest stall of the tick loop the watchdog restarts it for")
+	log_requests := flags.Bool("log-requests", false, "log every request with its status and duration at debug verbosity")
+	request_metrics := flags.Bool("request-metrics", false, "count requests per route and log their latencies after every game")
+	fault_rate := flags.Float64("fault-rate", 0, "answer this share of requests with an injected 503, to test how the bot copes")
+	fault_delay := flags.Duration("fault-delay", 0, "delay every request by a random time up to this, to test how the bot copes")
+	bridge := flags.String("bridge", "", "external strategy command or tcp:<address>/unix:<path> socket speaking JSON-RPC, overrides -strategy")
+	add_connection_flags(flags)
+	config_flags := add_config_flags(flags)
+	log_flags := add_log_flags(flags)
+	flags.Parse(args)
+	if err := config_flags.Apply(); err != nil {
+		log.Fatalln(err)
+	}
+	normalize_server_url()
+	if err := resolve_password(); err != nil {
+		log.Fatalln(err)
+	}
+	if err := log_flags.Apply(); err != nil {
+		log.Fatalln(err)
+	}
+	if *strategy_plugin != "" {
+		*strategy_name = "plugin:" + *strategy_plugin
+	}
+	if *bridge != "" {
+		*strategy_name = "bridge:" + *bridge
+	}
+
+	if *version_check != "off" {
+		if err := check_server_version(); err != nil {
+			if *version_check == "strict" {
+				log.Fatalln(err, "(use -version-check=warn to play anyway)")
+			}
+			log.Printf("warning: %v", err)
+		}
+	}
+	if GameID != "" {
+		if err := check_game(); err != nil {
+			log.Fatalln(err)
 		}
+	}
+	if *pprof_address != "" {
+		start_pprof(*pprof_address)
+	}
 
+	Random.Seed(*seed)
+	logf(VerbosityNormal, "random seed %d", Random.Current())
+
+	strategy, err := lookup_strategy(*strategy_name)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if *log_requests {
+		DefaultClient.Use(LoggingMiddleware(VerbosityDebug))
+	}
+	var metrics *RequestMetrics
+	if *request_metrics {
+		metrics = new_request_metrics()
+		DefaultClient.Use(metrics.Middleware())
+	}
+	if *fault_rate > 0 || *fault_delay > 0 {
+		log.Printf("warning: injecting faults into %.0f%% of the requests", *fault_rate*100)
+		DefaultClient.Use(FaultMiddleware(*fault_rate, *fault_delay, Random.Current()))
 	}
+	options := BotOptions{StatsDir: *stats_dir, StatsFormat: *stats_format, DryRun: *dry_run, ReuseUnchanged: *reuse_unchanged, PollOffset: *poll_offset, DeadlineMargin: *deadline_margin, CounterTactics: *counter_tactics, PhaseRoles: *phase_roles}
+	if *dashboard != "" {
+		options.Dashboard = start_dashboard(*dashboard)
+	}
+	if *snapshots != "" {
+		options.Snapshots = new_snapshot_recorder(*snapshots)
+	}
+	if *journal != "" {
+		options.Journal = new_order_journal(*journal)
+	}
+	if *record != "" {
+		options.Recorder = new_game_recorder(*record)
+	}
+	if *webhook != "" {
+		options.Notifier = new_notifier(*webhook)
+	}
+	if *acceptance_window > 0 && !*dry_run {
+		options.Acceptance = new_acceptance_monitor(*acceptance_window, *acceptance_drop)
+		if options.Notifier != nil {
+			options.Acceptance.OnAlert = options.Notifier.Alert
+		}
+	}
+	if *health_address != "" {
+		options.Health = new_health(*health_stale)
+		options.Health.serve(*health_address)
+	}
+	if *otlp_endpoint != "" {
+		options.Tracer = new_tracer(*otlp_endpoint, "ascifight-client")
+	}
+	if *step || *pausable {
+		options.Stepper = new_stepper(os.Stdin, os.Stdout, *step)
+	} else if *watchdog > 0 {
+		// a paused loop is not stalled, so there is no watchdog while stepping
+		options.Watchdog = new_watchdog(*watchdog, *watchdog_min)
+	}
+	play(strategy, options, func(stats *GameStats) (Strategy, bool) {
+		if metrics != nil {
+			log.Printf("requests\n%s", metrics.Report())
+		}
+		return strategy, true
+	})
 }
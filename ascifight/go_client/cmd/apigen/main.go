@@ -0,0 +1,366 @@
+// apigen generates the typed server bindings in api_gen.go from the
+// server's OpenAPI schema. It runs through go generate:
+//
+//	go generate
+//
+// and refreshes the stored schema from a running server with
+//
+//	go run ./cmd/apigen -url http://127.0.0.1:8000/openapi.json
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Title                string             `json:"title"`
+	Description          string             `json:"description"`
+	Enum                 []string           `json:"enum"`
+	Items                *Schema            `json:"items"`
+	AllOf                []*Schema          `json:"allOf"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	order                []string
+}
+
+// UnmarshalJSON keeps the declaration order of the properties, which is
+// what makes positional literals of the generated structs possible.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	var raw struct {
+		Properties json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil || len(raw.Properties) == 0 {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw.Properties))
+	decoder.Token()
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		s.order = append(s.order, key.(string))
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Operation struct {
+	Summary     string      `json:"summary"`
+	Description string      `json:"description"`
+	Parameters  []Parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]MediaType `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]MediaType `json:"content"`
+	} `json:"responses"`
+	Security []map[string][]string `json:"security"`
+}
+
+type Spec struct {
+	Info struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+func go_name(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == ' ' || r == '-' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func snake_name(summary string) string {
+	return strings.ToLower(strings.Join(strings.Fields(summary), "_"))
+}
+
+func ref_name(ref string) string {
+	return "API" + go_name(strings.TrimPrefix(ref, "#/components/schemas/"))
+}
+
+func go_type(s *Schema) string {
+	switch {
+	case s == nil:
+		return "interface{}"
+	case s.Ref != "":
+		return ref_name(s.Ref)
+	case len(s.AllOf) == 1:
+		return go_type(s.AllOf[0])
+	}
+	switch s.Type {
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "string":
+		return "string"
+	case "array":
+		return "[]" + go_type(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + go_type(s.AdditionalProperties)
+		}
+	}
+	return "interface{}"
+}
+
+func comment(b *bytes.Buffer, text string) {
+	text = strings.TrimSpace(strings.SplitN(text, "\n\n", 2)[0])
+	if text != "" {
+		fmt.Fprintf(b, "// %s\n", strings.ReplaceAll(text, "\n", "\n// "))
+	}
+}
+
+func write_schema(b *bytes.Buffer, name string, s *Schema) {
+	comment(b, s.Description)
+	if len(s.Enum) > 0 {
+		fmt.Fprintf(b, "type %s string\n\nconst (\n", name)
+		for _, value := range s.Enum {
+			fmt.Fprintf(b, "%s%s %s = %q\n", name, go_name(value), name, value)
+		}
+		b.WriteString(")\n\n")
+		return
+	}
+	required := make(map[string]bool)
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	fmt.Fprintf(b, "type %s struct {\n", name)
+	for _, property := range s.order {
+		field := s.Properties[property]
+		comment(b, field.Description)
+		tag := property
+		if !required[property] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(b, "%s %s `json:%q`\n", go_name(property), go_type(field), tag)
+	}
+	b.WriteString("}\n\n")
+}
+
+// body_schema returns the schema FastAPI generated for the request body.
+// Its properties become separate arguments of the binding.
+func body_schema(spec *Spec, op *Operation) *Schema {
+	if op.RequestBody == nil {
+		return nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok || media.Schema == nil {
+		return nil
+	}
+	if media.Schema.Ref != "" {
+		return spec.Components.Schemas[strings.TrimPrefix(media.Schema.Ref, "#/components/schemas/")]
+	}
+	return media.Schema
+}
+
+func write_operation(b *bytes.Buffer, imports map[string]bool, spec *Spec, method string, path string, op *Operation) {
+	name := "api_" + snake_name(op.Summary)
+	args := make([]string, 0)
+	path_expr := fmt.Sprintf("%q", strings.TrimPrefix(path, "/"))
+	query := make([]string, 0)
+	for _, p := range op.Parameters {
+		arg := p.Name
+		switch p.In {
+		case "path":
+			args = append(args, arg+" "+go_type(p.Schema))
+			placeholder := "{" + p.Name + "}"
+			parts := strings.SplitN(strings.TrimPrefix(path, "/"), placeholder, 2)
+			value := "fmt.Sprint(" + arg + ")"
+			path_expr = fmt.Sprintf("%q + %s + %q", parts[0], value, parts[1])
+			imports["fmt"] = true
+		case "query":
+			args = append(args, arg+" "+go_type(p.Schema))
+			query = append(query, fmt.Sprintf("query.Set(%q, fmt.Sprint(%s))", p.Name, arg))
+			imports["fmt"] = true
+			imports["net/url"] = true
+		}
+	}
+	body := body_schema(spec, op)
+	if body != nil {
+		for _, property := range body.order {
+			args = append(args, property+" "+go_type(body.Properties[property]))
+		}
+	}
+	result := "[]byte"
+	if success := op.Responses["200"]; success.Content != nil {
+		if media, ok := success.Content["application/json"]; ok {
+			result = go_type(media.Schema)
+		}
+	}
+	text := op.Description
+	if text == "" {
+		text = op.Summary
+	}
+	names := make([]string, 0, len(args))
+	for _, arg := range args {
+		names = append(names, strings.Fields(arg)[0])
+	}
+	comment(b, name+" calls "+strings.ToUpper(method)+" "+path+". "+text)
+	fmt.Fprintf(b, "func %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), result)
+	fmt.Fprintf(b, "return %s_context(%s)\n}\n\n", name, strings.Join(append([]string{"context.Background()"}, names...), ", "))
+	imports["context"] = true
+	fmt.Fprintf(b, "func %s_context(%s) (%s, error) {\n", name, strings.Join(append([]string{"ctx context.Context"}, args...), ", "), result)
+	query_expr := "nil"
+	if len(query) > 0 {
+		b.WriteString("query := url.Values{}\n")
+		b.WriteString(strings.Join(query, "\n") + "\n")
+		query_expr = "query"
+	}
+	body_expr := "nil"
+	if body != nil {
+		b.WriteString("body := map[string]interface{}{\n")
+		for _, property := range body.order {
+			fmt.Fprintf(b, "%q: %s,\n", property, property)
+		}
+		b.WriteString("}\n")
+		body_expr = "body"
+	}
+	fmt.Fprintf(b, "var result %s\n", result)
+	fmt.Fprintf(b, "err := api_request(ctx, %q, %s, %s, %s, %t, &result)\n", strings.ToUpper(method), path_expr, query_expr, body_expr, len(op.Security) > 0)
+	b.WriteString("return result, err\n}\n\n")
+}
+
+func generate(spec *Spec, source string) ([]byte, error) {
+	var b bytes.Buffer
+	imports := make(map[string]bool)
+	fmt.Fprintf(&b, "// APIVersion is the version of %q the bindings were generated from.\n", spec.Info.Title)
+	fmt.Fprintf(&b, "const APIVersion = %q\n\n", spec.Info.Version)
+	names := make([]string, 0, len(spec.Components.Schemas))
+	for name := range spec.Components.Schemas {
+		// request bodies are flattened into the arguments of the bindings
+		if !strings.HasPrefix(name, "Body_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		write_schema(&b, "API"+go_name(name), spec.Components.Schemas[name])
+	}
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		methods := make([]string, 0)
+		for method := range spec.Paths[path] {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			write_operation(&b, imports, spec, method, path, spec.Paths[path][method])
+		}
+	}
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "// Code generated by apigen from %s; DO NOT EDIT.\n\npackage main\n\n", source)
+	if len(imports) > 0 {
+		sorted := make([]string, 0, len(imports))
+		for name := range imports {
+			sorted = append(sorted, fmt.Sprintf("%q", name))
+		}
+		sort.Strings(sorted)
+		fmt.Fprintf(&header, "import (\n%s\n)\n\n", strings.Join(sorted, "\n"))
+	}
+	b.WriteTo(&header)
+	b = header
+	formatted, err := format.Source(b.Bytes())
+	if err != nil {
+		return b.Bytes(), err
+	}
+	return formatted, nil
+}
+
+func main() {
+	spec_path := flag.String("spec", "openapi.json", "OpenAPI schema to generate from")
+	out := flag.String("out", "api_gen.go", "file to write the bindings to")
+	fetch := flag.String("url", "", "fetch the schema from a running server into -spec first")
+	flag.Parse()
+
+	if *fetch != "" {
+		resp, err := http.Get(*fetch)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			log.Fatalf("fetching %s failed: %v %s", *fetch, err, resp.Status)
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, data, "", "  "); err != nil {
+			log.Fatalln(err)
+		}
+		indented.WriteString("\n")
+		if err := os.WriteFile(*spec_path, indented.Bytes(), 0644); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	data, err := os.ReadFile(*spec_path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		log.Fatalf("parsing %s: %v", *spec_path, err)
+	}
+	source, err := generate(&spec, *spec_path)
+	if err != nil {
+		log.Fatalf("formatting generated code: %v", err)
+	}
+	if err := os.WriteFile(*out, source, 0644); err != nil {
+		log.Fatalln(err)
+	}
+}
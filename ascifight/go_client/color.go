@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// team_colors are the ANSI colors the server uses for the teams, by team
+// number.
+var team_colors = []string{"\u001b[31m", "\u001b[32m", "\u001b[33m", "\u001b[34m", "\u001b[35m", "\u001b[36m"}
+
+const (
+	color_bold   = "\u001b[1m"
+	color_revert = "\u001b[0m"
+	// the server's board symbols
+	flag_icon = "▲"
+	base_icon = "◙"
+	wall_icon = "█"
+)
+
+// UseColor enables colored terminal output of the board and the scores.
+var UseColor = false
+
+// is_terminal reports whether f is an interactive terminal rather than a
+// file or a pipe.
+func is_terminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// set_color_mode applies -color: always, never, or auto for color when
+// stderr is a terminal, no log file is written and NO_COLOR is unset.
+func set_color_mode(mode string, log_file bool) error {
+	switch mode {
+	case "always":
+		UseColor = true
+	case "never":
+		UseColor = false
+	case "auto":
+		_, no_color := os.LookupEnv("NO_COLOR")
+		UseColor = !no_color && !log_file && is_terminal(os.Stderr)
+	default:
+		return fmt.Errorf("unknown color mode %q, expected auto, always or never", mode)
+	}
+	return nil
+}
+
+// ansi_team_color wraps text in the color of the team with the given number.
+// Teams beyond the server's colors and disabled color leave text as is.
+func ansi_team_color(number int, text string) string {
+	if !UseColor || number < 0 || number >= len(team_colors) {
+		return text
+	}
+	return team_colors[number] + text + color_revert
+}
+
+// colored_scores is format_scores with every team in its color and ours in
+// bold, for the terminal.
+func colored_scores(state GameState) string {
+	if !UseColor {
+		return format_scores(state.Scores)
+	}
+	parts := strings.Split(format_scores(state.Scores), ", ")
+	for i, part := range parts {
+		team := part[:strings.LastIndex(part, " ")]
+		parts[i] = ansi_team_color(team_index(state, team), part)
+		if team == Team {
+			parts[i] = color_bold + parts[i]
+		}
+	}
+	return strings.Join(parts, ", ")
+}
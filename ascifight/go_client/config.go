@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const default_config_file = "client_config.json"
+
+// ClientConfig is the client side configuration file. Values that are not
+// present in the file keep their built-in defaults.
+type ClientConfig struct {
+	Params   StrategyParams     `json:"params"`
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Profile bundles flag values under a name selected with -profile, e.g.
+//
+//	"tournament": {"server": "http://10.0.0.1:8000/", "team": "Team 3",
+//		"password": "secret", "strategy": "utility", "log-file": "bot.log"}
+//
+// Flags given on the command line take precedence over the profile.
+type Profile map[string]string
+
+func default_config() ClientConfig {
+	return ClientConfig{Params: default_params()}
+}
+
+func load_config(path string) (ClientConfig, error) {
+	config := default_config()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+func write_config(path string, config ClientConfig) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+type ConfigFlags struct {
+	flags   *flag.FlagSet
+	config  string
+	params  string
+	profile string
+}
+
+// add_config_flags registers -config, -profile and -params. Apply has to
+// run after parsing, so -params always overrides the file regardless of
+// flag order, and before the other flags are used, as the profile sets them.
+func add_config_flags(flags *flag.FlagSet) *ConfigFlags {
+	f := &ConfigFlags{flags: flags}
+	flags.StringVar(&f.config, "config", default_config_file, "client configuration file")
+	flags.StringVar(&f.params, "params", "", "comma separated name=value strategy parameters overriding the configuration")
+	flags.StringVar(&f.profile, "profile", "", "named profile of the configuration file whose flag values to use")
+	return f
+}
+
+func (f *ConfigFlags) Apply() error {
+	config, err := load_config(f.config)
+	if err != nil && !(os.IsNotExist(err) && f.config == default_config_file) {
+		return err
+	}
+	if f.profile != "" {
+		if err := f.apply_profile(config); err != nil {
+			return err
+		}
+	}
+	Params, err = parse_params(config.Params, f.params)
+	return err
+}
+
+func (f *ConfigFlags) apply_profile(config ClientConfig) error {
+	profile, ok := config.Profiles[f.profile]
+	if !ok {
+		names := make([]string, 0, len(config.Profiles))
+		for name := range config.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%s has no profile %q, known profiles: %s", f.config, f.profile, strings.Join(names, ", "))
+	}
+	explicit := make(map[string]bool)
+	f.flags.Visit(func(fl *flag.Flag) { explicit[fl.Name] = true })
+	for name, value := range profile {
+		if explicit[name] {
+			continue
+		}
+		if name == "profile" || name == "config" {
+			return fmt.Errorf("profile %q cannot set -%s", f.profile, name)
+		}
+		if f.flags.Lookup(name) == nil {
+			return fmt.Errorf("profile %q sets unknown flag -%s", f.profile, name)
+		}
+		if err := f.flags.Set(name, value); err != nil {
+			return fmt.Errorf("profile %q: -%s: %w", f.profile, name, err)
+		}
+	}
+	return nil
+}
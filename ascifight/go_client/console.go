@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var console_order_types = map[string]bool{"move": true, "grabput": true, "attack": true, "build": true, "destroy": true}
+
+var console_directions = map[string]bool{"left": true, "right": true, "up": true, "down": true}
+
+const console_help = `commands:
+  move|grabput|attack|build|destroy <actor> <left|right|up|down>
+  state [teams|actors|flags|bases|walls|all]
+  score            current and overall scores
+  timing           current tick and time to the next one
+  rules            game rules and actor properties
+  board            draw the board
+  help             show this help
+  quit             leave the console`
+
+func print_json(out io.Writer, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return
+	}
+	fmt.Fprintln(out, string(data))
+}
+
+type AllScores = APIAllScoresResponse
+
+func console_state(out io.Writer, what string) error {
+	state, err := DefaultClient.GameState(context.Background())
+	if err != nil {
+		return err
+	}
+	switch what {
+	case "", "all":
+		print_json(out, state)
+	case "teams":
+		print_json(out, state.Teams)
+	case "actors":
+		sort.Slice(state.Actors, func(i, j int) bool {
+			if state.Actors[i].Team != state.Actors[j].Team {
+				return state.Actors[i].Team < state.Actors[j].Team
+			}
+			return state.Actors[i].Ident < state.Actors[j].Ident
+		})
+		for _, actor := range state.Actors {
+			carrying := ""
+			if actor.Flag != "" {
+				carrying = ", carrying flag of " + actor.Flag
+			}
+			fmt.Fprintf(out, "%s %d %s at (%d,%d)%s\n", actor.Team, actor.Ident, actor.Type, actor.Coordinates.X, actor.Coordinates.Y, carrying)
+		}
+	case "flags":
+		for _, flag := range state.Flags {
+			fmt.Fprintf(out, "flag of %s at (%d,%d)\n", flag.Team, flag.Coordinates.X, flag.Coordinates.Y)
+		}
+	case "bases":
+		for _, base := range state.Bases {
+			fmt.Fprintf(out, "base of %s at (%d,%d)\n", base.Team, base.Coordinates.X, base.Coordinates.Y)
+		}
+	case "walls":
+		print_json(out, state.Walls)
+	default:
+		return fmt.Errorf("unknown state part %q", what)
+	}
+	return nil
+}
+
+// console_execute runs a single console line and reports whether the
+// console should keep running.
+func console_execute(out io.Writer, line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true, nil
+	}
+	command := strings.ToLower(fields[0])
+	switch {
+	case console_order_types[command]:
+		if len(fields) != 3 {
+			return true, fmt.Errorf("usage: %s <actor> <direction>", command)
+		}
+		actor, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return true, fmt.Errorf("actor must be a number: %w", err)
+		}
+		if !console_directions[fields[2]] {
+			return true, fmt.Errorf("unknown direction %q", fields[2])
+		}
+		order := Order{command, actor, fields[2], "console", PriorityOpportunistic}
+		if err := DefaultClient.SubmitOrder(context.Background(), order); err != nil {
+			return true, err
+		}
+		fmt.Fprintf(out, "submitted %s\n", order)
+	case command == "state":
+		what := ""
+		if len(fields) > 1 {
+			what = fields[1]
+		}
+		return true, console_state(out, what)
+	case command == "score" || command == "scores":
+		scores, err := DefaultClient.Scores(context.Background())
+		if err != nil {
+			return true, err
+		}
+		for _, s := range scores.Scores {
+			fmt.Fprintf(out, "%-20s %5d\n", s.Team, s.Score)
+		}
+		fmt.Fprintln(out, "overall:")
+		for _, s := range scores.OverallScores {
+			fmt.Fprintf(out, "%-20s %5d\n", s.Team, s.Score)
+		}
+	case command == "timing":
+		t, err := DefaultClient.Timing(context.Background())
+		if err != nil {
+			return true, err
+		}
+		fmt.Fprintf(out, "tick %d, next execution in %.2fs at %s\n", t.Tick, t.TimeToNextExecution, t.TimeOfNextExecution)
+	case command == "rules":
+		r, err := DefaultClient.Rules(context.Background())
+		if err != nil {
+			return true, err
+		}
+		print_json(out, r)
+	case command == "board":
+		state, err := DefaultClient.GameState(context.Background())
+		if err != nil {
+			return true, err
+		}
+		fmt.Fprint(out, render_board(state, GameRules.MapSize()))
+	case command == "help" || command == "?":
+		fmt.Fprintln(out, console_help)
+	case command == "quit" || command == "exit":
+		return false, nil
+	default:
+		return true, fmt.Errorf("unknown command %q, type help", command)
+	}
+	return true, nil
+}
+
+func console_command(args []string) {
+	flags := flag.NewFlagSet("console", flag.ExitOnError)
+	add_connection_flags(flags)
+	flags.Parse(args)
+	normalize_server_url()
+	if err := resolve_password(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("connected to %s as %s, type help for commands\n", Servers.Active(), Team)
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return
+		}
+		running, err := console_execute(os.Stdout, scanner.Text())
+		if err != nil {
+			fmt.Println("error:", err)
+		}
+		if !running {
+			return
+		}
+	}
+}
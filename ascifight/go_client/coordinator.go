@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// ActorPlanner proposes the options of one actor. It runs concurrently for
+// all our actors, so it must only read the state it is given.
+type ActorPlanner func(actor Actor, state GameState, base Base) []UtilityOption
+
+type ControllerInput struct {
+	Actor Actor
+	State GameState
+	Base  Base
+	Reply chan<- Proposal
+}
+
+// Proposal is what a controller suggests for its actor at one tick.
+type Proposal struct {
+	Ident   int
+	Options []UtilityOption
+}
+
+// ActorController owns one of our actors. It waits for states on its
+// channel and answers each with a proposal until the channel is closed.
+type ActorController struct {
+	Ident  int
+	inputs chan ControllerInput
+}
+
+func start_actor_controller(ident int, plan ActorPlanner) *ActorController {
+	c := &ActorController{Ident: ident, inputs: make(chan ControllerInput, 1)}
+	go func() {
+		for input := range c.inputs {
+			params_readers.RLock()
+			proposal := c.propose(plan, input)
+			params_readers.RUnlock()
+			input.Reply <- proposal
+		}
+	}()
+	return c
+}
+
+func (c *ActorController) propose(plan ActorPlanner, input ControllerInput) (p Proposal) {
+	p.Ident = c.Ident
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("controller of actor %d panicked at tick %d: %v", c.Ident, input.State.Tick, r)
+			p.Options = nil
+		}
+	}()
+	p.Options = plan(input.Actor, input.State, input.Base)
+	return p
+}
+
+// Coordinator is a strategy that hands the state to one controller
+// goroutine per actor and resolves their proposals into a conflict free
+// joint assignment. Controllers that do not answer before the deadline
+// leave their actor without orders for that tick.
+type Coordinator struct {
+	name        string
+	plan        ActorPlanner
+	mutex       sync.Mutex
+	controllers map[int]*ActorController
+}
+
+func new_coordinator(name string, plan ActorPlanner) *Coordinator {
+	return &Coordinator{name: name, plan: plan, controllers: make(map[int]*ActorController)}
+}
+
+func (c *Coordinator) Name() string {
+	return c.name
+}
+
+func (c *Coordinator) GenerateOrders(state GameState) []Order {
+	return c.GenerateOrdersContext(context.Background(), state)
+}
+
+func (c *Coordinator) GenerateOrdersContext(ctx context.Context, state GameState) []Order {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	orders := make([]Order, 0)
+	if len(filter_objects(state.Bases, true)) == 0 {
+		return orders
+	}
+	Plans.Observe(state)
+	// the controllers read the intents, so they are planned before asking
+	Intents.Plan(state)
+	actors := filter_objects(state.Actors, true)
+	c.update_controllers(actors)
+	// buffered, so controllers that answer too late never block
+	replies := make(chan Proposal, len(actors))
+	asked := 0
+	for _, actor := range actors {
+		select {
+		case c.controllers[actor.Ident].inputs <- ControllerInput{Actor: actor, State: state, Base: nearest_base(state, actor.Coordinates), Reply: replies}:
+			asked++
+		default:
+			log.Printf("controller of actor %d is still busy with an earlier tick", actor.Ident)
+		}
+	}
+	proposals := make(map[int][]UtilityOption, len(actors))
+collect:
+	for len(proposals) < asked {
+		select {
+		case p := <-replies:
+			proposals[p.Ident] = p.Options
+		case <-ctx.Done():
+			log.Printf("%d of %d actor controllers answered in time", len(proposals), asked)
+			break collect
+		}
+	}
+	planned := make([]Actor, 0, len(proposals))
+	options := make([][]UtilityOption, 0, len(proposals))
+	for _, actor := range actors {
+		if p, ok := proposals[actor.Ident]; ok {
+			planned = append(planned, actor)
+			options = append(options, p)
+		}
+	}
+	search_ctx, cancel := planning_context(ctx)
+	defer cancel()
+	assignments := best_joint_assignment_context(search_ctx, planned, options)
+	assign_zones(state, assignments)
+	for _, assignment := range assignments {
+		orders = utility_orders(assignment, state, nearest_base(state, assignment.Actor.Coordinates), orders)
+	}
+	return orders
+}
+
+// update_controllers starts controllers for new actors and stops those of
+// actors that are gone.
+func (c *Coordinator) update_controllers(actors []Actor) {
+	present := make(map[int]bool, len(actors))
+	for _, actor := range actors {
+		present[actor.Ident] = true
+		if _, ok := c.controllers[actor.Ident]; !ok {
+			c.controllers[actor.Ident] = start_actor_controller(actor.Ident, c.plan)
+		}
+	}
+	for ident, controller := range c.controllers {
+		if !present[ident] {
+			close(controller.inputs)
+			delete(c.controllers, ident)
+		}
+	}
+}
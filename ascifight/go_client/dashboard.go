@@ -0,0 +1,160 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed dashboard.html
+var dashboard_html []byte
+
+// DashboardSnapshot is everything the dashboard shows about a single tick.
+type DashboardSnapshot struct {
+	Team       string            `json:"team"`
+	Strategy   string            `json:"strategy"`
+	Tick       int               `json:"tick"`
+	Teams      []string          `json:"teams"`
+	Scores     Scores            `json:"scores"`
+	Board      [][]DashboardCell `json:"board"`
+	Danger     [][]float64       `json:"danger"`
+	Orders     []string          `json:"orders"`
+	Targets    []string          `json:"targets"`
+	FetchMs    float64           `json:"fetch_ms"`
+	DecisionMs float64           `json:"decision_ms"`
+	SubmitMs   float64           `json:"submit_ms"`
+	Updated    time.Time         `json:"updated"`
+}
+
+// DashboardCell is a board cell together with what is on it, one of empty,
+// wall, base, flag, own or enemy, so that the page never has to guess that
+// from the label.
+type DashboardCell struct {
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+}
+
+// dashboard_cells types the cells of board_cells, in the order it draws
+// them, so that the kind always belongs to the label shown.
+func dashboard_cells(state GameState, size int) [][]DashboardCell {
+	labels := board_cells(state, size)
+	cells := make([][]DashboardCell, len(labels))
+	for y := range labels {
+		cells[y] = make([]DashboardCell, len(labels[y]))
+		for x := range labels[y] {
+			cells[y][x] = DashboardCell{Kind: "empty"}
+		}
+	}
+	mark := func(c Coordinates, kind string) {
+		if c.X >= 0 && c.Y >= 0 && c.Y < len(cells) && c.X < len(cells[c.Y]) {
+			cells[c.Y][c.X] = DashboardCell{Kind: kind, Label: strings.TrimSpace(labels[c.Y][c.X])}
+		}
+	}
+	for _, wall := range state.Walls {
+		mark(Coordinates{wall.X, wall.Y}, "wall")
+	}
+	for _, flag := range state.Flags {
+		mark(flag.Coordinates, "flag")
+	}
+	for _, base := range state.Bases {
+		mark(base.Coordinates, "base")
+	}
+	for _, actor := range state.Actors {
+		if actor.Team == Team {
+			mark(actor.Coordinates, "own")
+		} else {
+			mark(actor.Coordinates, "enemy")
+		}
+	}
+	return cells
+}
+
+type Dashboard struct {
+	mutex    sync.Mutex
+	snapshot DashboardSnapshot
+}
+
+// danger_map rates every cell by how close the nearest enemy able to attack
+// is, 1 being next to it and falling off with distance.
+func danger_map(state GameState, size int) [][]float64 {
+	size = board_size(state, size)
+	danger := make([][]float64, size)
+	for y := range danger {
+		danger[y] = make([]float64, size)
+	}
+	for _, enemy := range filter_objects(state.Actors, false) {
+		if capabilities(enemy).Attack == 0 {
+			continue
+		}
+		for y := range danger {
+			for x := range danger[y] {
+				d := distance(Coordinates{x, y}, enemy.Coordinates)
+				value := capabilities(enemy).Attack / float64(max_int(d, 1))
+				if value > danger[y][x] {
+					danger[y][x] = value
+				}
+			}
+		}
+	}
+	return danger
+}
+
+func start_dashboard(address string) *Dashboard {
+	d := &Dashboard{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboard_html)
+	})
+	mux.HandleFunc("/api/snapshot", func(w http.ResponseWriter, req *http.Request) {
+		d.mutex.Lock()
+		data, err := json.Marshal(d.snapshot)
+		d.mutex.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+	go func() {
+		logf(VerbosityNormal, "dashboard listening on %s", address)
+		if err := http.ListenAndServe(address, mux); err != nil {
+			log.Printf("dashboard stopped: %v", err)
+		}
+	}()
+	return d
+}
+
+// Publish replaces the shown tick. The targets are the order reasons, which
+// name what each actor is heading for.
+func (d *Dashboard) Publish(strategy string, state GameState, orders []Order, fetch time.Duration, decision time.Duration, submit time.Duration) {
+	snapshot := DashboardSnapshot{
+		Team:       Team,
+		Strategy:   strategy,
+		Tick:       state.Tick,
+		Teams:      state.Teams,
+		Scores:     state.Scores,
+		Board:      dashboard_cells(state, GameRules.MapSize()),
+		Danger:     danger_map(state, GameRules.MapSize()),
+		Orders:     make([]string, 0, len(orders)),
+		Targets:    make([]string, 0, len(orders)),
+		FetchMs:    float64(fetch.Microseconds()) / 1000,
+		DecisionMs: float64(decision.Microseconds()) / 1000,
+		SubmitMs:   float64(submit.Microseconds()) / 1000,
+		Updated:    time.Now(),
+	}
+	for _, order := range orders {
+		snapshot.Orders = append(snapshot.Orders, order.String())
+		if order.reason != "" {
+			snapshot.Targets = append(snapshot.Targets, order.reason)
+		}
+	}
+	d.mutex.Lock()
+	d.snapshot = snapshot
+	d.mutex.Unlock()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Demolition is a set of one or two walls whose removal shortens the way
+// between our base and the enemy flags by Saving steps, at an expected Cost
+// in ticks for the actor tearing them down.
+type Demolition struct {
+	Walls  []Coordinates
+	Saving int
+	Cost   float64
+}
+
+func (d Demolition) String() string {
+	return fmt.Sprintf("shortcut through %d walls saving %d steps for %.1f ticks", len(d.Walls), d.Saving, d.Cost)
+}
+
+// flag_route is the length of the way from next to our base to next to the
+// nearest enemy flag, -1 when there is none.
+func flag_route(size int, base Coordinates, blocked map[Coordinates]bool, flags []Coordinates) int {
+	distance := PathMemo.base_distances(size, base, blocked)
+	best := -1
+	for _, flag := range flags {
+		for _, direction := range plan_directions {
+			c := predicted_position(flag, direction)
+			if c.X < 0 || c.Y < 0 || c.X >= size || c.Y >= size {
+				continue
+			}
+			if d := distance[c.Y][c.X]; d >= 0 && (best < 0 || d < best) {
+				best = d
+			}
+		}
+	}
+	return best
+}
+
+// shortcut_walls finds single walls and pairs of touching walls whose
+// removal makes the flag route notably shorter than walking around, and
+// prices each for the given actor. Only those saving more steps than they
+// cost and that can be done before the game ends are returned, the best
+// first.
+func shortcut_walls(state GameState, size int, base Base, actor Actor) []Demolition {
+	destroy := capabilities(actor).Destroy
+	if destroy <= 0 || len(state.Walls) == 0 {
+		return nil
+	}
+	size = board_size(state, size)
+	blocked := make(map[Coordinates]bool)
+	walls := make(map[Coordinates]bool, len(state.Walls))
+	for _, wall := range state.Walls {
+		blocked[wall] = true
+		walls[wall] = true
+	}
+	for _, b := range state.Bases {
+		blocked[b.Coordinates] = true
+	}
+	flags := make([]Coordinates, 0)
+	for _, flag := range filter_objects(state.Flags, false) {
+		flags = append(flags, flag.Coordinates)
+	}
+	if len(flags) == 0 {
+		return nil
+	}
+	before := flag_route(size, base.Coordinates, blocked, flags)
+	remaining := -1
+	if max_ticks := GameRules.MaxTicks(); max_ticks > 0 {
+		remaining = max_ticks - state.Tick
+	}
+	evaluate := func(removed ...Coordinates) (Demolition, bool) {
+		for _, wall := range removed {
+			delete(blocked, wall)
+		}
+		after := flag_route(size, base.Coordinates, blocked, flags)
+		for _, wall := range removed {
+			blocked[wall] = true
+		}
+		if after < 0 {
+			return Demolition{}, false
+		}
+		saving := before - after
+		if before < 0 {
+			// there is no way at all without the demolition
+			saving = size * size
+		}
+		nearest := size * size
+		for _, wall := range removed {
+			nearest = min_int(nearest, distance(actor.Coordinates, wall)-1)
+		}
+		d := Demolition{Walls: removed, Saving: saving}
+		d.Cost = float64(nearest+len(removed)-1) + float64(len(removed))/destroy
+		worth := float64(saving) > d.Cost && saving >= int(Params.DemolitionSaving)
+		if remaining >= 0 && d.Cost+float64(after) > float64(remaining) {
+			worth = false
+		}
+		return d, worth
+	}
+	demolitions := make([]Demolition, 0)
+	single := make(map[Coordinates]int, len(state.Walls))
+	for _, wall := range state.Walls {
+		d, ok := evaluate(wall)
+		single[wall] = d.Saving
+		if ok {
+			demolitions = append(demolitions, d)
+		}
+	}
+	// thicker walls only open up when both parts go, pairs that save no more
+	// than one of their walls alone are left out
+	for _, wall := range state.Walls {
+		for _, direction := range []string{"right", "up"} {
+			next := predicted_position(wall, direction)
+			if !walls[next] {
+				continue
+			}
+			if d, ok := evaluate(wall, next); ok && d.Saving > max_int(single[wall], single[next]) {
+				demolitions = append(demolitions, d)
+			}
+		}
+	}
+	sort.SliceStable(demolitions, func(i, j int) bool {
+		return float64(demolitions[i].Saving)-demolitions[i].Cost > float64(demolitions[j].Saving)-demolitions[j].Cost
+	})
+	return demolitions
+}
+
+// nearest_wall returns the wall of a demolition the actor reaches first.
+func (d Demolition) nearest_wall(actor Actor) Coordinates {
+	best := d.Walls[0]
+	for _, wall := range d.Walls[1:] {
+		if distance(actor.Coordinates, wall) < distance(actor.Coordinates, best) {
+			best = wall
+		}
+	}
+	return best
+}
+
+func demolish_claim(c Coordinates) string {
+	return fmt.Sprintf("demolish:%d:%d", c.X, c.Y)
+}
+
+func can_destroy(ctx *BehaviorContext) bool {
+	return capabilities(ctx.Actor).Destroy > 0
+}
+
+// demolish_action sends a destroyer to the most worthwhile shortcut no other
+// destroyer of this tick took. Destroying can fail and a shortcut may need
+// two walls gone, so it keeps at it over the following ticks.
+func demolish_action(ctx *BehaviorContext) Status {
+	claimed, _ := ctx.Blackboard["demolitions"].(map[Coordinates]bool)
+	if claimed == nil {
+		claimed = make(map[Coordinates]bool)
+		ctx.Blackboard["demolitions"] = claimed
+	}
+	for _, d := range shortcut_walls(ctx.State, GameRules.MapSize(), ctx.MyBase, ctx.Actor) {
+		wall := d.nearest_wall(ctx.Actor)
+		if claimed[wall] {
+			continue
+		}
+		for _, w := range d.Walls {
+			claimed[w] = true
+		}
+		ctx.Orders = seek_target(ctx.Actor, position_target(wall), "destroy", d.String(), ctx.Orders)
+		return Success
+	}
+	return Failure
+}
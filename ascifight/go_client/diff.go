@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// StateCache fetches the game state with If-None-Match, so the server can
+// answer with an empty 304 while the board has not changed.
+type StateCache struct {
+	etag string
+	data []byte
+	// generation is the endpoint generation the ETag was issued by.
+	generation int
+}
+
+// Fetch returns the current state and whether the server reported it as
+// unchanged. Tick and execution time of a cached state are stale and taken
+// from t instead.
+func (c *StateCache) Fetch(ctx context.Context, t Timing) (GameState, bool, error) {
+	var state GameState
+	header := http.Header{}
+	if generation := Servers.Generation(); generation != c.generation {
+		// another server cannot know the ETags of the one we failed over from
+		c.etag, c.data, c.generation = "", nil, generation
+	}
+	if c.etag != "" {
+		header.Set("If-None-Match", c.etag)
+	}
+	resp, data, err := api_call(ctx, "GET", "states/game_state", nil, nil, false, header)
+	if err != nil {
+		return state, false, err
+	}
+	unchanged := resp.StatusCode == http.StatusNotModified && c.data != nil
+	if unchanged {
+		data = c.data
+	} else {
+		c.etag = resp.Header.Get("ETag")
+		c.data = data
+	}
+	if err := decode_response("states/game_state", data, &state); err != nil {
+		return state, false, err
+	}
+	if unchanged {
+		state.Tick = t.Tick
+		state.TimeOfNextExecution = t.TimeOfNextExecution
+	}
+	return state, unchanged, nil
+}
+
+// StateDiff is what changed on the board between two states.
+type StateDiff struct {
+	Moved        []Actor
+	Appeared     []Actor
+	Vanished     []Actor
+	FlagsMoved   []Flag
+	WallsAdded   []Wall
+	WallsRemoved []Wall
+	Scores       map[string]int
+	Teams        bool
+}
+
+func (d StateDiff) Empty() bool {
+	return len(d.Moved) == 0 && len(d.Appeared) == 0 && len(d.Vanished) == 0 && len(d.FlagsMoved) == 0 &&
+		len(d.WallsAdded) == 0 && len(d.WallsRemoved) == 0 && len(d.Scores) == 0 && !d.Teams
+}
+
+func (d StateDiff) String() string {
+	if d.Empty() {
+		return "no changes"
+	}
+	parts := make([]string, 0)
+	count := func(n int, what string) {
+		if n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, what))
+		}
+	}
+	count(len(d.Moved), "actors changed")
+	count(len(d.Appeared), "actors appeared")
+	count(len(d.Vanished), "actors vanished")
+	count(len(d.FlagsMoved), "flags moved")
+	count(len(d.WallsAdded), "walls built")
+	count(len(d.WallsRemoved), "walls destroyed")
+	count(len(d.Scores), "scores changed")
+	if d.Teams {
+		parts = append(parts, "teams changed")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// diff_states compares two states ignoring tick and timing. An actor counts
+// as changed when it moved or picked up or dropped a flag.
+func diff_states(previous GameState, current GameState) StateDiff {
+	d := StateDiff{Scores: make(map[string]int)}
+	if strings.Join(previous.Teams, "\x00") != strings.Join(current.Teams, "\x00") {
+		d.Teams = true
+	}
+	for _, after := range current.Actors {
+		before, ok := find_actor(previous.Actors, after.Team, after.Ident)
+		switch {
+		case !ok:
+			d.Appeared = append(d.Appeared, after)
+		case before != after:
+			d.Moved = append(d.Moved, after)
+		}
+	}
+	for _, before := range previous.Actors {
+		if _, ok := find_actor(current.Actors, before.Team, before.Ident); !ok {
+			d.Vanished = append(d.Vanished, before)
+		}
+	}
+	lying := make(map[string]bool, len(previous.Flags))
+	for _, before := range previous.Flags {
+		lying[flag_key(before.Team, before.Coordinates)] = true
+	}
+	for _, after := range current.Flags {
+		if !lying[flag_key(after.Team, after.Coordinates)] {
+			d.FlagsMoved = append(d.FlagsMoved, after)
+		}
+	}
+	walls := make(map[Wall]bool, len(previous.Walls))
+	for _, wall := range previous.Walls {
+		walls[wall] = true
+	}
+	for _, wall := range current.Walls {
+		if !walls[wall] {
+			d.WallsAdded = append(d.WallsAdded, wall)
+		}
+		delete(walls, wall)
+	}
+	for wall := range walls {
+		d.WallsRemoved = append(d.WallsRemoved, wall)
+	}
+	for team, score := range current.Scores {
+		if previous.Scores[team] != score {
+			d.Scores[team] = score - previous.Scores[team]
+		}
+	}
+	for team, score := range previous.Scores {
+		if _, ok := current.Scores[team]; !ok {
+			d.Scores[team] = -score
+		}
+	}
+	return d
+}
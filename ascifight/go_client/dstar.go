@@ -0,0 +1,309 @@
+package main
+
+import "container/heap"
+
+const dstar_infinity = 1 << 30
+
+type dstar_key [2]int
+
+func (k dstar_key) less(o dstar_key) bool {
+	return k[0] < o[0] || (k[0] == o[0] && k[1] < o[1])
+}
+
+type dstar_entry struct {
+	cell Coordinates
+	key  dstar_key
+}
+
+type dstar_queue []dstar_entry
+
+func (q dstar_queue) Len() int            { return len(q) }
+func (q dstar_queue) Less(i, j int) bool  { return q[i].key.less(q[j].key) }
+func (q dstar_queue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *dstar_queue) Push(x interface{}) { *q = append(*q, x.(dstar_entry)) }
+func (q *dstar_queue) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// DStarLite searches paths from a moving start to the cells next to a fixed
+// target, see Koenig and Likhachev, "D* Lite". The search runs backwards
+// from the goal, so when the actor moves or a few cells become blocked or
+// free only the affected part of the search is repaired, instead of
+// searching the whole board again.
+type DStarLite struct {
+	size    int
+	target  Coordinates
+	start   Coordinates
+	last    Coordinates
+	km      int
+	blocked map[Coordinates]bool
+	// costs are extra steps charged for entering a cell.
+	costs map[Coordinates]int
+	g     map[Coordinates]int
+	rhs   map[Coordinates]int
+	open  dstar_queue
+	// queued holds the key of every cell in open, entries with another key
+	// are stale and skipped.
+	queued map[Coordinates]dstar_key
+}
+
+func new_dstar_lite(size int, start Coordinates, target Coordinates, blocked map[Coordinates]bool, costs map[Coordinates]int) *DStarLite {
+	d := &DStarLite{
+		size:    size,
+		target:  target,
+		start:   start,
+		last:    start,
+		blocked: make(map[Coordinates]bool, len(blocked)),
+		costs:   make(map[Coordinates]int, len(costs)),
+		g:       make(map[Coordinates]int),
+		rhs:     make(map[Coordinates]int),
+		queued:  make(map[Coordinates]dstar_key),
+	}
+	for cell := range blocked {
+		d.blocked[cell] = true
+	}
+	for cell, cost := range costs {
+		d.costs[cell] = cost
+	}
+	for _, direction := range plan_directions {
+		if goal := predicted_position(target, direction); d.inside(goal) {
+			d.rhs[goal] = 0
+			d.insert(goal)
+		}
+	}
+	return d
+}
+
+func (d *DStarLite) inside(c Coordinates) bool {
+	return c.X >= 0 && c.Y >= 0 && c.X < d.size && c.Y < d.size
+}
+
+func (d *DStarLite) goal(c Coordinates) bool {
+	return distance(c, d.target) == 1
+}
+
+func (d *DStarLite) get_g(c Coordinates) int {
+	if v, ok := d.g[c]; ok {
+		return v
+	}
+	return dstar_infinity
+}
+
+func (d *DStarLite) get_rhs(c Coordinates) int {
+	if v, ok := d.rhs[c]; ok {
+		return v
+	}
+	return dstar_infinity
+}
+
+// cost of stepping onto c.
+func (d *DStarLite) cost(c Coordinates) int {
+	if d.blocked[c] {
+		return dstar_infinity
+	}
+	return 1 + d.costs[c]
+}
+
+func (d *DStarLite) calculate_key(c Coordinates) dstar_key {
+	best := min_int(d.get_g(c), d.get_rhs(c))
+	if best >= dstar_infinity {
+		return dstar_key{dstar_infinity, dstar_infinity}
+	}
+	return dstar_key{best + distance(d.start, c) + d.km, best}
+}
+
+func (d *DStarLite) insert(c Coordinates) {
+	key := d.calculate_key(c)
+	d.queued[c] = key
+	heap.Push(&d.open, dstar_entry{c, key})
+}
+
+func (d *DStarLite) neighbours(c Coordinates) []Coordinates {
+	result := make([]Coordinates, 0, 4)
+	for _, direction := range plan_directions {
+		if next := predicted_position(c, direction); d.inside(next) {
+			result = append(result, next)
+		}
+	}
+	return result
+}
+
+func (d *DStarLite) update_vertex(c Coordinates) {
+	if !d.goal(c) {
+		best := dstar_infinity
+		for _, next := range d.neighbours(c) {
+			if step := d.cost(next); step < dstar_infinity {
+				if g := d.get_g(next); g < dstar_infinity {
+					best = min_int(best, step+g)
+				}
+			}
+		}
+		d.rhs[c] = best
+	}
+	delete(d.queued, c)
+	if d.get_g(c) != d.get_rhs(c) {
+		d.insert(c)
+	}
+}
+
+func (d *DStarLite) compute() {
+	for d.open.Len() > 0 {
+		top := d.open[0]
+		if key, ok := d.queued[top.cell]; !ok || key != top.key {
+			heap.Pop(&d.open)
+			continue
+		}
+		if !top.key.less(d.calculate_key(d.start)) && d.get_rhs(d.start) == d.get_g(d.start) {
+			return
+		}
+		heap.Pop(&d.open)
+		delete(d.queued, top.cell)
+		u := top.cell
+		if key := d.calculate_key(u); top.key.less(key) {
+			d.insert(u)
+		} else if d.get_g(u) > d.get_rhs(u) {
+			d.g[u] = d.get_rhs(u)
+			for _, p := range d.neighbours(u) {
+				d.update_vertex(p)
+			}
+		} else {
+			d.g[u] = dstar_infinity
+			d.update_vertex(u)
+			for _, p := range d.neighbours(u) {
+				d.update_vertex(p)
+			}
+		}
+	}
+}
+
+// Update moves the start and repairs the search for the cells whose
+// blocked state or cost changed since the last call.
+func (d *DStarLite) Update(start Coordinates, blocked map[Coordinates]bool, costs map[Coordinates]int) {
+	d.start = start
+	d.km += distance(d.last, start)
+	d.last = start
+	changed := make([]Coordinates, 0)
+	for cell := range blocked {
+		if !d.blocked[cell] {
+			changed = append(changed, cell)
+		}
+	}
+	for cell := range d.blocked {
+		if !blocked[cell] {
+			changed = append(changed, cell)
+		}
+	}
+	for cell, cost := range costs {
+		if d.costs[cell] != cost {
+			changed = append(changed, cell)
+		}
+	}
+	for cell := range d.costs {
+		if _, ok := costs[cell]; !ok {
+			changed = append(changed, cell)
+		}
+	}
+	for _, cell := range changed {
+		d.blocked[cell] = blocked[cell]
+		if !blocked[cell] {
+			delete(d.blocked, cell)
+		}
+		d.costs[cell] = costs[cell]
+		if costs[cell] == 0 {
+			delete(d.costs, cell)
+		}
+		// the cost of stepping onto cell changed for all its neighbours
+		for _, p := range d.neighbours(cell) {
+			d.update_vertex(p)
+		}
+	}
+}
+
+// Path returns the moves from the start to a cell next to the target for
+// an actor that last moved in heading, which may be empty.
+func (d *DStarLite) Path(heading string) ([]string, bool) {
+	d.compute()
+	start_heading := heading
+	if d.get_g(d.start) >= dstar_infinity && !d.goal(d.start) {
+		return nil, false
+	}
+	steps := make([]string, 0)
+	at := d.start
+	for !d.goal(at) {
+		if len(steps) > d.size*d.size {
+			return nil, false
+		}
+		best, ties := dstar_infinity, make([]string, 0, len(plan_directions))
+		for _, direction := range plan_directions {
+			next := predicted_position(at, direction)
+			if !d.inside(next) || d.cost(next) >= dstar_infinity || d.get_g(next) >= dstar_infinity {
+				continue
+			}
+			switch total := d.cost(next) + d.get_g(next); {
+			case total < best:
+				best, ties = total, append(ties[:0], direction)
+			case total == best:
+				ties = append(ties, direction)
+			}
+		}
+		if len(ties) == 0 {
+			return nil, false
+		}
+		// an equally short route that keeps the heading is taken, where it
+		// has to turn anyway one is picked at random to be less predictable
+		best_direction := ties[Random.Intn(len(ties))]
+		for _, direction := range ties {
+			if direction == heading {
+				best_direction = direction
+			}
+		}
+		steps = append(steps, best_direction)
+		heading = best_direction
+		at = predicted_position(at, best_direction)
+	}
+	return d.smooth(steps, start_heading), true
+}
+
+// turns counts the changes of direction along steps, starting from heading.
+func turns(heading string, steps ...string) int {
+	n := 0
+	for _, direction := range steps {
+		if heading != "" && direction != "" && direction != heading {
+			n++
+		}
+		heading = direction
+	}
+	return n
+}
+
+// smooth swaps neighbouring steps of a path wherever that saves a turn and
+// the cell passed instead is free and no dearer. The path stays as long and
+// ends in the same cell, but a staircase of single steps is straightened
+// into long runs, which also cross fewer other actors.
+func (d *DStarLite) smooth(steps []string, heading string) []string {
+	for changed := true; changed; {
+		changed = false
+		at := d.start
+		for i := 0; i+1 < len(steps); i++ {
+			a, b := steps[i], steps[i+1]
+			before, after := heading, ""
+			if i > 0 {
+				before = steps[i-1]
+			}
+			if i+2 < len(steps) {
+				after = steps[i+2]
+			}
+			passed, instead := predicted_position(at, a), predicted_position(at, b)
+			if a != b && turns(before, b, a, after) < turns(before, a, b, after) &&
+				d.inside(instead) && d.cost(instead) <= d.cost(passed) {
+				steps[i], steps[i+1] = b, a
+				changed = true
+			}
+			at = predicted_position(at, steps[i])
+		}
+	}
+	return steps
+}
@@ -0,0 +1,139 @@
+package main
+
+import "testing"
+
+// shortest_steps is a plain breadth first search for the number of moves
+// from start to a cell next to target, -1 if there is none.
+func shortest_steps(size int, start Coordinates, target Coordinates, blocked map[Coordinates]bool) int {
+	steps := map[Coordinates]int{start: 0}
+	queue := []Coordinates{start}
+	for len(queue) > 0 {
+		at := queue[0]
+		queue = queue[1:]
+		if distance(at, target) == 1 {
+			return steps[at]
+		}
+		for _, direction := range plan_directions {
+			next := predicted_position(at, direction)
+			if next.X < 0 || next.Y < 0 || next.X >= size || next.Y >= size || blocked[next] {
+				continue
+			}
+			if _, seen := steps[next]; !seen {
+				steps[next] = steps[at] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return -1
+}
+
+// check_path walks the moves and fails unless they avoid the blocked cells,
+// end next to the target and are as short as a fresh search.
+func check_path(t *testing.T, d *DStarLite, blocked map[Coordinates]bool) {
+	t.Helper()
+	want := shortest_steps(d.size, d.start, d.target, blocked)
+	steps, ok := d.Path("")
+	if want < 0 {
+		if ok {
+			t.Fatalf("found path %v from %v to %v, want none", steps, d.start, d.target)
+		}
+		return
+	}
+	if !ok {
+		t.Fatalf("no path from %v to %v, want %d moves", d.start, d.target, want)
+	}
+	at := d.start
+	for _, step := range steps {
+		at = predicted_position(at, step)
+		if blocked[at] {
+			t.Fatalf("path %v steps onto blocked %v", steps, at)
+		}
+	}
+	if distance(at, d.target) != 1 {
+		t.Fatalf("path %v ends at %v, not next to %v", steps, at, d.target)
+	}
+	if len(steps) != want {
+		t.Fatalf("path %v has %d moves, want %d", steps, len(steps), want)
+	}
+}
+
+func blocked_cells(cells ...Coordinates) map[Coordinates]bool {
+	blocked := make(map[Coordinates]bool, len(cells))
+	for _, cell := range cells {
+		blocked[cell] = true
+	}
+	return blocked
+}
+
+func TestDStarLiteRepair(t *testing.T) {
+	type update struct {
+		start   Coordinates
+		blocked map[Coordinates]bool
+	}
+	tests := []struct {
+		name    string
+		start   Coordinates
+		target  Coordinates
+		blocked map[Coordinates]bool
+		updates []update
+	}{
+		{
+			name:   "open board",
+			start:  Coordinates{0, 0},
+			target: Coordinates{5, 5},
+		},
+		{
+			name:    "already next to the target",
+			start:   Coordinates{4, 5},
+			target:  Coordinates{5, 5},
+			blocked: blocked_cells(Coordinates{4, 4}),
+		},
+		{
+			name:   "wall appears across the path",
+			start:  Coordinates{0, 3},
+			target: Coordinates{6, 3},
+			updates: []update{
+				{Coordinates{0, 3}, blocked_cells(Coordinates{3, 1}, Coordinates{3, 2}, Coordinates{3, 3}, Coordinates{3, 4}, Coordinates{3, 5})},
+			},
+		},
+		{
+			name:    "wall opens up",
+			start:   Coordinates{0, 3},
+			target:  Coordinates{6, 3},
+			blocked: blocked_cells(Coordinates{3, 1}, Coordinates{3, 2}, Coordinates{3, 3}, Coordinates{3, 4}, Coordinates{3, 5}),
+			updates: []update{
+				{Coordinates{0, 3}, blocked_cells(Coordinates{3, 1}, Coordinates{3, 2}, Coordinates{3, 4}, Coordinates{3, 5})},
+			},
+		},
+		{
+			name:   "actor moves while new walls appear",
+			start:  Coordinates{0, 0},
+			target: Coordinates{7, 7},
+			updates: []update{
+				{Coordinates{1, 0}, blocked_cells(Coordinates{2, 0}, Coordinates{2, 1})},
+				{Coordinates{1, 1}, blocked_cells(Coordinates{2, 0}, Coordinates{2, 1}, Coordinates{1, 2}, Coordinates{2, 2})},
+				{Coordinates{0, 1}, blocked_cells(Coordinates{2, 1}, Coordinates{1, 2}, Coordinates{2, 2}, Coordinates{0, 3})},
+			},
+		},
+		{
+			name:   "target gets walled in",
+			start:  Coordinates{0, 0},
+			target: Coordinates{4, 4},
+			updates: []update{
+				{Coordinates{0, 0}, blocked_cells(Coordinates{3, 4}, Coordinates{5, 4}, Coordinates{4, 3}, Coordinates{4, 5})},
+				{Coordinates{0, 0}, blocked_cells(Coordinates{3, 4}, Coordinates{5, 4}, Coordinates{4, 3})},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			const size = 8
+			d := new_dstar_lite(size, test.start, test.target, test.blocked, nil)
+			check_path(t, d, test.blocked)
+			for _, u := range test.updates {
+				d.Update(u.start, u.blocked, nil)
+				check_path(t, d, u.blocked)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import "fmt"
+
+// Endgame describes how close the game is to its end and what the score
+// asks for in the closing ticks.
+type Endgame struct {
+	// Remaining is the number of ticks left, -1 while the game length is
+	// unknown.
+	Remaining int
+	// Lead is our score minus the best opponent's.
+	Lead int
+	// Closing is set once a flag run across the board may no longer fit
+	// into the remaining ticks.
+	Closing bool
+	// Deny is set when an opponent capture would still turn the game,
+	// either by catching up with us at the end or by reaching the max score.
+	Deny bool
+	// AllIn is set when we are level or behind by less than a capture at
+	// the end, so that only a capture of ours decides the game.
+	AllIn bool
+}
+
+func (e Endgame) String() string {
+	return fmt.Sprintf("%d ticks left, lead %d", e.Remaining, e.Lead)
+}
+
+func current_endgame(state GameState) Endgame {
+	e := Endgame{Remaining: -1}
+	if max_ticks := GameRules.MaxTicks(); max_ticks > 0 {
+		e.Remaining = max_int(0, max_ticks-state.Tick)
+	}
+	ours, best := state.Scores[Team], 0
+	for team, score := range state.Scores {
+		if team != Team {
+			best = max_int(best, score)
+		}
+	}
+	e.Lead = ours - best
+	capture := max_int(1, GameRules.CaptureScore())
+	size := board_size(state, GameRules.MapSize())
+	e.Closing = e.Remaining >= 0 && e.Remaining <= 2*size
+	e.Deny = e.Closing && e.Lead > 0 && e.Lead <= capture
+	if max_score := GameRules.MaxScore(); max_score > 0 && best+capture >= max_score {
+		e.Deny = true
+	}
+	e.AllIn = e.Closing && e.Lead <= 0 && e.Lead+capture > 0
+	return e
+}
+
+// RunFeasible tells whether the actor can still grab the flag at flag and
+// bring it to base before the game ends.
+func (e Endgame) RunFeasible(actor Actor, flag Coordinates, base Coordinates) bool {
+	if e.Remaining < 0 {
+		return true
+	}
+	return distance(actor.Coordinates, flag)+distance(flag, base) <= e.Remaining
+}
+
+// must_deny_capture picks attackers to go after enemies carrying our flag
+// when their capture would turn the game.
+func must_deny_capture(ctx *BehaviorContext) bool {
+	if capabilities(ctx.Actor).Attack == 0 || !current_endgame(ctx.State).Deny {
+		return false
+	}
+	return len(our_flag_carriers(ctx.State)) > 0
+}
+
+func our_flag_carriers(state GameState) []Actor {
+	carriers := make([]Actor, 0)
+	for _, enemy := range filter_objects(state.Actors, false) {
+		if enemy.Flag == Team {
+			carriers = append(carriers, enemy)
+		}
+	}
+	return carriers
+}
+
+func deny_capture_action(ctx *BehaviorContext) Status {
+	carriers := our_flag_carriers(ctx.State)
+	if len(carriers) == 0 {
+		return Failure
+	}
+	nearest := carriers[0]
+	for _, carrier := range carriers[1:] {
+		if distance(ctx.Actor.Coordinates, carrier.Coordinates) < distance(ctx.Actor.Coordinates, nearest.Coordinates) {
+			nearest = carrier
+		}
+	}
+	why := fmt.Sprintf("denying the capture of %s actor %d, %s", nearest.Team, nearest.Ident, current_endgame(ctx.State))
+	ctx.Orders = seek_target(ctx.Actor, nearest, "attack", why, ctx.Orders)
+	return Success
+}
+
+func is_closing(ctx *BehaviorContext) bool {
+	return current_endgame(ctx.State).Closing
+}
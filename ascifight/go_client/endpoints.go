@@ -0,0 +1,76 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+)
+
+// Endpoints are the server URLs given to -server, e.g. a primary and a
+// backup or the LAN and the tunnel address of the same server. Requests go
+// to the active one, and when it cannot be reached the next one takes over
+// and stays active until it fails in turn.
+type Endpoints struct {
+	mutex  sync.Mutex
+	urls   []string
+	active int
+	// generation counts the failovers, so that state tied to one server
+	// like ETags can be dropped.
+	generation int
+}
+
+var Servers = &Endpoints{urls: []string{ServerUrl}}
+
+// Set takes a comma separated list of server URLs.
+func (e *Endpoints) Set(list string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.urls = e.urls[:0]
+	for _, u := range strings.Split(list, ",") {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		if !strings.HasSuffix(u, "/") {
+			u += "/"
+		}
+		e.urls = append(e.urls, u)
+	}
+	e.active = 0
+}
+
+func (e *Endpoints) List() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]string(nil), e.urls...)
+}
+
+func (e *Endpoints) Active() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if len(e.urls) == 0 {
+		return ""
+	}
+	return e.urls[e.active]
+}
+
+func (e *Endpoints) Generation() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.generation
+}
+
+// Failed reports that url could not be reached and switches to the next
+// endpoint, returning whether it did. Failures of an endpoint that is no
+// longer active, e.g. of requests still in flight, are ignored.
+func (e *Endpoints) Failed(url string, err error) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if len(e.urls) < 2 || e.urls[e.active] != url {
+		return false
+	}
+	e.active = (e.active + 1) % len(e.urls)
+	e.generation++
+	log.Printf("server %s unreachable, failing over to %s: %v", url, e.urls[e.active], err)
+	return true
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// events_route serves what happened in a tick. The server does not offer it
+// yet, it is used once check_server_version found it and the events are
+// inferred from state diffs until then.
+const events_route = "/states/events"
+
+var events_offered = false
+
+// ServerEvent is one entry of the event log of the server.
+type ServerEvent struct {
+	Tick        int    `json:"tick"`
+	Type        string `json:"type"`
+	Team        string `json:"team"`
+	Actor       int    `json:"actor"`
+	Flag        string `json:"flag"`
+	TargetTeam  string `json:"target_team"`
+	TargetActor int    `json:"target_actor"`
+	Detail      string `json:"detail"`
+}
+
+// game_event maps a server event on the kinds the stats know. A grab of our
+// flag is a steal, and a kill of one of our actors is its death.
+func (e ServerEvent) game_event() GameEvent {
+	event := GameEvent{Tick: e.Tick, Kind: e.Type, Team: e.Team, Actor: e.Actor, Flag: e.Flag, Detail: e.Detail}
+	switch e.Type {
+	case "grab":
+		if e.Team != Team && e.Flag == Team {
+			event.Kind = "steal"
+		}
+	case "kill":
+		if e.TargetTeam == Team && e.Team != Team {
+			event = GameEvent{Tick: e.Tick, Kind: "death", Team: Team, Actor: e.TargetActor}
+		} else {
+			event.Target = fmt.Sprintf("%s actor %d", e.TargetTeam, e.TargetActor)
+		}
+	case "order_failed":
+		event.Kind = "failed"
+	}
+	return event
+}
+
+// fetch_events returns the events of a tick as reported by the server.
+func fetch_events(ctx context.Context, tick int) ([]GameEvent, error) {
+	var log []ServerEvent
+	query := url.Values{"tick": {strconv.Itoa(tick)}}
+	if err := api_request(ctx, "GET", events_route[1:], query, nil, false, &log); err != nil {
+		return nil, fmt.Errorf("fetching the events of tick %d: %w", tick, err)
+	}
+	events := make([]GameEvent, 0, len(log))
+	for _, e := range log {
+		events = append(events, e.game_event())
+	}
+	return events, nil
+}
+
+// EventLog holds the events of the latest tick for the strategies.
+type EventLog struct {
+	mutex  sync.Mutex
+	tick   int
+	events []GameEvent
+}
+
+// TickEvents are the events that led to the state the strategies decide on,
+// empty while the server publishes no event log.
+var TickEvents = &EventLog{}
+
+func (l *EventLog) Set(tick int, events []GameEvent) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.tick = tick
+	l.events = events
+}
+
+// Get returns the events of tick, nil when they are not known.
+func (l *EventLog) Get(tick int) []GameEvent {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.tick != tick {
+		return nil
+	}
+	return l.events
+}
@@ -0,0 +1,67 @@
+package main
+
+import "math"
+
+// CaptureEstimate is what a flag run of one actor is expected to take.
+type CaptureEstimate struct {
+	// Ticks is the expected number of ticks until the flag is home,
+	// counting the walks and every attempt at grabbing and putting it.
+	Ticks float64
+	// Success is the chance to get the flag past its defenders.
+	Success float64
+}
+
+// TicksToScore is the expected number of ticks per capture, failed runs
+// included. The odds count as much as the risk tolerance lets them, a
+// reckless actor goes by the ticks alone.
+func (e CaptureEstimate) TicksToScore() float64 {
+	if e.Success <= 0 {
+		return math.Inf(1)
+	}
+	return e.Ticks / math.Pow(e.Success, caution())
+}
+
+// Value rates the run from 0 to 1 like proximity does, by its ticks per
+// capture instead of the distance.
+func (e CaptureEstimate) Value() float64 {
+	return 1 / (1 + e.TicksToScore())
+}
+
+// walk_distance is the number of steps from start to next to target around
+// walls, or the straight distance when target cannot be reached.
+func walk_distance(state GameState, size int, start Coordinates, target Coordinates) int {
+	if distance(start, target) <= 1 {
+		return 0
+	}
+	walls := make(map[Coordinates]bool, len(state.Walls))
+	for _, wall := range state.Walls {
+		walls[wall] = true
+	}
+	grid := PathMemo.base_distances(size, target, walls)
+	if start.Y < 0 || start.X < 0 || start.Y >= len(grid) || start.X >= len(grid[start.Y]) || grid[start.Y][start.X] < 0 {
+		return distance(start, target) - 1
+	}
+	return grid[start.Y][start.X]
+}
+
+// estimate_capture weighs a run of actor for the flag at flag to our base
+// at base. A grab or put with chance p takes 1/p attempts on average, and
+// every enemy attacker around the flag may strike the carrier on its way
+// out, the likelier the closer it stands and the better it attacks.
+func estimate_capture(state GameState, actor Actor, flag Coordinates, base Coordinates) CaptureEstimate {
+	size := board_size(state, GameRules.MapSize())
+	grab := capabilities(actor).Grab
+	if grab <= 0 {
+		return CaptureEstimate{Ticks: math.Inf(1)}
+	}
+	ticks := float64(walk_distance(state, size, actor.Coordinates, flag)) + 1/grab
+	ticks += float64(walk_distance(state, size, flag, base)) + 1/grab
+	success := 1.0
+	for _, enemy := range filter_objects(state.Actors, false) {
+		d := distance(enemy.Coordinates, flag)
+		if attack := capabilities(enemy).Attack; attack > 0 && d <= bait_guard_radius {
+			success *= 1 - attack/float64(1+d)
+		}
+	}
+	return CaptureEstimate{Ticks: ticks, Success: success}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// fetch_timeout bounds the requests made before the tick deadline is known.
+const fetch_timeout = 2 * time.Second
+
+// TickFetch is what the loop fetched in parallel at the start of a tick.
+type TickFetch struct {
+	Timing Timing
+	// WithState is set when the state was requested along with the timing.
+	WithState   bool
+	State       GameState
+	NotModified bool
+	StateErr    error
+	// Rules is only set when they were requested.
+	Rules   *Rules
+	Started time.Time
+}
+
+// fetch_tick requests the timing and, with with_state set, game state and
+// rules at the same time instead of one after the other. The loop only asks
+// for the state when a new tick is due, so the polls that merely find out
+// how long to sleep cost a single request. An error is only returned when
+// the timing could not be fetched; a failed state or rules request is left
+// for the caller to handle.
+func fetch_tick(parent context.Context, cache *StateCache, with_state bool, with_rules bool) (TickFetch, error) {
+	f := TickFetch{Started: time.Now(), WithState: with_state}
+	ctx, cancel := context.WithTimeout(parent, fetch_timeout)
+	defer cancel()
+	var wait sync.WaitGroup
+	var timing_err error
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		var t Timing
+		if timing_err = api_request(ctx, "GET", "states/timing", nil, nil, false, &t); timing_err == nil {
+			f.Timing = t
+		}
+	}()
+	if with_state {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			// an overloaded server is not waited for longer than a tick
+			ctx, cancel := context.WithTimeout(ctx, Pressure.FetchTimeout())
+			defer cancel()
+			// the cache fills in tick and time of an unchanged state, which
+			// are not known yet
+			f.State, f.NotModified, f.StateErr = cache.Fetch(ctx, Timing{})
+		}()
+	}
+	if with_state && with_rules {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			var rules Rules
+			if api_request(ctx, "GET", "states/game_rules", nil, nil, false, &rules) == nil {
+				f.Rules = &rules
+			}
+		}()
+	}
+	wait.Wait()
+	if timing_err != nil {
+		return f, timing_err
+	}
+	if f.NotModified {
+		f.State.Tick = f.Timing.Tick
+		f.State.TimeOfNextExecution = f.Timing.TimeOfNextExecution
+	}
+	return f, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import "fmt"
+
+// A team may own several flags, so the team a flag belongs to does not name
+// it. A flag is told apart from the others of its team by where it lies,
+// which holds for as long as nobody picks it up.
+
+// flag_key names the flag of team lying at c.
+func flag_key(team string, c Coordinates) string {
+	return fmt.Sprintf("%s:%d,%d", team, c.X, c.Y)
+}
+
+// flag_claim is the utility claim on grabbing flag.
+func flag_claim(flag Flag) string {
+	return "flag:" + flag_key(flag.Team, flag.Coordinates)
+}
+
+// carrier is the actor carrying flag, if anyone does.
+func carrier(state GameState, flag Flag) (Actor, bool) {
+	for _, actor := range state.Actors {
+		if actor.Flag == flag.Team && actor.Coordinates == flag.Coordinates {
+			return actor, true
+		}
+	}
+	return Actor{}, false
+}
+
+// lying_flags are the flags of our team or the others nobody carries, by
+// their flag_key.
+func lying_flags(state GameState, my_team bool) map[string]Flag {
+	flags := make(map[string]Flag)
+	for _, flag := range filter_objects(state.Flags, my_team) {
+		if _, carried := carrier(state, flag); !carried {
+			flags[flag_key(flag.Team, flag.Coordinates)] = flag
+		}
+	}
+	return flags
+}
+
+// at_home tells whether flag lies on a base of its team.
+func at_home(state GameState, flag Flag) bool {
+	for _, base := range state.Bases {
+		if base.Team == flag.Team && base.Coordinates == flag.Coordinates {
+			return true
+		}
+	}
+	return false
+}
+
+// flag_on tells whether any flag lies at c.
+func flag_on(state GameState, c Coordinates) bool {
+	for _, flag := range state.Flags {
+		if flag.Coordinates == c {
+			return true
+		}
+	}
+	return false
+}
+
+// flags_home counts the flags of team lying on one of its bases.
+func flags_home(state GameState, team string) int {
+	home := 0
+	for _, flag := range state.Flags {
+		if flag.Team == team && at_home(state, flag) {
+			home++
+		}
+	}
+	return home
+}
+
+// flag_returned tells whether a flag of team went back to a base of its
+// team between previous and current, as a captured flag does.
+func flag_returned(previous GameState, current GameState, team string) bool {
+	return flags_home(current, team) > flags_home(previous, team)
+}
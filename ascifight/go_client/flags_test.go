@@ -0,0 +1,89 @@
+package main
+
+import "testing"
+
+func TestFlagReturned(t *testing.T) {
+	flag := func(team string, x int, y int) Flag {
+		return Flag{OwnedObjectImpl{Team: team, Coordinates: Coordinates{x, y}}}
+	}
+	bases := []Base{
+		{OwnedObjectImpl{Team: "Team 1", Coordinates: Coordinates{0, 0}}},
+		{OwnedObjectImpl{Team: "Team 1", Coordinates: Coordinates{0, 9}}},
+		{OwnedObjectImpl{Team: "Team 2", Coordinates: Coordinates{9, 9}}},
+	}
+	state := func(flags ...Flag) GameState {
+		return GameState{Teams: []string{"Team 1", "Team 2"}, Bases: bases, Flags: flags}
+	}
+	tests := []struct {
+		name     string
+		previous GameState
+		current  GameState
+		team     string
+		returned bool
+	}{
+		{
+			name:     "flag stays home",
+			previous: state(flag("Team 1", 0, 0)),
+			current:  state(flag("Team 1", 0, 0)),
+			team:     "Team 1",
+		},
+		{
+			name:     "flag taken from its base",
+			previous: state(flag("Team 1", 0, 0)),
+			current:  state(flag("Team 1", 1, 0)),
+			team:     "Team 1",
+		},
+		{
+			name:     "flag moved in the field",
+			previous: state(flag("Team 1", 5, 5)),
+			current:  state(flag("Team 1", 5, 6)),
+			team:     "Team 1",
+		},
+		{
+			name:     "flag back on its base",
+			previous: state(flag("Team 1", 1, 0)),
+			current:  state(flag("Team 1", 0, 0)),
+			team:     "Team 1",
+			returned: true,
+		},
+		{
+			name:     "flag back on another base of its team",
+			previous: state(flag("Team 1", 1, 9)),
+			current:  state(flag("Team 1", 0, 9)),
+			team:     "Team 1",
+			returned: true,
+		},
+		{
+			name:     "one of two flags back while the other is still away",
+			previous: state(flag("Team 1", 0, 0), flag("Team 1", 1, 9), flag("Team 1", 5, 5)),
+			current:  state(flag("Team 1", 0, 0), flag("Team 1", 0, 9), flag("Team 1", 5, 6)),
+			team:     "Team 1",
+			returned: true,
+		},
+		{
+			name:     "one flag back while another is taken",
+			previous: state(flag("Team 1", 0, 0), flag("Team 1", 1, 9)),
+			current:  state(flag("Team 1", 1, 0), flag("Team 1", 0, 9)),
+			team:     "Team 1",
+		},
+		{
+			name:     "flag of another team back home",
+			previous: state(flag("Team 1", 0, 0), flag("Team 2", 8, 9)),
+			current:  state(flag("Team 1", 0, 0), flag("Team 2", 9, 9)),
+			team:     "Team 1",
+		},
+		{
+			name:     "flag on a base of another team",
+			previous: state(flag("Team 1", 8, 9)),
+			current:  state(flag("Team 1", 9, 9)),
+			team:     "Team 1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if returned := flag_returned(test.previous, test.current, test.team); returned != test.returned {
+				t.Errorf("flag_returned is %v, want %v", returned, test.returned)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math"
+	"sync"
+)
+
+// CellKnowledge is what we know about one cell of the board.
+type CellKnowledge struct {
+	Wall bool
+	Base bool
+	// Seen is the tick the cell was last visible, 0 if it never was.
+	Seen int
+}
+
+// WorldModel remembers the board as far as we have seen it. The server
+// currently shows the whole board, so every cell is visible every tick; a
+// state that lists its Visible cells only updates those, and everything
+// else keeps what was observed last. Strategies and the path search ask the
+// model instead of assuming perfect information.
+type WorldModel struct {
+	// Optimism is how the path search treats cells never seen: 1 walks
+	// through them like free cells, 0 treats them as blocked and values in
+	// between charge up to UnknownPenalty extra steps.
+	Optimism       float64
+	UnknownPenalty int
+	mutex          sync.Mutex
+	size           int
+	tick           int
+	cells          map[Coordinates]*CellKnowledge
+}
+
+func new_world_model() *WorldModel {
+	return &WorldModel{Optimism: 0.5, UnknownPenalty: 4, cells: make(map[Coordinates]*CellKnowledge)}
+}
+
+var World = new_world_model()
+
+// visible_cells lists the cells of the state that were observed this tick.
+func visible_cells(state GameState, size int) []Coordinates {
+	if state.Visible != nil {
+		return state.Visible
+	}
+	cells := make([]Coordinates, 0, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			cells = append(cells, Coordinates{X: x, Y: y})
+		}
+	}
+	return cells
+}
+
+func (w *WorldModel) Observe(state GameState, size int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	size = board_size(state, size)
+	if state.Tick < w.tick || size != w.size {
+		w.cells = make(map[Coordinates]*CellKnowledge)
+	}
+	w.tick = state.Tick
+	w.size = size
+	walls := make(map[Coordinates]bool, len(state.Walls))
+	for _, wall := range state.Walls {
+		walls[wall] = true
+	}
+	bases := make(map[Coordinates]bool, len(state.Bases))
+	for _, base := range state.Bases {
+		bases[base.Coordinates] = true
+	}
+	for _, c := range visible_cells(state, size) {
+		w.cells[c] = &CellKnowledge{Wall: walls[c], Base: bases[c], Seen: state.Tick}
+	}
+}
+
+// Cell returns what is known about c.
+func (w *WorldModel) Cell(c Coordinates) (CellKnowledge, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if k, ok := w.cells[c]; ok {
+		return *k, true
+	}
+	return CellKnowledge{}, false
+}
+
+// Age is how many ticks ago c was last seen, -1 for never.
+func (w *WorldModel) Age(c Coordinates) int {
+	k, ok := w.Cell(c)
+	if !ok {
+		return -1
+	}
+	return w.tick - k.Seen
+}
+
+// Unknown lists the cells of the board that were never seen.
+func (w *WorldModel) Unknown() []Coordinates {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	unknown := make([]Coordinates, 0)
+	for y := 0; y < w.size; y++ {
+		for x := 0; x < w.size; x++ {
+			if _, ok := w.cells[Coordinates{X: x, Y: y}]; !ok {
+				unknown = append(unknown, Coordinates{X: x, Y: y})
+			}
+		}
+	}
+	return unknown
+}
+
+// Remembered adds to the obstacles and step costs of the path search what
+// is known about cells out of sight: remembered walls and bases block, and
+// unknown cells block or cost extra depending on Optimism.
+func (w *WorldModel) Remembered(obstacles map[Coordinates]bool, costs map[Coordinates]int) {
+	penalty := int(math.Round((1 - w.Optimism) * float64(w.UnknownPenalty)))
+	for _, c := range w.Unknown() {
+		switch {
+		case w.Optimism <= 0:
+			obstacles[c] = true
+		case penalty > 0:
+			costs[c] += penalty
+		}
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for c, k := range w.cells {
+		if k.Seen < w.tick && (k.Wall || k.Base) {
+			obstacles[c] = true
+		}
+	}
+}
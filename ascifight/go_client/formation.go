@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// formation_radius is how close actors heading for the same target have
+	// to be to move as a group.
+	formation_radius = 2
+	// formation_slack is how far a follower may fall behind its slot before
+	// the leader waits for it.
+	formation_slack = 3
+	// formation_patience is how many ticks in a row a leader waits at most.
+	formation_patience = 2
+)
+
+// Formation is a group of actors on the way to the same target. The leader
+// walks its path, the followers keep the offsets they had to it when the
+// group formed.
+type Formation struct {
+	Leader  int
+	Offsets map[int]Coordinates
+	waited  int
+}
+
+// FormationBoard keeps actors that head for the same target together. The
+// planner tells every actor's target while the strategy runs, and Arrange
+// then rewrites its orders. A follower steps towards its slot next to where
+// the leader goes, and where the slot is blocked, in a corridor or between
+// walls, it follows its own path through. A leader waits a tick for
+// followers that fell far behind, unless it carries a flag.
+type FormationBoard struct {
+	mutex      sync.Mutex
+	tick       int
+	formations map[Coordinates]*Formation
+}
+
+func new_formation_board() *FormationBoard {
+	return &FormationBoard{formations: make(map[Coordinates]*Formation)}
+}
+
+// Formations are applied to the built-in strategies after the pincers, see
+// CoordinatedStrategy.
+var Formations = new_formation_board()
+
+// formation_groups puts the moving actors with the same target into groups
+// of actors each within formation_radius of another one.
+func formation_groups(movers []Actor, targets map[int]Coordinates) map[Coordinates][]Actor {
+	by_target := make(map[Coordinates][]Actor)
+	for _, actor := range movers {
+		if target, ok := targets[actor.Ident]; ok {
+			by_target[target] = append(by_target[target], actor)
+		}
+	}
+	groups := make(map[Coordinates][]Actor)
+	for target, actors := range by_target {
+		if len(actors) < 2 {
+			continue
+		}
+		// the largest cluster around the actor closest to the target
+		sort.SliceStable(actors, func(i, j int) bool {
+			return distance(actors[i].Coordinates, target) < distance(actors[j].Coordinates, target)
+		})
+		group := []Actor{actors[0]}
+		rest := actors[1:]
+		for grown := true; grown; {
+			grown = false
+			for i := 0; i < len(rest); i++ {
+				for _, member := range group {
+					if distance(rest[i].Coordinates, member.Coordinates) <= formation_radius {
+						group = append(group, rest[i])
+						rest = append(rest[:i:i], rest[i+1:]...)
+						i--
+						grown = true
+						break
+					}
+				}
+			}
+		}
+		if len(group) > 1 {
+			groups[target] = group
+		}
+	}
+	return groups
+}
+
+// Arrange rewrites the moves of the actors that travel in a group.
+func (b *FormationBoard) Arrange(state GameState, orders []Order) []Order {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if state.Tick < b.tick {
+		b.formations = make(map[Coordinates]*Formation)
+	}
+	b.tick = state.Tick
+	actors := make(map[int]Actor)
+	for _, actor := range filter_objects(state.Actors, true) {
+		actors[actor.Ident] = actor
+	}
+	moves := make(map[int]int)
+	movers := make([]Actor, 0)
+	for i, order := range orders {
+		if order.order_type == "move" {
+			if _, ok := moves[order.actor]; !ok {
+				moves[order.actor] = i
+				movers = append(movers, actors[order.actor])
+			}
+		}
+	}
+	groups := formation_groups(movers, Plans.Targets())
+	for target := range b.formations {
+		if _, ok := groups[target]; !ok {
+			delete(b.formations, target)
+		}
+	}
+	// a slot is walled off by walls and bases, actors only block a step
+	walled := make(map[Coordinates]bool)
+	for _, wall := range state.Walls {
+		walled[wall] = true
+	}
+	for _, base := range state.Bases {
+		walled[base.Coordinates] = true
+	}
+	blocked := make(map[Coordinates]bool)
+	for c := range walled {
+		blocked[c] = true
+	}
+	for _, actor := range state.Actors {
+		blocked[actor.Coordinates] = true
+	}
+	size := board_size(state, GameRules.MapSize())
+	held := make(map[int]bool)
+	targets := make([]Coordinates, 0, len(groups))
+	for target := range groups {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].X < targets[j].X || (targets[i].X == targets[j].X && targets[i].Y < targets[j].Y)
+	})
+	for _, target := range targets {
+		group := groups[target]
+		f := b.form(target, group)
+		leader := actors[f.Leader]
+		next := predicted_position(leader.Coordinates, orders[moves[f.Leader]].direction)
+		// cells taken by the moves of the group this tick
+		blocked[next] = true
+		lagging := false
+		for _, actor := range group {
+			offset, ok := f.Offsets[actor.Ident]
+			// carriers keep the way they were steered
+			if actor.Ident == f.Leader || !ok || actor.Flag != "" {
+				continue
+			}
+			slot := Coordinates{next.X + offset.X, next.Y + offset.Y}
+			d := distance(actor.Coordinates, slot)
+			if d > formation_slack {
+				lagging = true
+			}
+			order := &orders[moves[actor.Ident]]
+			if d == 0 {
+				held[actor.Ident] = true
+				continue
+			}
+			direction := find_path(actor.Coordinates, slot)
+			step := predicted_position(actor.Coordinates, direction)
+			inside := slot.X >= 0 && slot.Y >= 0 && slot.X < size && slot.Y < size
+			switch {
+			case distance(step, target) > distance(actor.Coordinates, target):
+				// ahead of its slot, it lets the leader pass
+				held[actor.Ident] = true
+			case inside && !walled[slot] && !blocked[step]:
+				blocked[step] = true
+				order.direction = direction
+				order.reason = fmt.Sprintf("%s, in formation behind actor %d", order.reason, f.Leader)
+			default:
+				// the slot is walled off, it keeps to its own path
+				if own := predicted_position(actor.Coordinates, order.direction); blocked[own] {
+					held[actor.Ident] = true
+				} else {
+					blocked[own] = true
+				}
+			}
+		}
+		if lagging && leader.Flag == "" && f.waited < formation_patience {
+			f.waited++
+			held[f.Leader] = true
+			logf(VerbosityDebug, "actor %d waits for its formation to close up", f.Leader)
+		} else {
+			f.waited = 0
+		}
+	}
+	if len(held) == 0 {
+		return orders
+	}
+	kept := make([]Order, 0, len(orders))
+	for i, order := range orders {
+		if !held[order.actor] || moves[order.actor] != i {
+			kept = append(kept, order)
+		}
+	}
+	return kept
+}
+
+// form returns the formation of group, the one it had before while its
+// leader is still in it and else a new one led by the actor closest to the
+// target. Members that joined since get the offset they have now.
+func (b *FormationBoard) form(target Coordinates, group []Actor) *Formation {
+	f, ok := b.formations[target]
+	leader, found := Actor{}, false
+	for _, actor := range group {
+		if ok && actor.Ident == f.Leader {
+			leader, found = actor, true
+		}
+	}
+	if !found {
+		// groups list the actor closest to the target first
+		leader = group[0]
+		f = &Formation{Leader: leader.Ident, Offsets: make(map[int]Coordinates)}
+		b.formations[target] = f
+	}
+	members := make(map[int]bool, len(group))
+	for _, actor := range group {
+		members[actor.Ident] = true
+		if _, ok := f.Offsets[actor.Ident]; !ok && actor.Ident != f.Leader {
+			f.Offsets[actor.Ident] = Coordinates{actor.Coordinates.X - leader.Coordinates.X, actor.Coordinates.Y - leader.Coordinates.Y}
+		}
+	}
+	for ident := range f.Offsets {
+		if !members[ident] {
+			delete(f.Offsets, ident)
+		}
+	}
+	return f
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormationGroups(t *testing.T) {
+	target := Coordinates{9, 9}
+	other := Coordinates{0, 9}
+	tests := []struct {
+		name    string
+		movers  []Actor
+		targets map[int]Coordinates
+		// groups holds the idents of every group, closest to the target
+		// first
+		groups map[Coordinates][]int
+	}{
+		{
+			name:    "a single actor is no group",
+			movers:  []Actor{our_actor(0, 5, 5)},
+			targets: map[int]Coordinates{0: target},
+			groups:  map[Coordinates][]int{},
+		},
+		{
+			name:    "close actors with the same target form a group",
+			movers:  []Actor{our_actor(0, 4, 4), our_actor(1, 5, 5)},
+			targets: map[int]Coordinates{0: target, 1: target},
+			groups:  map[Coordinates][]int{target: {1, 0}},
+		},
+		{
+			name:    "actors with different targets stay apart",
+			movers:  []Actor{our_actor(0, 4, 4), our_actor(1, 5, 5)},
+			targets: map[int]Coordinates{0: target, 1: other},
+			groups:  map[Coordinates][]int{},
+		},
+		{
+			name:    "actors without a target are left out",
+			movers:  []Actor{our_actor(0, 4, 4), our_actor(1, 5, 5)},
+			targets: map[int]Coordinates{1: target},
+			groups:  map[Coordinates][]int{},
+		},
+		{
+			name:    "far actors with the same target stay apart",
+			movers:  []Actor{our_actor(0, 1, 1), our_actor(1, 5, 5)},
+			targets: map[int]Coordinates{0: target, 1: target},
+			groups:  map[Coordinates][]int{},
+		},
+		{
+			name:    "a chain of close actors is one group",
+			movers:  []Actor{our_actor(0, 1, 1), our_actor(1, 3, 1), our_actor(2, 5, 1)},
+			targets: map[int]Coordinates{0: target, 1: target, 2: target},
+			groups:  map[Coordinates][]int{target: {2, 1, 0}},
+		},
+		{
+			name:    "only the cluster around the actor closest to the target",
+			movers:  []Actor{our_actor(0, 0, 0), our_actor(1, 1, 0), our_actor(2, 7, 7), our_actor(3, 8, 7)},
+			targets: map[int]Coordinates{0: target, 1: target, 2: target, 3: target},
+			groups:  map[Coordinates][]int{target: {3, 2}},
+		},
+		{
+			name:    "groups for two targets",
+			movers:  []Actor{our_actor(0, 4, 4), our_actor(1, 5, 5), our_actor(2, 1, 8), our_actor(3, 2, 8)},
+			targets: map[int]Coordinates{0: target, 1: target, 2: other, 3: other},
+			groups:  map[Coordinates][]int{target: {1, 0}, other: {2, 3}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groups := make(map[Coordinates][]int)
+			for target, group := range formation_groups(test.movers, test.targets) {
+				for _, actor := range group {
+					groups[target] = append(groups[target], actor.Ident)
+				}
+			}
+			if !reflect.DeepEqual(groups, test.groups) {
+				t.Errorf("groups %v, want %v", groups, test.groups)
+			}
+		})
+	}
+}
@@ -1,3 +1,5 @@
 module ascifight_client
 
 go 1.20
+
+require github.com/d5/tengo/v2 v2.17.0
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// golden_seed is the seed the golden orders are decided with.
+const golden_seed = 1
+
+// GoldenOrders are the recorded orders of every strategy in one scenario.
+// They are kept next to the scenario file with the extension .golden.
+type GoldenOrders struct {
+	Scenario string                   `json:"scenario"`
+	Seed     int64                    `json:"seed"`
+	Orders   map[string][]PluginOrder `json:"orders"`
+}
+
+func golden_path(s Scenario) string {
+	return strings.TrimSuffix(s.Path, ".json") + ".golden"
+}
+
+func read_golden(path string) (GoldenOrders, error) {
+	golden := GoldenOrders{Orders: make(map[string][]PluginOrder)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return golden, err
+	}
+	if err := json.Unmarshal(data, &golden); err != nil {
+		return golden, fmt.Errorf("%s: %w", path, err)
+	}
+	if golden.Orders == nil {
+		golden.Orders = make(map[string][]PluginOrder)
+	}
+	return golden, nil
+}
+
+func write_golden(path string, golden GoldenOrders) error {
+	data, err := json.MarshalIndent(golden, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// GoldenDiff is a strategy whose orders in a scenario changed. New marks a
+// strategy without recorded orders.
+type GoldenDiff struct {
+	Scenario string
+	Strategy string
+	New      bool
+	Golden   []PluginOrder
+	Current  []PluginOrder
+}
+
+// check_golden decides the orders of strategies in s and compares them with
+// the recorded ones. With update the recorded orders are replaced by the
+// current ones instead.
+func check_golden(s Scenario, strategies []Strategy, reasons bool, update bool) ([]GoldenDiff, error) {
+	path := golden_path(s)
+	golden, err := read_golden(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	golden.Scenario = s.Name
+	diffs := make([]GoldenDiff, 0)
+	for _, strategy := range strategies {
+		orders, err := s.Orders(strategy, golden_seed)
+		if err != nil {
+			return nil, err
+		}
+		current := recorded_orders(orders)
+		recorded, ok := golden.Orders[strategy.Name()]
+		if ok && golden.Seed == golden_seed && same_orders(recorded, current, reasons) {
+			continue
+		}
+		diffs = append(diffs, GoldenDiff{Scenario: s.Name, Strategy: strategy.Name(), New: !ok, Golden: recorded, Current: current})
+		golden.Orders[strategy.Name()] = current
+	}
+	if update && len(diffs) > 0 {
+		golden.Seed = golden_seed
+		if err := write_golden(path, golden); err != nil {
+			return nil, err
+		}
+	}
+	return diffs, nil
+}
+
+// golden_command runs the strategies over a library of scenarios and
+// compares their orders with the recorded golden orders, so that a change
+// of strategy code shows up as a reviewable diff of the decisions. It exits
+// with status 1 on any difference unless -update records the new orders.
+func golden_command(args []string) {
+	flags := flag.NewFlagSet("golden", flag.ExitOnError)
+	strategy_list := flags.String("strategies", strings.Join(strategy_names(), ","), "comma separated strategies to check")
+	update := flags.Bool("update", false, "record the current orders as the new golden orders")
+	reasons := flags.Bool("reasons", false, "also compare the reasons and priorities of the orders")
+	config_flags := add_config_flags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s golden [flags] [scenario.json|directory...]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := config_flags.Apply(); err != nil {
+		log.Fatalln(err)
+	}
+	strategies, err := lookup_strategies(*strategy_list)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"scenarios"}
+	}
+	scenarios := make([]Scenario, 0)
+	for _, path := range paths {
+		loaded, err := load_scenarios(path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		scenarios = append(scenarios, loaded...)
+	}
+	sort.SliceStable(scenarios, func(i, j int) bool { return scenarios[i].Path < scenarios[j].Path })
+	changed := 0
+	for _, s := range scenarios {
+		diffs, err := check_golden(s, strategies, *reasons, *update)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, d := range diffs {
+			if d.New {
+				fmt.Printf("%s, %s: no golden orders\n", d.Scenario, d.Strategy)
+			} else {
+				fmt.Printf("%s, %s: orders changed\n", d.Scenario, d.Strategy)
+				print_orders("golden", d.Golden)
+			}
+			print_orders("current", d.Current)
+		}
+		changed += len(diffs)
+	}
+	checked := len(scenarios) * len(strategies)
+	switch {
+	case changed == 0:
+		fmt.Printf("%d scenarios, %d strategies, all orders match\n", len(scenarios), len(strategies))
+	case *update:
+		fmt.Printf("%d of %d golden orders updated\n", changed, checked)
+	default:
+		fmt.Printf("%d of %d golden orders differ, rerun with -update to accept them\n", changed, checked)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health tracks the progress of the tick loop for external monitors.
+type Health struct {
+	mutex       sync.Mutex
+	stale_after time.Duration
+	started     time.Time
+	last_tick   int
+	tick_at     time.Time
+	fetch_at    time.Time
+	last_error  string
+	error_at    time.Time
+	missed      int
+	acceptance  *AcceptanceReport
+}
+
+type HealthReport struct {
+	Status        string     `json:"status"`
+	Connected     bool       `json:"connected"`
+	LastTick      int        `json:"last_tick"`
+	LastTickAt    time.Time  `json:"last_tick_at"`
+	LastFetchAt   time.Time  `json:"last_fetch_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	UptimeSeconds float64    `json:"uptime_seconds"`
+	// MissedTicks counts the ticks the bot did not play since it started.
+	MissedTicks int `json:"missed_ticks"`
+	// Acceptance is the share of orders the server accepted lately.
+	Acceptance *AcceptanceReport `json:"acceptance,omitempty"`
+}
+
+type AcceptanceReport struct {
+	Rate     float64  `json:"rate"`
+	Baseline *float64 `json:"baseline,omitempty"`
+	Alerting bool     `json:"alerting"`
+	Alerts   int      `json:"alerts"`
+}
+
+func new_health(stale_after time.Duration) *Health {
+	return &Health{stale_after: stale_after, started: time.Now()}
+}
+
+func (h *Health) Fetched() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.fetch_at = time.Now()
+}
+
+func (h *Health) Ticked(tick int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.last_tick = tick
+	h.tick_at = time.Now()
+}
+
+func (h *Health) Missed(ticks int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.missed += ticks
+}
+
+func (h *Health) Accepted(m *AcceptanceMonitor) {
+	report := &AcceptanceReport{Alerting: m.Alerting(), Alerts: m.Alerts()}
+	report.Rate, _ = m.Rate()
+	if baseline, ok := m.Baseline(); ok {
+		report.Baseline = &baseline
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.acceptance = report
+}
+
+func (h *Health) Failed(err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.last_error = err.Error()
+	h.error_at = time.Now()
+}
+
+// Report is healthy while a state fetch succeeded within the stale period.
+// Before the first fetch the bot counts as starting, which is alive but not
+// ready.
+func (h *Health) Report() HealthReport {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	report := HealthReport{
+		Connected:     !h.fetch_at.IsZero() && !h.error_at.After(h.fetch_at),
+		LastTick:      h.last_tick,
+		LastTickAt:    h.tick_at,
+		LastFetchAt:   h.fetch_at,
+		LastError:     h.last_error,
+		UptimeSeconds: time.Since(h.started).Seconds(),
+		MissedTicks:   h.missed,
+		Acceptance:    h.acceptance,
+	}
+	if !h.error_at.IsZero() {
+		error_at := h.error_at
+		report.LastErrorAt = &error_at
+	}
+	switch {
+	case h.fetch_at.IsZero() && time.Since(h.started) < h.stale_after:
+		report.Status = "starting"
+	case time.Since(h.fetch_at) > h.stale_after:
+		report.Status = "stale"
+	default:
+		report.Status = "ok"
+	}
+	return report
+}
+
+func (h *Health) serve(address string) {
+	write := func(w http.ResponseWriter, ok bool) {
+		data, _ := json.Marshal(h.Report())
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(data)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		write(w, h.Report().Status != "stale")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, req *http.Request) {
+		report := h.Report()
+		write(w, report.Status == "ok" && report.Connected)
+	})
+	// the log level can be switched during a game, e.g.
+	// curl -X POST localhost:8081/loglevel?level=debug
+	mux.HandleFunc("/loglevel", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost || req.Method == http.MethodPut {
+			level, err := parse_verbosity(req.URL.Query().Get("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			set_verbosity(level)
+			log.Printf("log level set to %s", level)
+		}
+		fmt.Fprintln(w, current_verbosity())
+	})
+	go func() {
+		logf(VerbosityNormal, "health endpoint listening on %s", address)
+		if err := http.ListenAndServe(address, mux); err != nil {
+			log.Printf("health endpoint stopped: %v", err)
+		}
+	}()
+}
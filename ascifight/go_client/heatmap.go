@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+	"sync"
+)
+
+// Heatmap accumulates where enemy actors spent their time. Every tick the
+// old heat fades by Decay and each enemy adds heat to its cell and, at
+// half strength, to the cells it could reach next.
+type Heatmap struct {
+	Decay float64
+	mutex sync.Mutex
+	size  int
+	tick  int
+	heat  map[Coordinates]float64
+	peak  float64
+}
+
+func new_heatmap(decay float64) *Heatmap {
+	return &Heatmap{Decay: decay, heat: make(map[Coordinates]float64)}
+}
+
+// EnemyHeat is fed by the planner with every state it sees.
+var EnemyHeat = new_heatmap(0.95)
+
+func (h *Heatmap) Observe(state GameState) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if state.Tick < h.tick {
+		h.heat = make(map[Coordinates]float64)
+	} else if state.Tick == h.tick {
+		return
+	}
+	fade := math.Pow(h.Decay, float64(state.Tick-h.tick))
+	h.tick = state.Tick
+	h.peak = 0
+	for c, value := range h.heat {
+		if value *= fade; value < 0.01 {
+			delete(h.heat, c)
+		} else {
+			h.heat[c] = value
+		}
+	}
+	for _, enemy := range filter_objects(state.Actors, false) {
+		h.heat[enemy.Coordinates] += 1
+		for _, direction := range plan_directions {
+			h.heat[predicted_position(enemy.Coordinates, direction)] += 0.5
+		}
+	}
+	for _, value := range h.heat {
+		h.peak = math.Max(h.peak, value)
+	}
+}
+
+// Level is the heat of c relative to the hottest cell, between 0 and 1.
+func (h *Heatmap) Level(c Coordinates) float64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.peak == 0 {
+		return 0
+	}
+	return h.heat[c] / h.peak
+}
+
+// Costs turns the heat into whole extra steps for the path search, weight
+// steps for the hottest cell. Rounding keeps the cells whose cost changes
+// from one tick to the next few, so incremental searches stay cheap.
+func (h *Heatmap) Costs(weight float64) map[Coordinates]int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	costs := make(map[Coordinates]int)
+	if h.peak == 0 || weight <= 0 {
+		return costs
+	}
+	for c, value := range h.heat {
+		if cost := int(math.Round(weight * value / h.peak)); cost > 0 {
+			costs[c] = cost
+		}
+	}
+	return costs
+}
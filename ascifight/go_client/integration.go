@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// IntegrationConfig is the server configuration the integration run boots
+// the server with, so that the rules it reports can be checked.
+type IntegrationConfig struct {
+	MapSize  int
+	MaxTicks int
+	MaxScore int
+	TickWait float64
+	PreGame  float64
+	Actors   []string
+	Teams    []ArenaBot
+}
+
+// Toml renders the configuration in the format of ascifight/config.toml.
+func (c IntegrationConfig) Toml() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[server]\npre_game_wait = %g\ntick_wait_time = %g\nlog_dir = \"logs\"\nscores_file = \"scores.log\"\n\n", c.PreGame, c.TickWait)
+	actors := make([]string, 0, len(c.Actors))
+	for _, actor := range c.Actors {
+		actors = append(actors, fmt.Sprintf("%q", actor))
+	}
+	fmt.Fprintf(&b, "[game]\nmap_size = %d\nmax_score = %d\nmax_ticks = %d\ncapture_score = 5\nkill_score = 1\nwinning_bonus = 10\nhome_flag_required = false\nwalls = 0\nactors = [%s]\n\n",
+		c.MapSize, c.MaxScore, c.MaxTicks, strings.Join(actors, ", "))
+	for _, team := range c.Teams {
+		fmt.Fprintf(&b, "[[teams]]\nname = %q\npassword = %q\n\n", team.Team, team.Password)
+	}
+	b.WriteString("[image]\nsize = 800\n")
+	return b.String()
+}
+
+// copy_server_package copies the Python package below server_dir to dir,
+// leaving out the Go client, logs and caches, and writes config into it.
+// The server reads its configuration next to its sources, so this is how
+// it is started with a known configuration without touching the original.
+func copy_server_package(server_dir string, dir string, config IntegrationConfig) error {
+	source := filepath.Join(server_dir, "ascifight")
+	if _, err := os.Stat(filepath.Join(source, "config.py")); err != nil {
+		return fmt.Errorf("no ascifight server package in %s: %w", server_dir, err)
+	}
+	target := filepath.Join(dir, "ascifight")
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, _ := filepath.Rel(source, path)
+		if info.IsDir() {
+			switch info.Name() {
+			case "go_client", "logs", "__pycache__", ".git":
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(target, relative), 0755)
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(filepath.Join(target, relative))
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(target, "config.toml"), []byte(config.Toml()), 0644)
+}
+
+type IntegrationCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Skipped checks need something the server does not offer.
+	Skipped bool   `json:"skipped,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+type IntegrationReport struct {
+	Started time.Time          `json:"started"`
+	Ended   time.Time          `json:"ended"`
+	Checks  []IntegrationCheck `json:"checks"`
+}
+
+func (r *IntegrationReport) Check(name string, err error) bool {
+	check := IntegrationCheck{Name: name, Passed: err == nil}
+	if err != nil {
+		check.Detail = err.Error()
+	}
+	r.add(check)
+	return err == nil
+}
+
+func (r *IntegrationReport) Skip(name string, reason string) {
+	r.add(IntegrationCheck{Name: name, Passed: true, Skipped: true, Detail: reason})
+}
+
+func (r *IntegrationReport) add(check IntegrationCheck) {
+	status := "ok"
+	switch {
+	case check.Skipped:
+		status = "skip"
+	case !check.Passed:
+		status = "FAIL"
+	}
+	line := fmt.Sprintf("%-4s %s", status, check.Name)
+	if check.Detail != "" {
+		line += ": " + check.Detail
+	}
+	fmt.Println(line)
+	r.Checks = append(r.Checks, check)
+}
+
+func (r *IntegrationReport) Failed() int {
+	failed := 0
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+func check_rules(config IntegrationConfig) error {
+	rules, err := GameRules.Get()
+	if err != nil {
+		return err
+	}
+	problems := make([]string, 0)
+	if rules.MapSize != config.MapSize {
+		problems = append(problems, fmt.Sprintf("map size %d instead of %d", rules.MapSize, config.MapSize))
+	}
+	if rules.MaxTicks != config.MaxTicks {
+		problems = append(problems, fmt.Sprintf("%d ticks instead of %d", rules.MaxTicks, config.MaxTicks))
+	}
+	for _, actor := range config.Actors {
+		if _, ok := GameRules.ActorProperty(actor); !ok {
+			problems = append(problems, fmt.Sprintf("no properties for actor type %s", actor))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// check_auth expects a wrong password to be refused and an order with an
+// invalid direction to pass authentication but fail validation. Neither
+// changes the game.
+func check_auth(report *IntegrationReport) {
+	password := Password
+	Password = password + "-wrong"
+	err := DefaultClient.SubmitOrder(context.Background(), Order{order_type: "move", actor: 0, direction: "nowhere"})
+	Password = password
+	if !errors.Is(err, ErrUnauthorized) {
+		err = fmt.Errorf("a wrong password was answered with %v", err)
+	} else {
+		err = nil
+	}
+	report.Check("wrong password is refused", err)
+	err = DefaultClient.SubmitOrder(context.Background(), Order{order_type: "move", actor: 0, direction: "nowhere"})
+	if !errors.Is(err, ErrInvalidOrder) {
+		err = fmt.Errorf("an invalid direction was answered with %v", err)
+	} else {
+		err = nil
+	}
+	report.Check("invalid order is rejected", err)
+}
+
+func check_registration(report *IntegrationReport) {
+	can_register, can_verify, err := registration_routes()
+	if err != nil {
+		report.Check("registration", err)
+		return
+	}
+	if !can_register {
+		report.Skip("registration", "the server does not offer "+register_route)
+		return
+	}
+	name := "integration " + time.Now().Format("150405")
+	var registration Registration
+	err = api_request(context.Background(), "POST", register_route[1:], nil, Registration{Name: name}, false, &registration)
+	if !report.Check("registration of "+name, err) || !can_verify {
+		return
+	}
+	team, password := Team, Password
+	Team, Password = registration.Name, registration.Password
+	report.Check("login of the registered team", verify_login())
+	Team, Password = team, password
+}
+
+// check_state asserts what has to hold for every state of the configured
+// game.
+func check_state(state GameState, config IntegrationConfig) error {
+	if err := validate_state(state, config.MapSize); err != nil {
+		return err
+	}
+	bases := make(map[string]int)
+	for _, base := range state.Bases {
+		bases[base.Team]++
+	}
+	actors := make(map[string]int)
+	for _, actor := range state.Actors {
+		actors[actor.Team]++
+	}
+	problems := make([]string, 0)
+	for _, team := range config.Teams {
+		if _, ok := state.Scores[team.Team]; !ok {
+			problems = append(problems, "no score for "+team.Team)
+		}
+		if bases[team.Team] == 0 {
+			problems = append(problems, "no base of "+team.Team)
+		}
+		if actors[team.Team] == 0 {
+			problems = append(problems, "no actors of "+team.Team)
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// free_move returns a move of actor onto a free cell of the board.
+func free_move(state GameState, actor Actor, size int) (Order, bool) {
+	blocked := make(map[Coordinates]bool)
+	for _, wall := range state.Walls {
+		blocked[wall] = true
+	}
+	for _, base := range state.Bases {
+		blocked[base.Coordinates] = true
+	}
+	for _, a := range state.Actors {
+		blocked[a.Coordinates] = true
+	}
+	for _, direction := range plan_directions {
+		c := predicted_position(actor.Coordinates, direction)
+		if c.X >= 0 && c.Y >= 0 && c.X < size && c.Y < size && !blocked[c] {
+			return Order{order_type: "move", actor: actor.Ident, direction: direction, reason: "integration"}, true
+		}
+	}
+	return Order{}, false
+}
+
+// play_integration_ticks follows the game for a number of ticks, checks
+// every state and moves our first actor, expecting the moves to show up in
+// the following states.
+func play_integration_ticks(report *IntegrationReport, config IntegrationConfig, ticks int, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	var previous *GameState
+	var sent []Order
+	var rejected []Order
+	observed, moved, failed := 0, 0, 0
+	transitions := make([]string, 0)
+	for observed < ticks {
+		if time.Now().After(deadline) {
+			report.Check("ticks advance", fmt.Errorf("only %d of %d ticks within %v", observed, ticks, timeout))
+			return
+		}
+		state, err := DefaultClient.GameState(context.Background())
+		if err != nil {
+			report.Check("fetching the game state", err)
+			return
+		}
+		if previous != nil && state.Tick == previous.Tick {
+			time.Sleep(50 * time.Millisecond)
+			continue
+		}
+		if previous != nil && state.Tick < previous.Tick {
+			// a new game started, the orders of the old one tell nothing
+			previous, sent = nil, nil
+		}
+		if previous != nil && state.Tick > previous.Tick+1 {
+			transitions = append(transitions, fmt.Sprintf("tick %d followed tick %d", state.Tick, previous.Tick))
+		}
+		observed++
+		if err := check_state(state, config); err != nil {
+			report.Check(fmt.Sprintf("state of tick %d", state.Tick), err)
+			failed++
+		}
+		if previous != nil && len(sent) > 0 {
+			for _, o := range verify_orders(*previous, state, sent, rejected) {
+				if o.Result == "ok" {
+					moved++
+				} else if o.Result != "killed" {
+					transitions = append(transitions, o.String())
+				}
+			}
+		}
+		sent, rejected = nil, nil
+		for _, actor := range state.Actors {
+			if actor.Team != Team {
+				continue
+			}
+			if order, ok := free_move(state, actor, config.MapSize); ok {
+				sent = []Order{order}
+				var err error
+				if rejected, err = DefaultClient.SubmitOrders(context.Background(), sent); err != nil {
+					report.Check("submitting orders", err)
+					return
+				}
+			}
+			break
+		}
+		previous = &state
+	}
+	if failed == 0 {
+		report.Check(fmt.Sprintf("%d states are consistent", observed), nil)
+	}
+	if moved == 0 {
+		transitions = append(transitions, "no move order went through")
+	}
+	if len(transitions) > 0 {
+		report.Check("state transitions", errors.New(strings.Join(transitions, "; ")))
+	} else {
+		report.Check(fmt.Sprintf("state transitions, %d moves went through", moved), nil)
+	}
+}
+
+// integration_command boots the Python server with a known configuration
+// and checks the contract between client and server end to end: version,
+// rules, authentication, registration and the state after a few ticks of
+// orders. It exits with status 1 when a check failed.
+func integration_command(args []string) {
+	flags := flag.NewFlagSet("integration", flag.ExitOnError)
+	server_cmd := flags.String("server-cmd", "uvicorn ascifight.main:app --port 8000", "command that starts the game server, run in the copied server package")
+	server_dir := flags.String("server-dir", ".", "directory containing the ascifight server package")
+	dir := flags.String("dir", "integration", "directory for the server copy, logs and the report")
+	startup := flags.Duration("startup-timeout", 30*time.Second, "how long to wait for the server to come up")
+	ticks := flags.Int("ticks", 5, "ticks to play")
+	map_size := flags.Int("map-size", 15, "board length of the configured game")
+	max_ticks := flags.Int("max-ticks", 50, "length of the configured game")
+	tick_wait := flags.Float64("tick-wait", 0.5, "seconds between ticks of the configured game")
+	actors := flags.String("actors", "Runner", "comma separated actor types of every team in the configured game")
+	flags.StringVar(&ServerUrl, "server", ServerUrl, "base url the started server listens on")
+	flags.Parse(args)
+	normalize_server_url()
+
+	config := IntegrationConfig{MapSize: *map_size, MaxTicks: *max_ticks, MaxScore: 100, TickWait: *tick_wait, PreGame: 1,
+		Actors: strings.Split(*actors, ","),
+		Teams:  []ArenaBot{{Team: "Team 1", Password: "1"}, {Team: "Team 2", Password: "2"}}}
+	Team, Password = config.Teams[0].Team, config.Teams[0].Password
+	Auth = BasicAuth{}
+	server_copy := filepath.Join(*dir, "server")
+	if err := os.RemoveAll(server_copy); err != nil {
+		log.Fatalln(err)
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		log.Fatalln(err)
+	}
+	if err := copy_server_package(*server_dir, server_copy, config); err != nil {
+		log.Fatalln(err)
+	}
+	report := &IntegrationReport{Started: time.Now()}
+	server, err := start_arena_server(*server_cmd, server_copy, *dir, *startup)
+	if report.Check("server starts", err) {
+		report.Check("server version", check_server_version())
+		report.Check("game rules", check_rules(config))
+		check_auth(report)
+		check_registration(report)
+		timeout := *startup + time.Duration(float64(*ticks+2)*config.TickWait*float64(time.Second))
+		play_integration_ticks(report, config, *ticks, timeout)
+		server.Stop()
+	}
+	report.Ended = time.Now()
+	if err := write_json_report(report, *dir, "integration", report.Ended); err != nil {
+		log.Printf("writing integration report failed: %v", err)
+	}
+	failed := report.Failed()
+	fmt.Printf("%d checks, %d failed\n", len(report.Checks), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
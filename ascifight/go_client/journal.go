@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// PendingOrders are orders decided for a tick that were not submitted yet.
+type PendingOrders struct {
+	Team string `json:"team"`
+	Game string `json:"game,omitempty"`
+	Tick int    `json:"tick"`
+	// Execution is the time of next execution the server announced for the
+	// tick, telling it apart from the same tick of another game.
+	Execution string        `json:"execution"`
+	Orders    []PluginOrder `json:"orders"`
+}
+
+// OrderJournal keeps the orders of the current tick in a file from the
+// moment they are decided until they were submitted. A bot that died in
+// between finds them there after a quick restart and submits them before
+// the deadline instead of losing the tick. Orders that went out before the
+// crash are sent again.
+type OrderJournal struct {
+	path string
+}
+
+func new_order_journal(path string) *OrderJournal {
+	return &OrderJournal{path: path}
+}
+
+// Write replaces the journal with the orders of state. The file is written
+// next to the journal and renamed over it, so a crash while writing leaves
+// the previous journal intact rather than half of the new one.
+func (j *OrderJournal) Write(state GameState, orders []Order) error {
+	data, err := json.Marshal(PendingOrders{Team: Team, Game: GameID, Tick: state.Tick, Execution: state.TimeOfNextExecution, Orders: recorded_orders(orders)})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+	temporary := j.path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temporary, j.path)
+}
+
+// Clear empties the journal once the orders were submitted.
+func (j *OrderJournal) Clear() error {
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Pending returns the journaled orders if they belong to the tick of state
+// in the game the bot plays. Orders of any other tick are stale and cleared.
+func (j *OrderJournal) Pending(state GameState) ([]Order, bool, error) {
+	data, err := os.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var pending PendingOrders
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, false, j.Clear()
+	}
+	if pending.Team != Team || pending.Game != GameID || pending.Tick != state.Tick || pending.Execution != state.TimeOfNextExecution {
+		return nil, false, j.Clear()
+	}
+	return plugin_orders(pending.Orders), true, nil
+}
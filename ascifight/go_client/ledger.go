@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ledger_file      = "results.jsonl"
+	elo_start_rating = 1500.0
+	elo_k_factor     = 32.0
+)
+
+// GameResult is one line of the results ledger.
+type GameResult struct {
+	Time      time.Time `json:"time"`
+	Team      string    `json:"team"`
+	Strategy  string    `json:"strategy"`
+	Opponents []string  `json:"opponents"`
+	Scores    Scores    `json:"scores"`
+	Outcome   string    `json:"outcome"`
+}
+
+func outcome_against(scores Scores, team string, opponent string) float64 {
+	switch {
+	case scores[team] > scores[opponent]:
+		return 1
+	case scores[team] < scores[opponent]:
+		return 0
+	default:
+		return 0.5
+	}
+}
+
+func game_outcome(scores Scores, team string) string {
+	best := true
+	shared := false
+	for name, score := range scores {
+		if name == team {
+			continue
+		}
+		if score > scores[team] {
+			best = false
+		} else if score == scores[team] {
+			shared = true
+		}
+	}
+	switch {
+	case !best:
+		return "loss"
+	case shared:
+		return "draw"
+	default:
+		return "win"
+	}
+}
+
+func new_game_result(s *GameStats) GameResult {
+	opponents := make([]string, 0)
+	for name := range s.FinalScores {
+		if name != s.Team {
+			opponents = append(opponents, name)
+		}
+	}
+	sort.Strings(opponents)
+	return GameResult{
+		Time:      s.Ended,
+		Team:      s.Team,
+		Strategy:  s.Strategy,
+		Opponents: opponents,
+		Scores:    s.FinalScores,
+		Outcome:   game_outcome(s.FinalScores, s.Team),
+	}
+}
+
+func append_result(dir string, result GameResult) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, ledger_file), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(result)
+}
+
+func read_results(dir string) ([]GameResult, error) {
+	file, err := os.Open(filepath.Join(dir, ledger_file))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	results := make([]GameResult, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var result GameResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
+
+func expected_score(rating float64, other float64) float64 {
+	return 1 / (1 + math.Pow(10, (other-rating)/400))
+}
+
+// compute_elo replays the ledger and rates our strategies against the
+// opposing teams, treating every opponent of a game as a separate match.
+func compute_elo(results []GameResult) map[string]float64 {
+	ratings := make(map[string]float64)
+	rating := func(name string) float64 {
+		if r, ok := ratings[name]; ok {
+			return r
+		}
+		return elo_start_rating
+	}
+	for _, result := range results {
+		player := "strategy:" + result.Strategy
+		for _, opponent := range result.Opponents {
+			other := "team:" + opponent
+			r_player, r_other := rating(player), rating(other)
+			actual := outcome_against(result.Scores, result.Team, opponent)
+			ratings[player] = r_player + elo_k_factor*(actual-expected_score(r_player, r_other))
+			ratings[other] = r_other + elo_k_factor*((1-actual)-expected_score(r_other, r_player))
+		}
+	}
+	return ratings
+}
+
+type StrategySummary struct {
+	Strategy     string
+	Games        int
+	Wins         int
+	Draws        int
+	Losses       int
+	RollingWins  int
+	RollingGames int
+	Rating       float64
+}
+
+func summarize_results(results []GameResult, window int) []StrategySummary {
+	ratings := compute_elo(results)
+	by_strategy := make(map[string][]GameResult)
+	for _, result := range results {
+		by_strategy[result.Strategy] = append(by_strategy[result.Strategy], result)
+	}
+	summaries := make([]StrategySummary, 0, len(by_strategy))
+	for strategy, games := range by_strategy {
+		summary := StrategySummary{Strategy: strategy, Games: len(games), Rating: ratings["strategy:"+strategy]}
+		for i, game := range games {
+			rolling := i >= len(games)-window
+			if rolling {
+				summary.RollingGames++
+			}
+			switch game.Outcome {
+			case "win":
+				summary.Wins++
+				if rolling {
+					summary.RollingWins++
+				}
+			case "draw":
+				summary.Draws++
+			default:
+				summary.Losses++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Rating > summaries[j].Rating })
+	return summaries
+}
+
+func percentage(part int, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(whole)
+}
+
+func stats_command(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	dir := flags.String("stats-dir", "stats", "directory containing the results ledger")
+	window := flags.Int("window", 20, "number of most recent games for the rolling winrate")
+	flags.Parse(args)
+
+	results, err := read_results(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading results failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d games in %s\n\n", len(results), filepath.Join(*dir, ledger_file))
+	fmt.Printf("%-20s %7s %5s %5s %5s %8s %10s\n", "strategy", "rating", "games", "wins", "draws", "winrate", "rolling")
+	for _, s := range summarize_results(results, *window) {
+		fmt.Printf("%-20s %7.1f %5d %5d %5d %7.1f%% %9.1f%%\n",
+			s.Strategy, s.Rating, s.Games, s.Wins, s.Draws,
+			percentage(s.Wins, s.Games), percentage(s.RollingWins, s.RollingGames))
+	}
+
+	ratings := compute_elo(results)
+	teams := make([]string, 0)
+	for name := range ratings {
+		if strings.HasPrefix(name, "team:") {
+			teams = append(teams, name)
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool { return ratings[teams[i]] > ratings[teams[j]] })
+	if len(teams) > 0 {
+		fmt.Printf("\n%-20s %7s\n", "opponent", "rating")
+		for _, name := range teams {
+			fmt.Printf("%-20s %7.1f\n", strings.TrimPrefix(name, "team:"), ratings[name])
+		}
+	}
+}
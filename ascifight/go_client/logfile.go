@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile is a log file that is rotated once it grows past MaxSize
+// bytes or gets older than MaxAge. Rotated files are named after the time
+// of rotation, e.g. bot-20240101-120000.log, and only the Keep newest or
+// those younger than Retention survive. Zero values disable the limit.
+type RotatingFile struct {
+	Path      string
+	MaxSize   int64
+	MaxAge    time.Duration
+	Keep      int
+	Retention time.Duration
+	mutex     sync.Mutex
+	file      *os.File
+	size      int64
+	opened    time.Time
+}
+
+func open_rotating_file(path string, max_size int64, max_age time.Duration, keep int, retention time.Duration) (*RotatingFile, error) {
+	f := &RotatingFile{Path: path, MaxSize: max_size, MaxAge: max_age, Keep: keep, Retention: retention}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	if dir := filepath.Dir(f.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.opened = time.Now()
+	return nil
+}
+
+func (f *RotatingFile) rotated_name(at time.Time) string {
+	extension := filepath.Ext(f.Path)
+	return strings.TrimSuffix(f.Path, extension) + "-" + at.Format("20060102-150405") + extension
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	name := f.rotated_name(time.Now())
+	// several rotations within a second must not overwrite each other
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s.%d", f.rotated_name(time.Now()), i)
+	}
+	if err := os.Rename(f.Path, name); err != nil {
+		return err
+	}
+	f.prune()
+	return f.open()
+}
+
+// prune removes the rotated files beyond the retention limits.
+func (f *RotatingFile) prune() {
+	extension := filepath.Ext(f.Path)
+	matches, err := filepath.Glob(strings.TrimSuffix(f.Path, extension) + "-*" + extension + "*")
+	if err != nil {
+		return
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	for i, name := range matches {
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		expired := f.Retention > 0 && time.Since(info.ModTime()) > f.Retention
+		if (f.Keep > 0 && i >= f.Keep) || expired {
+			os.Remove(name)
+		}
+	}
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	too_big := f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize
+	too_old := f.MaxAge > 0 && time.Since(f.opened) >= f.MaxAge
+	if too_big || too_old {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.file.Close()
+}
+
+type LogFlags struct {
+	path      string
+	max_size  int64
+	max_age   time.Duration
+	keep      int
+	retention time.Duration
+	level     string
+	color     string
+}
+
+// add_log_flags registers the log file flags, Apply opens the file after
+// parsing.
+func add_log_flags(flags *flag.FlagSet) *LogFlags {
+	f := &LogFlags{}
+	flags.StringVar(&f.path, "log-file", "", "also write the log to this file")
+	flags.Int64Var(&f.max_size, "log-max-size", 50, "size in MB after which the log file is rotated, 0 disables")
+	flags.DurationVar(&f.max_age, "log-max-age", 0, "age after which the log file is rotated, e.g. 1h, 0 disables")
+	flags.IntVar(&f.keep, "log-keep", 10, "number of rotated log files to keep, 0 keeps all")
+	flags.DurationVar(&f.retention, "log-retention", 0, "age after which rotated log files are removed, 0 keeps them")
+	flags.StringVar(&f.color, "color", "auto", "colored board and scores with the server's team colors: auto, always or never")
+	flags.StringVar(&f.level, "log-level", "normal", "quiet (scores and errors), normal, debug (every order) or trace (full states), switchable at runtime through /loglevel of -health")
+	return f
+}
+
+// Apply sets the log level and sends the log to the file as well as to
+// stderr.
+func (f *LogFlags) Apply() error {
+	level, err := parse_verbosity(f.level)
+	if err != nil {
+		return err
+	}
+	set_verbosity(level)
+	if err := set_color_mode(f.color, f.path != ""); err != nil {
+		return err
+	}
+	if f.path == "" {
+		return nil
+	}
+	file, err := open_rotating_file(f.path, f.max_size*1024*1024, f.max_age, f.keep, f.retention)
+	if err != nil {
+		return err
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, file))
+	return nil
+}
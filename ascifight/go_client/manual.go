@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+type Key struct {
+	char  rune
+	arrow string
+}
+
+// raw_terminal switches the terminal to unbuffered input without echo and
+// returns a function that restores the previous settings.
+func raw_terminal() (func(), error) {
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = os.Stdin
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %w", err)
+	}
+	if _, err := stty("cbreak", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(saved) }, nil
+}
+
+func read_keys(keys chan<- Key) {
+	reader := bufio.NewReader(os.Stdin)
+	arrows := map[rune]string{'A': "up", 'B': "down", 'C': "right", 'D': "left"}
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			close(keys)
+			return
+		}
+		if r == 27 {
+			if next, _, _ := reader.ReadRune(); next == '[' {
+				code, _, _ := reader.ReadRune()
+				if arrow, ok := arrows[code]; ok {
+					keys <- Key{arrow: arrow}
+				}
+			}
+			continue
+		}
+		keys <- Key{char: r}
+	}
+}
+
+type ManualControl struct {
+	state     GameState
+	size      int
+	tick      int
+	next      time.Time
+	selected  int
+	action    string
+	submitted []Order
+	message   string
+}
+
+var manual_hotkeys = map[rune]string{'g': "grabput", 'a': "attack", 'b': "build", 'd': "destroy", 'm': "move"}
+
+func (m *ManualControl) handle(key Key) bool {
+	switch {
+	case key.arrow != "":
+		if _, ok := find_actor(m.state.Actors, Team, m.selected); !ok {
+			m.message = fmt.Sprintf("actor %d is not on the board", m.selected)
+			break
+		}
+		order := Order{m.action, m.selected, key.arrow, "manual control", PriorityOpportunistic}
+		if err := DefaultClient.SubmitOrder(context.Background(), order); err != nil {
+			m.message = err.Error()
+		} else {
+			m.message = "submitted " + order.String()
+			m.submitted = append(m.submitted, order)
+		}
+		m.action = "move"
+	case key.char >= '0' && key.char <= '9':
+		m.selected = int(key.char - '0')
+		m.message = fmt.Sprintf("selected actor %d", m.selected)
+	case manual_hotkeys[key.char] != "":
+		m.action = manual_hotkeys[key.char]
+		m.message = m.action + ": press an arrow key for the direction"
+	case key.char == 'q':
+		return false
+	}
+	return true
+}
+
+func (m *ManualControl) draw() {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	remaining := time.Until(m.next).Seconds()
+	if remaining < 0 {
+		remaining = 0
+	}
+	fmt.Fprintf(&b, "%s  tick %d, next in %.1fs\r\n", Team, m.tick, remaining)
+	fmt.Fprintf(&b, "%s\r\n\r\n", render_scores(m.state))
+	b.WriteString(strings.ReplaceAll(render_board(m.state, m.size), "\n", "\r\n"))
+	fmt.Fprintf(&b, "\r\nactor %d, next order %s\r\n", m.selected, m.action)
+	for _, order := range m.submitted {
+		fmt.Fprintf(&b, "  this tick: %s\r\n", order)
+	}
+	fmt.Fprintf(&b, "%s\r\n\r\n", m.message)
+	b.WriteString("0-9 select actor, arrows act, m move, g grab/put, a attack, b build, d destroy, q quit\r\n")
+	os.Stdout.WriteString(b.String())
+}
+
+func (m *ManualControl) refresh() {
+	t, err := DefaultClient.Timing(context.Background())
+	if err != nil {
+		m.message = err.Error()
+		return
+	}
+	m.next = time.Now().Add(time.Duration(t.TimeToNextExecution * float64(time.Second)))
+	if t.Tick == m.tick && m.state.Teams != nil {
+		return
+	}
+	state, err := DefaultClient.GameState(context.Background())
+	if err != nil {
+		m.message = err.Error()
+		return
+	}
+	m.state = state
+	if t.Tick != m.tick {
+		m.submitted = nil
+	}
+	m.tick = t.Tick
+}
+
+func manual_command(args []string) {
+	flags := flag.NewFlagSet("manual", flag.ExitOnError)
+	log_file := flags.String("log", "manual.log", "file receiving the log output while the board is shown")
+	add_connection_flags(flags)
+	flags.Parse(args)
+	normalize_server_url()
+	if err := resolve_password(); err != nil {
+		log.Fatalln(err)
+	}
+
+	out, err := os.OpenFile(*log_file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer out.Close()
+	log.SetOutput(out)
+	defer log.SetOutput(os.Stderr)
+
+	restore, err := raw_terminal()
+	if err != nil {
+		log.SetOutput(os.Stderr)
+		log.Fatalln(err)
+	}
+	defer restore()
+
+	m := &ManualControl{action: "move"}
+	m.size = GameRules.MapSize()
+	keys := make(chan Key)
+	go read_keys(keys)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	m.refresh()
+	m.draw()
+	for {
+		select {
+		case key, ok := <-keys:
+			if !ok || !m.handle(key) {
+				return
+			}
+		case <-interrupt:
+			return
+		case <-ticker.C:
+			m.refresh()
+		}
+		m.draw()
+	}
+}
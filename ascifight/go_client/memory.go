@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// EnemyRecord is what we remember about one enemy actor.
+type EnemyRecord struct {
+	Team     string
+	Ident    int
+	Type     string
+	Position Coordinates
+	// Heading is the direction of its last move, empty before it moved or
+	// after it respawned.
+	Heading string
+	// Idle counts the ticks it stood still since its last move.
+	Idle     int
+	Flag     string
+	LastSeen int
+}
+
+func (r EnemyRecord) key() string {
+	return fmt.Sprintf("%s:%d", r.Team, r.Ident)
+}
+
+// EnemyMemory keeps the enemy records across ticks. It is fed by the tick
+// loop with every state, independent of when a strategy looks at it, and
+// keeps actors that are not in the latest state.
+type EnemyMemory struct {
+	mutex   sync.Mutex
+	tick    int
+	records map[string]*EnemyRecord
+}
+
+func new_enemy_memory() *EnemyMemory {
+	return &EnemyMemory{records: make(map[string]*EnemyRecord)}
+}
+
+var Enemies = new_enemy_memory()
+
+func (m *EnemyMemory) Observe(state GameState) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if state.Tick < m.tick {
+		m.records = make(map[string]*EnemyRecord)
+	}
+	m.tick = state.Tick
+	for _, enemy := range filter_objects(state.Actors, false) {
+		r := EnemyRecord{Team: enemy.Team, Ident: enemy.Ident, Type: enemy.Type, Position: enemy.Coordinates, Flag: enemy.Flag, LastSeen: state.Tick}
+		if old, ok := m.records[r.key()]; ok && old.LastSeen < state.Tick {
+			switch distance(old.Position, enemy.Coordinates) {
+			case 0:
+				r.Heading = old.Heading
+				r.Idle = old.Idle + 1
+			case 1:
+				r.Heading = find_path(old.Position, enemy.Coordinates)
+			}
+		} else if ok {
+			r.Heading, r.Idle = old.Heading, old.Idle
+		}
+		m.records[r.key()] = &r
+	}
+}
+
+func (m *EnemyMemory) Get(team string, ident int) (EnemyRecord, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	r, ok := m.records[fmt.Sprintf("%s:%d", team, ident)]
+	if !ok {
+		return EnemyRecord{}, false
+	}
+	return *r, true
+}
+
+// All returns every remembered enemy, ordered by team and ident.
+func (m *EnemyMemory) All() []EnemyRecord {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	records := make([]EnemyRecord, 0, len(m.records))
+	for _, r := range m.records {
+		records = append(records, *r)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Team != records[j].Team {
+			return records[i].Team < records[j].Team
+		}
+		return records[i].Ident < records[j].Ident
+	})
+	return records
+}
+
+// Predict extrapolates where the enemy will be in ticks ticks if it keeps
+// its heading. Enemies that stood still are expected to stay.
+func (r EnemyRecord) Predict(ticks int, size int) Coordinates {
+	position := r.Position
+	if r.Heading == "" || r.Idle > 0 {
+		return position
+	}
+	for i := 0; i < ticks; i++ {
+		next := predicted_position(position, r.Heading)
+		if next.X < 0 || next.Y < 0 || (size > 0 && (next.X >= size || next.Y >= size)) {
+			break
+		}
+		position = next
+	}
+	return position
+}
+
+// Approaching tells whether the enemy's last move brought it closer to c.
+func (r EnemyRecord) Approaching(c Coordinates) bool {
+	if r.Heading == "" || r.Idle > 0 {
+		return false
+	}
+	next := predicted_position(r.Position, r.Heading)
+	return distance(next, c) < distance(r.Position, c)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RoundTrip sends one request and returns the answer of the server.
+type RoundTrip func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the sending of every request of a client. It may change
+// the request, look at or replace the response, or not call next at all.
+// The request already carries its credentials and X-Request-ID.
+type Middleware func(next RoundTrip) RoundTrip
+
+// WithMiddleware adds middleware to the chain of the client. The first one
+// given sees a request first and its response last.
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(c *Client) { c.Use(middleware...) }
+}
+
+// Use adds middleware to the chain, like WithMiddleware. It is meant for
+// the setup of the DefaultClient and must not run while requests are sent.
+func (c *Client) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// round_trip is the middleware chain ending in the HTTP client.
+func (c *Client) round_trip() RoundTrip {
+	trip := c.http_client().Do
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		trip = c.middleware[i](trip)
+	}
+	return trip
+}
+
+// HeaderMiddleware sets a header on every request, e.g. the credentials of
+// a proxy in front of the server.
+func HeaderMiddleware(key string, value string) Middleware {
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set(key, value)
+			return next(req)
+		}
+	}
+}
+
+// LoggingMiddleware logs every request with its status and duration at the
+// given verbosity.
+func LoggingMiddleware(level Verbosity) Middleware {
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			status := "no answer"
+			if resp != nil {
+				status = resp.Status
+			}
+			logf(level, "%s %s: %s after %v (request %s)", req.Method, req.URL, status, time.Since(start).Round(time.Microsecond), req.Header.Get("X-Request-ID"))
+			return resp, err
+		}
+	}
+}
+
+// FaultMiddleware answers the given share of requests with a 503 instead
+// of sending them, and delays the others by up to delay, to see how the bot
+// copes with a struggling server.
+func FaultMiddleware(rate float64, delay time.Duration, seed int64) Middleware {
+	var mutex sync.Mutex
+	rng := rand.New(rand.NewSource(seed))
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			mutex.Lock()
+			fail := rng.Float64() < rate
+			wait := time.Duration(0)
+			if delay > 0 {
+				wait = time.Duration(rng.Int63n(int64(delay)))
+			}
+			mutex.Unlock()
+			if fail {
+				body := `{"detail":"injected fault"}`
+				return &http.Response{
+					Status:     "503 Service Unavailable",
+					StatusCode: http.StatusServiceUnavailable,
+					Header:     http.Header{"Content-Type": {"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Request:    req,
+				}, nil
+			}
+			select {
+			case <-time.After(wait):
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+			return next(req)
+		}
+	}
+}
+
+// RouteMetrics are the counts and latencies of the requests to one route.
+type RouteMetrics struct {
+	Requests int           `json:"requests"`
+	Failed   int           `json:"failed"`
+	Statuses map[int]int   `json:"statuses"`
+	Total    time.Duration `json:"total"`
+	Max      time.Duration `json:"max"`
+	Bytes    int64         `json:"bytes"`
+	route    string
+}
+
+// RequestMetrics counts the requests of a client per route.
+type RequestMetrics struct {
+	mutex  sync.Mutex
+	routes map[string]*RouteMetrics
+}
+
+func new_request_metrics() *RequestMetrics {
+	return &RequestMetrics{routes: make(map[string]*RouteMetrics)}
+}
+
+// metrics_route drops the actor from order paths, so that all orders of a
+// type are counted together.
+func metrics_route(req *http.Request) string {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) >= 3 && parts[len(parts)-3] == "orders" {
+		parts = parts[:len(parts)-1]
+	}
+	return req.Method + " /" + strings.Join(parts, "/")
+}
+
+func (m *RequestMetrics) Middleware() Middleware {
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			elapsed := time.Since(start)
+			route := metrics_route(req)
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+			r, ok := m.routes[route]
+			if !ok {
+				r = &RouteMetrics{Statuses: make(map[int]int), route: route}
+				m.routes[route] = r
+			}
+			r.Requests++
+			r.Total += elapsed
+			if elapsed > r.Max {
+				r.Max = elapsed
+			}
+			if err != nil {
+				r.Failed++
+			} else {
+				r.Statuses[resp.StatusCode]++
+				if resp.ContentLength > 0 {
+					r.Bytes += resp.ContentLength
+				}
+			}
+			return resp, err
+		}
+	}
+}
+
+// Report lists the routes with their request counts and latencies.
+func (m *RequestMetrics) Report() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	routes := make([]*RouteMetrics, 0, len(m.routes))
+	for _, r := range m.routes {
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].route < routes[j].route })
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%-32s %8s %6s %9s %9s  %s\n", "route", "requests", "failed", "mean ms", "max ms", "statuses")
+	for _, r := range routes {
+		statuses := make([]string, 0, len(r.Statuses))
+		for status, n := range r.Statuses {
+			statuses = append(statuses, fmt.Sprintf("%d:%d", status, n))
+		}
+		sort.Strings(statuses)
+		mean := float64(r.Total) / float64(r.Requests) / float64(time.Millisecond)
+		fmt.Fprintf(&b, "%-32s %8d %6d %9.2f %9.2f  %s\n", r.route, r.Requests, r.Failed, mean,
+			float64(r.Max)/float64(time.Millisecond), strings.Join(statuses, " "))
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// TickWork is how long the stages of the latest handled tick took and
+// whether they ran into its deadline.
+type TickWork struct {
+	Tick   int
+	Budget time.Duration
+	Fetch  time.Duration
+	Decide time.Duration
+	Submit time.Duration
+	// DecideLate and SubmitLate are set when the deadline passed during
+	// the stage.
+	DecideLate bool
+	SubmitLate bool
+	// FetchErrors are the failed fetches since the tick was handled.
+	FetchErrors int
+}
+
+// missed_cause names the likely reason the ticks after work were not
+// played.
+func missed_cause(work TickWork, stepping bool) string {
+	total := work.Fetch + work.Decide + work.Submit
+	switch {
+	case stepping:
+		return "paused for review"
+	case work.FetchErrors > 0:
+		return fmt.Sprintf("server unreachable, %d failed fetches", work.FetchErrors)
+	case work.DecideLate:
+		return fmt.Sprintf("long decision of %v", work.Decide.Round(time.Millisecond))
+	case work.SubmitLate:
+		return fmt.Sprintf("submission timeout after %v", work.Submit.Round(time.Millisecond))
+	case total > work.Budget && work.Fetch >= work.Decide && work.Fetch >= work.Submit:
+		return fmt.Sprintf("slow fetch of %v", work.Fetch.Round(time.Millisecond))
+	case total > work.Budget && work.Decide >= work.Submit:
+		return fmt.Sprintf("long decision of %v", work.Decide.Round(time.Millisecond))
+	case total > work.Budget:
+		return fmt.Sprintf("slow submission of %v", work.Submit.Round(time.Millisecond))
+	default:
+		return "late poll"
+	}
+}
+
+// RecordMissed counts ticks skipped after the tick of work.
+func (s *GameStats) RecordMissed(tick int, missed int, cause string) {
+	s.MissedTicks += missed
+	s.Events = append(s.Events, GameEvent{Tick: tick, Kind: "missed", Team: Team, Detail: fmt.Sprintf("%d ticks, %s", missed, cause)})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"sync"
+)
+
+// OpponentStyle is how the opponents were seen to play.
+type OpponentStyle string
+
+const (
+	StyleUnknown  OpponentStyle = "unknown"
+	StyleBalanced OpponentStyle = "balanced"
+	// StyleRush sends most actors across the board at once.
+	StyleRush OpponentStyle = "rush"
+	// StyleTurtle keeps most actors around the own base.
+	StyleTurtle OpponentStyle = "turtle"
+	// StyleCamp parks actors next to our base waiting for the flag.
+	StyleCamp OpponentStyle = "camp"
+)
+
+// opponent_sample are the shares of enemy actors in one tick that were on
+// our side of the board, around their own base and idling close to ours.
+type opponent_sample struct {
+	advanced float64
+	home     float64
+	camping  float64
+}
+
+// OpponentModel classifies the opponents over a sliding window of ticks, so
+// that a change of their play is noticed mid-game as well.
+type OpponentModel struct {
+	Window  int
+	mutex   sync.Mutex
+	tick    int
+	samples []opponent_sample
+	style   OpponentStyle
+}
+
+func new_opponent_model(window int) *OpponentModel {
+	return &OpponentModel{Window: window, style: StyleUnknown}
+}
+
+var Opponents = new_opponent_model(20)
+
+// opponent_radius is how close to a base an actor counts as around it.
+const opponent_radius = 3
+
+func sample_opponents(state GameState) (opponent_sample, bool) {
+	enemies := filter_objects(state.Actors, false)
+	if len(filter_objects(state.Bases, true)) == 0 || len(enemies) == 0 {
+		return opponent_sample{}, false
+	}
+	var s opponent_sample
+	for _, enemy := range enemies {
+		// measured to the closest base of either side
+		home, ok := closest_base(state.Bases, enemy.Team, enemy.Coordinates)
+		if !ok {
+			continue
+		}
+		ours, _ := closest_base(state.Bases, Team, enemy.Coordinates)
+		to_ours, to_home := distance(enemy.Coordinates, ours.Coordinates), distance(enemy.Coordinates, home.Coordinates)
+		if to_ours < to_home {
+			s.advanced++
+		}
+		if to_home <= opponent_radius {
+			s.home++
+		}
+		if record, ok := Enemies.Get(enemy.Team, enemy.Ident); ok && to_ours <= opponent_radius && record.Idle > 0 {
+			s.camping++
+		}
+	}
+	n := float64(len(enemies))
+	return opponent_sample{s.advanced / n, s.home / n, s.camping / n}, true
+}
+
+// Observe adds the state to the window and tells whether the style changed.
+// The opponents stay unknown until a full window was seen.
+func (m *OpponentModel) Observe(state GameState) (OpponentStyle, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	previous := m.style
+	if state.Tick < m.tick {
+		m.samples = nil
+		m.style = StyleUnknown
+	}
+	m.tick = state.Tick
+	if s, ok := sample_opponents(state); ok {
+		m.samples = append(m.samples, s)
+		if len(m.samples) > m.Window {
+			m.samples = m.samples[len(m.samples)-m.Window:]
+		}
+	}
+	if len(m.samples) >= m.Window && m.Window > 0 {
+		m.style = classify_opponents(m.samples)
+	}
+	return m.style, m.style != previous
+}
+
+func (m *OpponentModel) Style() OpponentStyle {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.style
+}
+
+func classify_opponents(samples []opponent_sample) OpponentStyle {
+	var total opponent_sample
+	for _, s := range samples {
+		total.advanced += s.advanced
+		total.home += s.home
+		total.camping += s.camping
+	}
+	n := float64(len(samples))
+	switch {
+	case total.camping/n >= 0.3:
+		return StyleCamp
+	case total.advanced/n >= 0.6:
+		return StyleRush
+	case total.home/n >= 0.6:
+		return StyleTurtle
+	default:
+		return StyleBalanced
+	}
+}
+
+// counter_params adapts the parameters the game was started with to the
+// style of the opponents.
+func counter_params(base StrategyParams, style OpponentStyle) StrategyParams {
+	p := base
+	switch style {
+	case StyleRush:
+		// keep actors home to meet the rush and walls to slow it down
+		p.DefenderShare = math.Max(p.DefenderShare, 0.34)
+		p.UtilityDefend *= 1.5
+		p.UtilityIntercept *= 1.5
+		p.UtilityBuild *= 1.5
+	case StyleTurtle:
+		// nobody is coming, so go for their flag with everything and break
+		// through the walls around it
+		p.DefenderShare = 0
+		p.UtilityDefend *= 0.5
+		p.UtilityBuild *= 0.5
+		p.UtilityDestroy *= 1.5
+		p.UtilityCamp *= 2
+	case StyleCamp:
+		// clear the campers before they get to our flag
+		p.DefenderShare = math.Max(p.DefenderShare, 0.25)
+		p.UtilityIntercept *= 2
+	}
+	return p
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallel_map calls f for every item on a pool of GOMAXPROCS workers and
+// returns the results in the order of the items. However the work was
+// spread, the caller merges them in the same order, so the outcome does not
+// depend on the scheduling. A panic in f is raised again in the caller,
+// where the recover boundary of decide catches it.
+func parallel_map[T any, R any](items []T, f func(item T) R) []R {
+	results := make([]R, len(items))
+	workers := min_int(runtime.GOMAXPROCS(0), len(items))
+	if workers <= 1 {
+		for i, item := range items {
+			results[i] = f(item)
+		}
+		return results
+	}
+	var wait sync.WaitGroup
+	var once sync.Once
+	var failure any
+	next := make(chan int)
+	for w := 0; w < workers; w++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					once.Do(func() { failure = r })
+					// drain the items so that the feeding loop ends
+					for range next {
+					}
+				}
+			}()
+			for i := range next {
+				results[i] = f(items[i])
+			}
+		}()
+	}
+	for i := range items {
+		next <- i
+	}
+	close(next)
+	wait.Wait()
+	if failure != nil {
+		panic(failure)
+	}
+	return results
+}
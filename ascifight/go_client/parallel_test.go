@@ -0,0 +1,62 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestParallelMap(t *testing.T) {
+	// more than one worker, also on a single core machine
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	tests := []struct {
+		name  string
+		items int
+		// panics lists the items f panics on
+		panics map[int]bool
+	}{
+		{name: "no items", items: 0},
+		{name: "single item runs inline", items: 1},
+		{name: "more items than workers", items: 50},
+		{name: "panic in the only item", items: 1, panics: map[int]bool{0: true}},
+		{name: "panic in the first item", items: 50, panics: map[int]bool{0: true}},
+		{name: "panic in the last item", items: 50, panics: map[int]bool{49: true}},
+		{name: "panics in several workers", items: 50, panics: map[int]bool{3: true, 17: true, 31: true, 45: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			items := make([]int, test.items)
+			for i := range items {
+				items[i] = i
+			}
+			var results []int
+			recovered := func() (r any) {
+				defer func() { r = recover() }()
+				results = parallel_map(items, func(item int) int {
+					if test.panics[item] {
+						panic(item)
+					}
+					return item * item
+				})
+				return nil
+			}()
+			if len(test.panics) > 0 {
+				item, ok := recovered.(int)
+				if !ok || !test.panics[item] {
+					t.Fatalf("recovered %v, want the panic of one of %v", recovered, test.panics)
+				}
+				return
+			}
+			if recovered != nil {
+				t.Fatalf("unexpected panic %v", recovered)
+			}
+			if len(results) != len(items) {
+				t.Fatalf("%d results for %d items", len(results), len(items))
+			}
+			for i, result := range results {
+				if result != i*i {
+					t.Errorf("result %d is %d, want %d", i, result, i*i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StrategyParams are the tunable heuristics of the built-in strategies.
+type StrategyParams struct {
+	FollowupAction float64 `json:"followup_action"`
+	DefenderShare  float64 `json:"defender_share"`
+	GuardRadius    float64 `json:"guard_radius"`
+	HeatAvoidance  float64 `json:"heat_avoidance"`
+	// DemolitionSaving is the least a shortcut has to save to be worth
+	// destroying walls for.
+	DemolitionSaving float64 `json:"demolition_saving"`
+	// Randomness is how much close choices of targets are shuffled, as a
+	// share of their score.
+	Randomness float64 `json:"randomness"`
+
+	UtilityGrab      float64 `json:"utility_grab"`
+	UtilityReturn    float64 `json:"utility_return"`
+	UtilityDefend    float64 `json:"utility_defend"`
+	UtilityIntercept float64 `json:"utility_intercept"`
+	UtilityCamp      float64 `json:"utility_camp"`
+	UtilityBuild     float64 `json:"utility_build"`
+	UtilityDestroy   float64 `json:"utility_destroy"`
+	// UtilityBait is what playing its part in a bait and switch is worth
+	// to an actor of the actors strategy.
+	UtilityBait      float64 `json:"utility_bait"`
+	UtilityProximity float64 `json:"utility_proximity"`
+	// UtilityRival is what going after the leading team rather than the
+	// weakest is worth with three or more teams.
+	UtilityRival float64 `json:"utility_rival"`
+	// UtilityAlliance is what sparing the teams that fight the leader and
+	// denying the captures of the leader are worth, 0 plays every team
+	// alike.
+	UtilityAlliance float64 `json:"utility_alliance"`
+	// Risk trades the length of paths and the odds of runs and fights
+	// against the danger on the way, from careful at 0 to reckless at 1.
+	Risk float64 `json:"risk"`
+}
+
+func default_params() StrategyParams {
+	return StrategyParams{
+		FollowupAction:   1,
+		DefenderShare:    0,
+		GuardRadius:      3,
+		HeatAvoidance:    4,
+		DemolitionSaving: 4,
+		Randomness:       0.1,
+
+		UtilityGrab:      1,
+		UtilityReturn:    2,
+		UtilityDefend:    0.6,
+		UtilityIntercept: 1.2,
+		UtilityCamp:      0.3,
+		UtilityBuild:     2,
+		UtilityDestroy:   2,
+		UtilityBait:      1.5,
+		UtilityRival:     1,
+		UtilityAlliance:  1,
+		UtilityProximity: 1,
+		Risk:             0.5,
+	}
+}
+
+var Params = default_params()
+
+// params_readers is read locked by whatever reads Params away from the
+// tick loop: the strategy run by decide, which may outlive its deadline,
+// and the actor controllers. Parameters adapted during a game wait in
+// next_params until none of them holds it.
+var params_readers sync.RWMutex
+
+var next_params struct {
+	mutex  sync.Mutex
+	params *StrategyParams
+}
+
+// set_params replaces Params once no strategy reads them any more.
+func set_params(p StrategyParams) {
+	next_params.mutex.Lock()
+	defer next_params.mutex.Unlock()
+	next_params.params = &p
+}
+
+// apply_params takes over the parameters of set_params, unless a strategy
+// or controller is still running, in which case a later call does.
+func apply_params() {
+	next_params.mutex.Lock()
+	defer next_params.mutex.Unlock()
+	if next_params.params == nil || !params_readers.TryLock() {
+		return
+	}
+	Params = *next_params.params
+	next_params.params = nil
+	params_readers.Unlock()
+}
+
+// ParamSpec describes one dimension of the parameter vector and the range
+// the tuner is allowed to search.
+type ParamSpec struct {
+	Name  string
+	Min   float64
+	Max   float64
+	Step  float64
+	field func(p *StrategyParams) *float64
+}
+
+var param_specs = []ParamSpec{
+	{"followup_action", 0, 1, 1, func(p *StrategyParams) *float64 { return &p.FollowupAction }},
+	{"defender_share", 0, 1, 0.25, func(p *StrategyParams) *float64 { return &p.DefenderShare }},
+	{"guard_radius", 1, 6, 1, func(p *StrategyParams) *float64 { return &p.GuardRadius }},
+	{"heat_avoidance", 0, 10, 1, func(p *StrategyParams) *float64 { return &p.HeatAvoidance }},
+	{"demolition_saving", 1, 10, 1, func(p *StrategyParams) *float64 { return &p.DemolitionSaving }},
+	{"randomness", 0, 0.5, 0.05, func(p *StrategyParams) *float64 { return &p.Randomness }},
+	{"utility_grab", 0, 3, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityGrab }},
+	{"utility_return", 0, 3, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityReturn }},
+	{"utility_defend", 0, 3, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityDefend }},
+	{"utility_intercept", 0, 3, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityIntercept }},
+	{"utility_camp", 0, 3, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityCamp }},
+	{"utility_build", 0, 5, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityBuild }},
+	{"utility_destroy", 0, 5, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityDestroy }},
+	{"utility_bait", 0, 3, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityBait }},
+	{"utility_rival", 0, 3, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityRival }},
+	{"utility_alliance", 0, 3, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityAlliance }},
+	{"utility_proximity", 0, 3, 0.25, func(p *StrategyParams) *float64 { return &p.UtilityProximity }},
+	{"risk", 0, 1, 0.25, func(p *StrategyParams) *float64 { return &p.Risk }},
+}
+
+func param_spec(name string) (ParamSpec, bool) {
+	for _, spec := range param_specs {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return ParamSpec{}, false
+}
+
+func (p StrategyParams) Vector() []float64 {
+	vector := make([]float64, len(param_specs))
+	for i, spec := range param_specs {
+		vector[i] = *spec.field(&p)
+	}
+	return vector
+}
+
+func params_from_vector(vector []float64) StrategyParams {
+	p := default_params()
+	for i, spec := range param_specs {
+		*spec.field(&p) = spec.Clamp(vector[i])
+	}
+	return p
+}
+
+// Clamp keeps a value within the range of the spec and on its step grid.
+func (s ParamSpec) Clamp(value float64) float64 {
+	if s.Step > 0 {
+		value = s.Min + math.Round((value-s.Min)/s.Step)*s.Step
+	}
+	return math.Max(s.Min, math.Min(s.Max, value))
+}
+
+// Values lists every grid point of the spec.
+func (s ParamSpec) Values() []float64 {
+	values := make([]float64, 0)
+	for v := s.Min; v <= s.Max+s.Step/2; v += s.Step {
+		values = append(values, s.Clamp(v))
+	}
+	return values
+}
+
+// String renders the params in the same key=value form parse_params reads.
+func (p StrategyParams) String() string {
+	parts := make([]string, 0, len(param_specs))
+	for _, spec := range param_specs {
+		parts = append(parts, spec.Name+"="+strconv.FormatFloat(*spec.field(&p), 'g', -1, 64))
+	}
+	return strings.Join(parts, ",")
+}
+
+// parse_params applies a comma separated list of name=value pairs on top of p.
+func parse_params(p StrategyParams, text string) (StrategyParams, error) {
+	for _, pair := range strings.Split(text, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return p, fmt.Errorf("parameter %q is not of the form name=value", pair)
+		}
+		spec, ok := param_spec(strings.TrimSpace(name))
+		if !ok {
+			return p, fmt.Errorf("unknown parameter %q, available: %s", name, strings.Join(param_names(), ", "))
+		}
+		value = strings.TrimSpace(value)
+		number, err := strconv.ParseFloat(value, 64)
+		if level, ok := risk_levels[value]; ok && spec.Name == "risk" {
+			number, err = level, nil
+		}
+		if err != nil {
+			return p, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		*spec.field(&p) = number
+	}
+	return p, nil
+}
+
+func param_names() []string {
+	names := make([]string, 0, len(param_specs))
+	for _, spec := range param_specs {
+		names = append(names, spec.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,124 @@
+package main
+
+import "sync"
+
+// path_cache_size bounds the number of paths and of flood fills kept. The
+// wall planners flood the board once per candidate, so there are many per
+// tick.
+const path_cache_size = 4096
+
+// cell_hash mixes a cell into 64 bits, see splitmix64.
+func cell_hash(c Coordinates) uint64 {
+	h := uint64(c.X)<<32 ^ uint64(uint32(c.Y)) + 0x9e3779b97f4a7c15
+	h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+	h = (h ^ h>>27) * 0x94d049bb133111eb
+	return h ^ h>>31
+}
+
+// cells_hash identifies a set of cells independent of its order.
+func cells_hash(cells map[Coordinates]bool) uint64 {
+	h := uint64(len(cells))
+	for c, ok := range cells {
+		if ok {
+			h ^= cell_hash(c)
+		}
+	}
+	return h
+}
+
+// costs_hash identifies a set of step costs independent of its order.
+func costs_hash(costs map[Coordinates]int) uint64 {
+	h := uint64(len(costs))
+	for c, cost := range costs {
+		h ^= cell_hash(c) * uint64(2*cost+1)
+	}
+	return h
+}
+
+type path_key struct {
+	start  Coordinates
+	target Coordinates
+	size   int
+	// heading is the last move of the actor, it breaks ties between paths
+	heading string
+	// costs identifies the step costs the path was searched with
+	costs uint64
+}
+
+type flood_key struct {
+	size    int
+	base    Coordinates
+	blocked uint64
+	cells   int
+}
+
+// PathCache keeps the paths searched by the planner and the flood fills of
+// the wall planners across ticks. Paths are kept per wall set and dropped
+// all at once when a wall was built or destroyed. Until then a path is
+// reused as long as no actor or base stands on it, so that every actor
+// heading for the same place from the same cell needs a single search.
+// A flood fill is keyed by everything that blocks it and can only go stale
+// by being pushed out.
+type PathCache struct {
+	mutex  sync.Mutex
+	walls  uint64
+	paths  map[path_key][]string
+	floods map[flood_key][][]int
+}
+
+func new_path_cache() *PathCache {
+	return &PathCache{paths: make(map[path_key][]string), floods: make(map[flood_key][][]int)}
+}
+
+// PathMemo is shared by the planner and the wall planners.
+var PathMemo = new_path_cache()
+
+// Path returns a path searched before from start to next to target on the
+// same walls, if none of its cells is blocked now.
+func (c *PathCache) Path(key path_key, walls uint64, obstacles map[Coordinates]bool) ([]string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if walls != c.walls {
+		c.paths, c.walls = make(map[path_key][]string), walls
+	}
+	steps, ok := c.paths[key]
+	if !ok {
+		return nil, false
+	}
+	at := key.start
+	for _, direction := range steps {
+		if at = predicted_position(at, direction); obstacles[at] {
+			return nil, false
+		}
+	}
+	return steps, true
+}
+
+func (c *PathCache) StorePath(key path_key, walls uint64, steps []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if walls != c.walls || len(c.paths) >= path_cache_size {
+		c.paths, c.walls = make(map[path_key][]string), walls
+	}
+	c.paths[key] = steps
+}
+
+// base_distances is the flood fill of base_distances, computed once for
+// every blocked set. The grid is shared and must not be changed.
+func (c *PathCache) base_distances(size int, base Coordinates, blocked map[Coordinates]bool) [][]int {
+	key := flood_key{size: size, base: base, blocked: cells_hash(blocked), cells: len(blocked)}
+	c.mutex.Lock()
+	distance, ok := c.floods[key]
+	c.mutex.Unlock()
+	if ok {
+		return distance
+	}
+	distance = base_distances(size, base, blocked)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.floods) >= path_cache_size {
+		c.floods = make(map[flood_key][][]int)
+	}
+	c.floods[key] = distance
+	return distance
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"math"
+	"sync"
+)
+
+// GamePhase is the stage of the game the roles of our actors are fit to.
+type GamePhase string
+
+const (
+	PhaseOpening   GamePhase = "opening"
+	PhaseMidgame   GamePhase = "midgame"
+	PhaseEndgame   GamePhase = "endgame"
+	PhaseWeCarry   GamePhase = "we-carry"
+	PhaseTheyCarry GamePhase = "they-carry"
+)
+
+// opening_contact is how close our actors and the enemy's have to come to
+// end the opening.
+const opening_contact = 3
+
+// PhaseMachine follows the phase of the game from tick to tick. The opening
+// lasts until the actors first meet or a board crossing worth of ticks
+// passed and does not come back. A flag carried by either side takes
+// precedence over the other phases for as long as it is carried, our own
+// flag first, and the endgame precedes the midgame once the runs stop
+// fitting into the remaining ticks.
+type PhaseMachine struct {
+	mutex   sync.Mutex
+	tick    int
+	phase   GamePhase
+	opening bool
+}
+
+func new_phase_machine() *PhaseMachine {
+	return &PhaseMachine{phase: PhaseOpening, opening: true}
+}
+
+// Phases is the phase of the game being played.
+var Phases = new_phase_machine()
+
+// Observe moves the machine on to state and returns the phase and whether
+// it changed.
+func (m *PhaseMachine) Observe(state GameState) (GamePhase, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if state.Tick < m.tick {
+		m.phase, m.opening = PhaseOpening, true
+	}
+	m.tick = state.Tick
+	ours := filter_objects(state.Actors, true)
+	enemies := filter_objects(state.Actors, false)
+	if m.opening {
+		if state.Tick > board_size(state, GameRules.MapSize()) {
+			m.opening = false
+		}
+		for _, actor := range ours {
+			for _, enemy := range enemies {
+				if distance(actor.Coordinates, enemy.Coordinates) <= opening_contact {
+					m.opening = false
+				}
+			}
+		}
+	}
+	phase := PhaseMidgame
+	switch {
+	case len(our_flag_carriers(state)) > 0:
+		phase = PhaseTheyCarry
+	case carrying(ours):
+		phase = PhaseWeCarry
+	case current_endgame(state).Closing:
+		phase = PhaseEndgame
+	case m.opening:
+		phase = PhaseOpening
+	}
+	previous := m.phase
+	m.phase = phase
+	return phase, phase != previous
+}
+
+func (m *PhaseMachine) Phase() GamePhase {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.phase
+}
+
+func carrying(actors []Actor) bool {
+	for _, actor := range actors {
+		if actor.Flag != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// phase_params shifts the roles of our actors to the phase: the opening
+// sends runners for the flags while the base is still safe, a flag of ours
+// on its way home keeps more of them back to guard it and meet the chasers,
+// and our flag in enemy hands turns everyone able to on the carrier. In the
+// endgame walls no longer pay off, the rest is up to the endgame rules.
+func phase_params(base StrategyParams, phase GamePhase) StrategyParams {
+	p := base
+	switch phase {
+	case PhaseOpening:
+		p.DefenderShare *= 0.5
+		p.UtilityGrab *= 1.5
+		p.UtilityDefend *= 0.5
+	case PhaseWeCarry:
+		p.DefenderShare = math.Max(p.DefenderShare, 0.25)
+		p.UtilityDefend *= 1.25
+		p.UtilityIntercept *= 1.5
+	case PhaseTheyCarry:
+		p.UtilityIntercept *= 2
+		p.UtilityBuild *= 0.5
+	case PhaseEndgame:
+		p.UtilityBuild *= 0.5
+	}
+	return p
+}
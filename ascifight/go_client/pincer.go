@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// pincer_patience is how many ticks the attackers in reach hold for the
+// others to take their posts before they strike without them.
+const pincer_patience = 4
+
+type pincer_key struct {
+	team  string
+	ident int
+}
+
+// PincerBoard times the attacks of several of our actors on the same
+// enemy. The strategy reports the enemies it chose to hunt down while it
+// runs, and Synchronize then rewrites the orders of each group: every
+// attacker is given a post two cells from the enemy on a side of its own,
+// and the ones two cells away hold until all are posted, so that they
+// strike together from different directions instead of one after the
+// other. Attackers already next to the enemy keep their attack.
+type PincerBoard struct {
+	mutex   sync.Mutex
+	tick    int
+	bases   []Base
+	targets map[pincer_key]Actor
+	groups  map[pincer_key][]Actor
+	// since is the tick each group started holding at.
+	since map[pincer_key]int
+}
+
+func new_pincer_board() *PincerBoard {
+	return &PincerBoard{targets: make(map[pincer_key]Actor), groups: make(map[pincer_key][]Actor), since: make(map[pincer_key]int)}
+}
+
+// Pincers are observed and applied around the built-in strategies by
+// CoordinatedStrategy.
+var Pincers = new_pincer_board()
+
+// Observe starts the attacks of a new tick.
+func (b *PincerBoard) Observe(state GameState) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if state.Tick < b.tick {
+		b.since = make(map[pincer_key]int)
+	}
+	b.tick = state.Tick
+	b.bases = filter_objects(state.Bases, true)
+	b.targets = make(map[pincer_key]Actor)
+	b.groups = make(map[pincer_key][]Actor)
+}
+
+// Attack reports that actor hunts enemy down this tick. Flag carriers and
+// intruders within the guard radius of one of our bases are fought off at
+// once and never waited for.
+func (b *PincerBoard) Attack(actor Actor, enemy Actor) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if enemy.Flag != "" {
+		return
+	}
+	for _, base := range b.bases {
+		if float64(distance(enemy.Coordinates, base.Coordinates)) <= Params.GuardRadius {
+			return
+		}
+	}
+	key := pincer_key{enemy.Team, enemy.Ident}
+	for _, other := range b.groups[key] {
+		if other.Ident == actor.Ident {
+			return
+		}
+	}
+	b.targets[key] = enemy
+	b.groups[key] = append(b.groups[key], actor)
+}
+
+// Synchronize rewrites the orders of the attackers that share a target.
+func (b *PincerBoard) Synchronize(state GameState, orders []Order) []Order {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if state.Tick != b.tick {
+		return orders
+	}
+	keys := make([]pincer_key, 0, len(b.groups))
+	for key, group := range b.groups {
+		if len(group) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].team < keys[j].team || (keys[i].team == keys[j].team && keys[i].ident < keys[j].ident)
+	})
+	for key := range b.since {
+		if len(b.groups[key]) < 2 {
+			delete(b.since, key)
+		}
+	}
+	walls := make(map[Coordinates]bool, len(state.Walls))
+	for _, wall := range state.Walls {
+		walls[wall] = true
+	}
+	size := board_size(state, GameRules.MapSize())
+	for _, key := range keys {
+		orders = b.synchronize(key, state.Tick, size, walls, orders)
+	}
+	return orders
+}
+
+func (b *PincerBoard) synchronize(key pincer_key, tick int, size int, walls map[Coordinates]bool, orders []Order) []Order {
+	enemy := b.targets[key]
+	group := b.groups[key]
+	posts := pincer_posts(enemy.Coordinates, group, size, walls)
+	posted := true
+	for _, actor := range group {
+		if distance(actor.Coordinates, enemy.Coordinates) > 2 {
+			posted = false
+		}
+	}
+	if posted {
+		if since, ok := b.since[key]; ok {
+			logf(VerbosityDebug, "%d actors strike %s actor %d together after holding %d ticks", len(group), key.team, key.ident, tick-since)
+		}
+		delete(b.since, key)
+		return orders
+	}
+	since, ok := b.since[key]
+	if !ok {
+		since = tick
+		b.since[key] = tick
+	}
+	if tick-since >= pincer_patience {
+		// the posts cannot all be taken, the ones in reach go in alone
+		return orders
+	}
+	kept := make([]Order, 0, len(orders))
+	replaced := make(map[int]bool, len(group))
+	for _, actor := range group {
+		if distance(actor.Coordinates, enemy.Coordinates) > 1 {
+			replaced[actor.Ident] = true
+		}
+	}
+	for _, order := range orders {
+		if !replaced[order.actor] {
+			kept = append(kept, order)
+		}
+	}
+	for _, actor := range group {
+		d := distance(actor.Coordinates, enemy.Coordinates)
+		post, ok := posts[actor.Ident]
+		switch {
+		case d <= 1:
+			// next to the enemy already, its own attack order stands
+		case d == 2 || !ok:
+			// holds out of reach for the others
+		default:
+			reason := fmt.Sprintf("pincer on %s actor %d, taking post at %v", key.team, key.ident, post)
+			direction := Plans.Direction(actor, post)
+			if distance(actor.Coordinates, post) <= 1 {
+				direction = find_path(actor.Coordinates, post)
+			}
+			kept = append(kept, Order{"move", actor.Ident, direction, reason, order_priority(actor, "move")})
+		}
+	}
+	return kept
+}
+
+// pincer_posts gives every attacker of enemy a cell two steps from it, on
+// the side it comes from while that side is free and else on the free side
+// closest to it. The attackers closer to the enemy choose first.
+func pincer_posts(enemy Coordinates, group []Actor, size int, walls map[Coordinates]bool) map[int]Coordinates {
+	ordered := append([]Actor(nil), group...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return distance(ordered[i].Coordinates, enemy) < distance(ordered[j].Coordinates, enemy)
+	})
+	taken := make(map[string]bool)
+	posts := make(map[int]Coordinates, len(group))
+	for _, actor := range ordered {
+		best, side := Coordinates{}, ""
+		natural := find_path(enemy, actor.Coordinates)
+		for _, direction := range plan_directions {
+			post := predicted_position(predicted_position(enemy, direction), direction)
+			if taken[direction] || walls[post] || post.X < 0 || post.Y < 0 || post.X >= size || post.Y >= size {
+				continue
+			}
+			if direction == natural {
+				best, side = post, direction
+				break
+			}
+			if side == "" || distance(actor.Coordinates, post) < distance(actor.Coordinates, best) {
+				best, side = post, direction
+			}
+		}
+		if side != "" {
+			taken[side] = true
+			posts[actor.Ident] = best
+		}
+	}
+	return posts
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func our_actor(ident int, x int, y int) Actor {
+	return Actor{Type: "Attacker", Ident: ident, OwnedObjectImpl: OwnedObjectImpl{Team: Team, Coordinates: Coordinates{x, y}}}
+}
+
+func TestPincerPosts(t *testing.T) {
+	enemy := Coordinates{5, 5}
+	tests := []struct {
+		name  string
+		enemy Coordinates
+		group []Actor
+		walls []Coordinates
+		posts map[int]Coordinates
+	}{
+		{
+			name:  "post on the side the attacker comes from",
+			enemy: enemy,
+			group: []Actor{our_actor(0, 1, 5)},
+			posts: map[int]Coordinates{0: {3, 5}},
+		},
+		{
+			name:  "closer attacker chooses first, the other takes the closest free side",
+			enemy: enemy,
+			group: []Actor{our_actor(1, 1, 4), our_actor(0, 2, 5)},
+			posts: map[int]Coordinates{0: {3, 5}, 1: {5, 3}},
+		},
+		{
+			name:  "walled post moves to the closest free side",
+			enemy: enemy,
+			group: []Actor{our_actor(0, 1, 6)},
+			walls: []Coordinates{{3, 5}},
+			posts: map[int]Coordinates{0: {5, 7}},
+		},
+		{
+			name:  "posts off the board are skipped",
+			enemy: Coordinates{1, 5},
+			group: []Actor{our_actor(0, 0, 5)},
+			walls: []Coordinates{{3, 5}},
+			posts: map[int]Coordinates{0: {1, 3}},
+		},
+		{
+			name:  "no more attackers than sides",
+			enemy: enemy,
+			group: []Actor{our_actor(0, 3, 5), our_actor(1, 5, 8), our_actor(2, 9, 5), our_actor(3, 5, 1), our_actor(4, 0, 0)},
+			posts: map[int]Coordinates{0: {3, 5}, 1: {5, 7}, 2: {7, 5}, 3: {5, 3}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			walls := make(map[Coordinates]bool, len(test.walls))
+			for _, wall := range test.walls {
+				walls[wall] = true
+			}
+			posts := pincer_posts(test.enemy, test.group, 10, walls)
+			if !reflect.DeepEqual(posts, test.posts) {
+				t.Errorf("posts %v, want %v", posts, test.posts)
+			}
+		})
+	}
+}
+
+func TestPincerSynchronize(t *testing.T) {
+	GameRules.Set(Rules{MapSize: 10})
+	defer GameRules.Invalidate()
+	fighting := Actor{Type: "Attacker", Ident: 0, OwnedObjectImpl: OwnedObjectImpl{Team: "Team 2", Coordinates: Coordinates{5, 5}}}
+	carrier := fighting
+	carrier.Flag = Team
+	intruder := fighting
+	intruder.Coordinates = Coordinates{2, 1}
+	base := Base{OwnedObjectImpl{Team: Team, Coordinates: Coordinates{0, 0}}}
+	adjacent, holding, far := our_actor(0, 4, 5), our_actor(1, 7, 5), our_actor(2, 5, 8)
+	attack := Order{"attack", adjacent.Ident, "right", "", PriorityOpportunistic}
+	tests := []struct {
+		name   string
+		enemy  Actor
+		orders []Order
+	}{
+		{
+			name:  "adjacent attacker strikes, the one in reach holds and the far one takes its post",
+			enemy: fighting,
+			orders: []Order{
+				attack,
+				{"move", far.Ident, "down", "pincer on Team 2 actor 0, taking post at {5 7}", order_priority(far, "move")},
+			},
+		},
+		{
+			name:  "a flag carrier is attacked at once",
+			enemy: carrier,
+		},
+		{
+			name:  "an intruder near our base is attacked at once",
+			enemy: intruder,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := GameState{Teams: []string{Team, "Team 2"}, Tick: 3, Actors: []Actor{adjacent, holding, far, test.enemy}, Bases: []Base{base}}
+			orders := []Order{
+				attack,
+				{"move", holding.Ident, "left", "", PriorityOpportunistic},
+				{"move", far.Ident, "down", "", PriorityOpportunistic},
+			}
+			board := new_pincer_board()
+			board.Observe(state)
+			for _, actor := range []Actor{adjacent, holding, far} {
+				board.Attack(actor, test.enemy)
+			}
+			want := test.orders
+			if want == nil {
+				want = orders
+			}
+			if got := board.Synchronize(state, orders); !reflect.DeepEqual(got, want) {
+				t.Errorf("orders %v, want %v", got, want)
+			}
+		})
+	}
+}
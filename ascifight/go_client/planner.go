@@ -0,0 +1,200 @@
+package main
+
+import "sync"
+
+var plan_directions = []string{"left", "right", "down", "up"}
+
+// PlanQueue is the remaining path of one actor towards its target.
+type PlanQueue struct {
+	Target Coordinates
+	// Position is where the actor stands when the next step is due.
+	Position Coordinates
+	// Heading is the last move taken from the queue.
+	Heading string
+	Steps   []string
+}
+
+// Planner keeps a queue of future moves per actor. A path is searched once
+// and then followed; it is planned again only when the target changed, the
+// last move did not go through or the next steps hit a new obstacle.
+type Planner struct {
+	mutex     sync.Mutex
+	tick      int
+	size      int
+	obstacles map[Coordinates]bool
+	walls     map[Coordinates]bool
+	// fog are the extra steps charged for cells never seen.
+	fog map[Coordinates]int
+	// threats are the cells next to an enemy attacker.
+	threats map[Coordinates]bool
+	// targets are where the actors were sent this tick.
+	targets  map[int]Coordinates
+	queues   map[int]*PlanQueue
+	searches map[int]*DStarLite
+}
+
+func new_planner() *Planner {
+	return &Planner{queues: make(map[int]*PlanQueue), searches: make(map[int]*DStarLite)}
+}
+
+// Plans are the move queues of the built-in strategies.
+var Plans = new_planner()
+
+// Observe hands the planner the state of the current tick. Strategies call
+// it before asking for directions.
+func (p *Planner) Observe(state GameState) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if state.Tick < p.tick {
+		// a new game started
+		p.queues = make(map[int]*PlanQueue)
+		p.searches = make(map[int]*DStarLite)
+	}
+	p.tick = state.Tick
+	p.targets = make(map[int]Coordinates)
+	EnemyHeat.Observe(state)
+	p.size = board_size(state, GameRules.MapSize())
+	p.obstacles = make(map[Coordinates]bool)
+	p.walls = make(map[Coordinates]bool)
+	for _, wall := range state.Walls {
+		p.obstacles[wall] = true
+		p.walls[wall] = true
+	}
+	for _, base := range state.Bases {
+		p.obstacles[base.Coordinates] = true
+	}
+	for _, actor := range state.Actors {
+		p.obstacles[actor.Coordinates] = true
+	}
+	p.threats = threat_cells(state)
+	p.fog = make(map[Coordinates]int)
+	World.Remembered(p.obstacles, p.fog)
+	for c := range p.obstacles {
+		if k, ok := World.Cell(c); ok && k.Wall {
+			p.walls[c] = true
+		}
+	}
+}
+
+// Targets returns where Direction sent every actor this tick.
+func (p *Planner) Targets() map[int]Coordinates {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	targets := make(map[int]Coordinates, len(p.targets))
+	for ident, target := range p.targets {
+		targets[ident] = target
+	}
+	return targets
+}
+
+// Queued returns the moves every actor has queued after the one of this
+// tick.
+func (p *Planner) Queued() map[int][]string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	queued := make(map[int][]string, len(p.queues))
+	for ident, q := range p.queues {
+		queued[ident] = append([]string(nil), q.Steps...)
+	}
+	return queued
+}
+
+func (p *Planner) inside(c Coordinates) bool {
+	return c.X >= 0 && c.Y >= 0 && c.X < p.size && c.Y < p.size
+}
+
+// search returns the path of actor next to target. A path searched before
+// from the same cell is taken from PathMemo while it is free. Otherwise the
+// D* Lite search of the actor is kept while its target stays the same and
+// only repaired for what changed on the board since.
+func (p *Planner) search(actor Actor, target Coordinates, heading string) ([]string, bool) {
+	if distance(actor.Coordinates, target) <= 1 {
+		return []string{}, true
+	}
+	// flag runs avoid the corridors the enemy frequents
+	costs := make(map[Coordinates]int, len(p.fog))
+	for c, cost := range p.fog {
+		costs[c] = cost
+	}
+	// cells our moves were recently blocked on cost extra for everyone
+	for c, cost := range Outcomes.Costs() {
+		costs[c] += cost
+	}
+	if actor.Flag != "" {
+		for c, cost := range EnemyHeat.Costs(Params.HeatAvoidance * caution()) {
+			costs[c] += cost
+		}
+	}
+	for c, cost := range threat_costs(p.threats) {
+		costs[c] += cost
+	}
+	key := path_key{start: actor.Coordinates, target: target, size: p.size, heading: heading, costs: costs_hash(costs)}
+	walls := cells_hash(p.walls)
+	if steps, ok := PathMemo.Path(key, walls, p.obstacles); ok {
+		return steps, true
+	}
+	d, ok := p.searches[actor.Ident]
+	if !ok || d.target != target || d.size != p.size {
+		d = new_dstar_lite(p.size, actor.Coordinates, target, p.obstacles, costs)
+		p.searches[actor.Ident] = d
+	} else {
+		d.Update(actor.Coordinates, p.obstacles, costs)
+	}
+	steps, found := d.Path(heading)
+	if found {
+		PathMemo.StorePath(key, walls, steps)
+	}
+	return steps, found
+}
+
+// valid re-checks a queue against the current tick: the actor has to stand
+// where the queue expects it, the next cell must be free and no wall may
+// have been built on the rest of the path.
+func (p *Planner) valid(q *PlanQueue, actor Actor, target Coordinates) bool {
+	if q.Target != target || q.Position != actor.Coordinates || len(q.Steps) == 0 {
+		return false
+	}
+	at := actor.Coordinates
+	for i, direction := range q.Steps {
+		at = predicted_position(at, direction)
+		if p.walls[at] || (i == 0 && p.obstacles[at]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Direction returns the next move of actor on its way next to target. When
+// no path exists it falls back to the straight direction. Paths searched
+// anew prefer to keep the heading of the last move that went through, so an
+// actor does not swerve between equally short routes from tick to tick.
+func (p *Planner) Direction(actor Actor, target Coordinates) string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.obstacles == nil {
+		return find_path(actor.Coordinates, target)
+	}
+	p.targets[actor.Ident] = target
+	q, ok := p.queues[actor.Ident]
+	heading := ""
+	if ok && q.Position == actor.Coordinates {
+		heading = q.Heading
+	}
+	if !ok || !p.valid(q, actor, target) {
+		steps, found := p.search(actor, target, heading)
+		if !found || len(steps) == 0 {
+			direction := find_path_heading(actor.Coordinates, target, heading)
+			// the heading is kept for the straight moves as well
+			p.queues[actor.Ident] = &PlanQueue{Target: target, Heading: direction,
+				Position: predicted_position(actor.Coordinates, direction)}
+			return direction
+		}
+		q = &PlanQueue{Target: target, Steps: steps}
+		p.queues[actor.Ident] = q
+	}
+	direction := q.Steps[0]
+	q.Steps = q.Steps[1:]
+	q.Heading = direction
+	q.Position = predicted_position(actor.Coordinates, direction)
+	return direction
+}
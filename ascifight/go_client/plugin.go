@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"plugin"
+)
+
+// PluginStrategy is what a strategy plugin has to export under the symbol
+// name "Strategy". Input and orders cross the plugin boundary as JSON so
+// plugins do not depend on the types of this package.
+type PluginStrategy interface {
+	Name() string
+	GenerateOrders(state []byte) ([]byte, error)
+}
+
+// PluginInput is the JSON document handed to a plugin every tick.
+type PluginInput struct {
+	Team  string    `json:"team"`
+	State GameState `json:"state"`
+}
+
+// PluginOrder is the JSON form of an order returned by a plugin.
+type PluginOrder struct {
+	Type      string `json:"type"`
+	Actor     int    `json:"actor"`
+	Direction string `json:"direction"`
+	Reason    string `json:"reason,omitempty"`
+	// Priority orders the submission, higher first.
+	Priority int `json:"priority,omitempty"`
+}
+
+type LoadedPlugin struct {
+	path     string
+	strategy PluginStrategy
+}
+
+func load_strategy_plugin(path string) (*LoadedPlugin, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := p.Lookup("Strategy")
+	if err != nil {
+		return nil, err
+	}
+	strategy, ok := symbol.(PluginStrategy)
+	if !ok {
+		return nil, fmt.Errorf("%s: symbol Strategy of type %T does not implement PluginStrategy", path, symbol)
+	}
+	return &LoadedPlugin{path: path, strategy: strategy}, nil
+}
+
+func (p *LoadedPlugin) Name() string {
+	return "plugin:" + p.strategy.Name()
+}
+
+func (p *LoadedPlugin) GenerateOrders(state GameState) []Order {
+	data, err := json.Marshal(PluginInput{Team: Team, State: state})
+	if err != nil {
+		log.Printf("encoding state for plugin failed: %v", err)
+		return nil
+	}
+	result, err := p.strategy.GenerateOrders(data)
+	if err != nil {
+		log.Printf("strategy plugin %s failed: %v", p.path, err)
+		return nil
+	}
+	var decoded []PluginOrder
+	if err := json.Unmarshal(result, &decoded); err != nil {
+		log.Printf("strategy plugin %s returned invalid orders: %v", p.path, err)
+		return nil
+	}
+	return plugin_orders(decoded)
+}
+
+func plugin_orders(decoded []PluginOrder) []Order {
+	orders := make([]Order, 0, len(decoded))
+	for _, o := range decoded {
+		orders = append(orders, Order{o.Type, o.Actor, o.Direction, o.Reason, o.Priority})
+	}
+	return orders
+}
@@ -0,0 +1,67 @@
+// Example strategy plugin. Build it with
+//
+//	go build -buildmode=plugin -o example.so ./plugins/example
+//
+// and run the client with -strategy-plugin=example.so.
+package main
+
+import "encoding/json"
+
+type coordinates struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type input struct {
+	Team  string `json:"team"`
+	State struct {
+		Actors []struct {
+			Team        string      `json:"team"`
+			Ident       int         `json:"ident"`
+			Coordinates coordinates `json:"coordinates"`
+		} `json:"actors"`
+	} `json:"state"`
+}
+
+type order struct {
+	Type      string `json:"type"`
+	Actor     int    `json:"actor"`
+	Direction string `json:"direction"`
+}
+
+type centrist struct{}
+
+func (centrist) Name() string {
+	return "centrist"
+}
+
+// GenerateOrders walks all our actors to the centre of a 15x15 board.
+func (centrist) GenerateOrders(data []byte) ([]byte, error) {
+	var in input
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, err
+	}
+	centre := coordinates{7, 7}
+	orders := make([]order, 0)
+	for _, actor := range in.State.Actors {
+		if actor.Team != in.Team {
+			continue
+		}
+		c := actor.Coordinates
+		switch {
+		case c.X < centre.X:
+			orders = append(orders, order{"move", actor.Ident, "right"})
+		case c.X > centre.X:
+			orders = append(orders, order{"move", actor.Ident, "left"})
+		case c.Y < centre.Y:
+			orders = append(orders, order{"move", actor.Ident, "up"})
+		case c.Y > centre.Y:
+			orders = append(orders, order{"move", actor.Ident, "down"})
+		}
+	}
+	return json.Marshal(orders)
+}
+
+var Strategy centrist
+
+func main() {}
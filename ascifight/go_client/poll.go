@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// Poller decides how long the tick loop waits before asking for the timing
+// again. It sleeps until just after the announced execution and then
+// re-polls in short, slowly growing steps until the new tick shows up, so
+// the state is fetched as soon as possible after the server resolved a tick.
+type Poller struct {
+	Offset  time.Duration
+	Initial time.Duration
+	Max     time.Duration
+	step    time.Duration
+	// due is set while the next timing request should see a new tick.
+	due bool
+}
+
+func new_poller(offset time.Duration) *Poller {
+	return &Poller{Offset: offset, Initial: 5 * time.Millisecond, Max: 100 * time.Millisecond, due: true}
+}
+
+// Wait returns the delay before the next timing request while the tick has
+// not changed.
+func (p *Poller) Wait(t Timing) time.Duration {
+	remaining := time.Duration(t.TimeToNextExecution * float64(time.Second))
+	p.due = true
+	if remaining > 0 {
+		p.step = 0
+		return remaining + p.Offset
+	}
+	// the execution is overdue, so the server is resolving the tick right now
+	if p.step == 0 {
+		p.step = p.Initial
+	} else if p.step *= 2; p.step > p.Max {
+		p.step = p.Max
+	}
+	return p.step
+}
+
+// Ticked resets the re-poll steps once the new tick was seen.
+func (p *Poller) Ticked() {
+	p.step = 0
+	p.due = false
+}
+
+// Due tells whether the next timing request is expected to show a new tick,
+// which is when fetching the state along with it pays off. Right after a
+// tick was handled the timing only tells how long to sleep.
+func (p *Poller) Due() bool {
+	return p.due
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+)
+
+// start_pprof serves the runtime profiles under /debug/pprof/ on their own
+// listener, so they are never exposed on the dashboard or health ports.
+func start_pprof(address string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	// no cmdline handler, the command line carries the -password
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	go func() {
+		logf(VerbosityNormal, "pprof listening on %s", address)
+		if err := http.ListenAndServe(address, mux); err != nil {
+			log.Printf("pprof stopped: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// SeededRand is the source of all deliberate randomness of the bot, so that
+// a game can be replayed with the same seed. The sequence restarts every
+// tick from the seed and the tick number, so the orders for a state only
+// depend on the seed and not on what earlier ticks drew. It is shared by
+// the actor controllers and therefore locked.
+type SeededRand struct {
+	mutex sync.Mutex
+	seed  int64
+	tick  int
+	rng   *rand.Rand
+}
+
+func new_seeded_rand(seed int64) *SeededRand {
+	return &SeededRand{seed: seed, rng: rand.New(rand.NewSource(seed))}
+}
+
+var Random = new_seeded_rand(time.Now().UnixNano())
+
+// Seed restarts the sequence, 0 picks a seed from the clock.
+func (r *SeededRand) Seed(seed int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.seed = seed
+	r.tick = 0
+	r.rng = rand.New(rand.NewSource(seed))
+}
+
+// mix_seed derives a well spread seed from seed and keys with the
+// finalizer of splitmix64.
+func mix_seed(seed int64, keys ...int) int64 {
+	z := uint64(seed)
+	for _, key := range keys {
+		z += 0x9e3779b97f4a7c15 + uint64(key)
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		z ^= z >> 31
+	}
+	return int64(z)
+}
+
+// Tick restarts the sequence for the decision on tick.
+func (r *SeededRand) Tick(tick int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tick = tick
+	r.rng = rand.New(rand.NewSource(mix_seed(r.seed, tick)))
+}
+
+// Stream is a separate generator for code running concurrently within a
+// tick, like the actor controllers, whose draws would otherwise depend on
+// the scheduling. Equal keys give equal streams within a tick.
+func (r *SeededRand) Stream(keys ...int) *rand.Rand {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return rand.New(rand.NewSource(mix_seed(r.seed, append([]int{r.tick, -1}, keys...)...)))
+}
+
+func (r *SeededRand) Current() int64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.seed
+}
+
+func (r *SeededRand) Float64() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.rng.Float64()
+}
+
+func (r *SeededRand) Intn(n int) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.rng.Intn(n)
+}
+
+// jitter scales value by up to Params.Randomness, so that close choices
+// are not always made the same way.
+func jitter(value float64) float64 {
+	if Params.Randomness <= 0 {
+		return value
+	}
+	return value * (1 + Params.Randomness*Random.Float64())
+}
+
+// jitter_with is jitter drawing from a Stream.
+func jitter_with(r *rand.Rand, value float64) float64 {
+	if Params.Randomness <= 0 {
+		return value
+	}
+	return value * (1 + Params.Randomness*r.Float64())
+}
+
+func add_seed_flag(flags *flag.FlagSet) *int64 {
+	return flags.Int64("seed", 0, "seed of all random choices, 0 picks one from the clock")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket spaces out requests to Rate per second, letting Burst of them
+// through at once. A Rate of 0 disables the limit, Pause still holds all
+// requests back while the server asks for it.
+type TokenBucket struct {
+	Rate         float64
+	Burst        int
+	mutex        sync.Mutex
+	tokens       float64
+	last         time.Time
+	paused_until time.Time
+}
+
+func new_token_bucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{Rate: rate, Burst: burst, tokens: float64(burst)}
+}
+
+// OrderLimiter paces the order submissions.
+var OrderLimiter = new_token_bucket(0, 10)
+
+// rate_limit_pause is how long submissions pause after a 429 without a
+// Retry-After header.
+const rate_limit_pause = 100 * time.Millisecond
+
+// rate_limit_retries is how often a rate limited order is sent again before
+// it counts as rejected. Callers without a deadline would otherwise retry
+// for as long as the server keeps refusing.
+const rate_limit_retries = 5
+
+func max_duration(a time.Duration, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (b *TokenBucket) reserve() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	wait := time.Duration(0)
+	if now.Before(b.paused_until) {
+		wait = b.paused_until.Sub(now)
+	}
+	if b.Rate <= 0 {
+		return wait
+	}
+	burst := float64(max_int(b.Burst, 1))
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*b.Rate)
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens < 0 {
+		if refill := time.Duration(-b.tokens / b.Rate * float64(time.Second)); refill > wait {
+			wait = refill
+		}
+	}
+	return wait
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	wait := b.reserve()
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pause holds all requests back for d, as asked by a Retry-After header.
+func (b *TokenBucket) Pause(d time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if until := time.Now().Add(d); until.After(b.paused_until) {
+		b.paused_until = until
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import "log"
+
+// reconcile_memory rebuilds what the strategies remember from state alone
+// when the bot starts into a running game, be it after a crash or because
+// play was called again in the same process. The models only notice a new
+// game by its tick going back, so a game joined at a later tick than the
+// last one ended at would otherwise be mixed with it, and nothing carried
+// over from before the restart can be trusted to match the board.
+func reconcile_memory(state GameState, counter bool, phased bool, baseline StrategyParams) {
+	reset_memory()
+	// the opponents are classified anew, until then the baseline is played
+	set_params(baseline)
+	observe_state(state, counter, phased, baseline)
+	carriers := Enemies.reconcile(state)
+	ours := 0
+	for _, actor := range filter_objects(state.Actors, true) {
+		if actor.Flag != "" {
+			ours++
+		}
+	}
+	log.Printf("joined game at tick %d, rebuilt memory from the state: %d enemies, %d enemy and %d of our flag carriers",
+		state.Tick, len(filter_objects(state.Actors, false)), carriers, ours)
+}
+
+// reconcile gives enemies seen carrying a flag for the first time the
+// heading towards their base. A carrier is known to head home, while the
+// heading of every other enemy has to wait for its next move. It returns
+// the number of carriers.
+func (m *EnemyMemory) reconcile(state GameState) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	carriers := 0
+	for _, r := range m.records {
+		if r.Flag == "" || r.LastSeen != state.Tick {
+			continue
+		}
+		carriers++
+		if base, ok := closest_base(state.Bases, r.Team, r.Position); ok && r.Heading == "" && r.Position != base.Coordinates {
+			r.Heading = find_path(r.Position, base.Coordinates)
+		}
+	}
+	return carriers
+}
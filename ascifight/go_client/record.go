@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RecordingHeader is the first line of a recording. It holds everything
+// besides the states that the orders of a game depend on.
+type RecordingHeader struct {
+	Started  time.Time      `json:"started"`
+	Seed     int64          `json:"seed"`
+	Strategy string         `json:"strategy"`
+	Team     string         `json:"team"`
+	Rules    Rules          `json:"rules"`
+	Params   StrategyParams `json:"params"`
+	// Baseline are the parameters counter tactics start from.
+	Baseline       StrategyParams `json:"baseline"`
+	FogOptimism    float64        `json:"fog_optimism"`
+	CounterTactics bool           `json:"counter_tactics"`
+	PhaseRoles     bool           `json:"phase_roles,omitempty"`
+	ReuseUnchanged bool           `json:"reuse_unchanged"`
+	DryRun         bool           `json:"dry_run"`
+}
+
+// RecordedTick is one line of a recording after the header.
+type RecordedTick struct {
+	Tick  int       `json:"tick"`
+	State GameState `json:"state"`
+	// Orders are the orders decided for the tick, Skipped is set when they
+	// were held back while stepping.
+	Orders   []PluginOrder `json:"orders"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Rejected []PluginOrder `json:"rejected,omitempty"`
+	Reused   bool          `json:"reused,omitempty"`
+	Events   []GameEvent   `json:"events"`
+}
+
+func recorded_orders(orders []Order) []PluginOrder {
+	recorded := make([]PluginOrder, 0, len(orders))
+	for _, o := range orders {
+		recorded = append(recorded, PluginOrder{o.order_type, o.actor, o.direction, o.reason, o.priority})
+	}
+	return recorded
+}
+
+// GameRecorder writes every game as a JSON lines file in dir, so that the
+// decisions can be replayed and compared later.
+type GameRecorder struct {
+	dir  string
+	file *os.File
+	out  *bufio.Writer
+}
+
+func new_game_recorder(dir string) *GameRecorder {
+	return &GameRecorder{dir: dir}
+}
+
+// Start opens the recording of a new game with header.
+func (r *GameRecorder) Start(header RecordingHeader) error {
+	if err := r.Finish(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(filepath.Join(r.dir, "recording_"+header.Started.Format("20060102_150405")+".jsonl"))
+	if err != nil {
+		return err
+	}
+	r.file = file
+	r.out = bufio.NewWriter(file)
+	return r.write(header)
+}
+
+func (r *GameRecorder) Started() bool {
+	return r.file != nil
+}
+
+func (r *GameRecorder) write(line any) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	r.out.Write(data)
+	r.out.WriteByte('\n')
+	// flushed every tick so that a crash leaves a usable recording
+	return r.out.Flush()
+}
+
+func (r *GameRecorder) Record(tick RecordedTick) error {
+	if r.file == nil {
+		return nil
+	}
+	return r.write(tick)
+}
+
+func (r *GameRecorder) Finish() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.out.Flush()
+	if close_err := r.file.Close(); err == nil {
+		err = close_err
+	}
+	r.file = nil
+	r.out = nil
+	return err
+}
+
+func read_recording(path string) (RecordingHeader, []RecordedTick, error) {
+	var header RecordingHeader
+	file, err := os.Open(path)
+	if err != nil {
+		return header, nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return header, nil, err
+		}
+		return header, nil, errors.New("empty recording")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return header, nil, fmt.Errorf("header: %w", err)
+	}
+	ticks := make([]RecordedTick, 0)
+	for line := 2; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var tick RecordedTick
+		if err := json.Unmarshal(scanner.Bytes(), &tick); err != nil {
+			return header, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ticks = append(ticks, tick)
+	}
+	return header, ticks, scanner.Err()
+}
+
+// reset_memory forgets everything the strategies learned, as if the
+// process had just started.
+func reset_memory() {
+	Plans = new_planner()
+	Enemies = new_enemy_memory()
+	world := new_world_model()
+	world.Optimism, world.UnknownPenalty = World.Optimism, World.UnknownPenalty
+	World = world
+	Opponents = new_opponent_model(20)
+	EnemyHeat = new_heatmap(0.95)
+	TickEvents = &EventLog{}
+	Outcomes.Reset()
+	PathMemo = new_path_cache()
+	Intents = new_intent_board()
+	Pincers = new_pincer_board()
+	Zones = new_zone_defense()
+	Phases = new_phase_machine()
+	Formations = new_formation_board()
+	Alliances = new_alliance_board()
+}
+
+// ReplayDiff is a tick whose replayed orders differ from the recorded ones.
+type ReplayDiff struct {
+	Tick     int
+	Recorded []PluginOrder
+	Replayed []PluginOrder
+}
+
+func same_orders(a []PluginOrder, b []PluginOrder, reasons bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := a[i], b[i]
+		if x.Type != y.Type || x.Actor != y.Actor || x.Direction != y.Direction {
+			return false
+		}
+		if reasons && (x.Reason != y.Reason || x.Priority != y.Priority) {
+			return false
+		}
+	}
+	return true
+}
+
+// replay_game runs strategy over the recorded states with the recorded
+// seed, rules and parameters and returns the ticks where it decides
+// differently. The outcomes of the recorded orders are fed back exactly as
+// they were during the game.
+func replay_game(header RecordingHeader, ticks []RecordedTick, strategy Strategy, reasons bool) []ReplayDiff {
+	reset_memory()
+	World.Optimism = header.FogOptimism
+	GameRules.Set(header.Rules)
+	Team = header.Team
+	Params = header.Params
+	Random.Seed(header.Seed)
+	diffs := make([]ReplayDiff, 0)
+	var previous *RecordedTick
+	var sent []Order
+	for i := range ticks {
+		tick := &ticks[i]
+		if previous == nil && tick.State.Tick > 1 {
+			reconcile_memory(tick.State, header.CounterTactics, header.PhaseRoles, header.Baseline)
+		} else {
+			observe_state(tick.State, header.CounterTactics, header.PhaseRoles, header.Baseline)
+		}
+		if tick.Events != nil {
+			TickEvents.Set(tick.Tick, tick.Events)
+		}
+		if previous != nil && tick.State.Stale == 0 && !header.DryRun {
+			var submitted []Order
+			if !previous.Skipped {
+				submitted = plugin_orders(previous.Orders)
+			}
+			verify_tick(previous.State, tick.State, submitted, plugin_orders(previous.Rejected))
+		}
+		var orders []Order
+		if tick.Reused && previous != nil {
+			orders = sent
+		} else {
+			orders = decide(context.Background(), strategy, tick.State)
+		}
+		orders = validate_orders(orders, tick.State)
+		prioritize_orders(orders)
+		if got := recorded_orders(orders); !same_orders(tick.Orders, got, reasons) {
+			diffs = append(diffs, ReplayDiff{Tick: tick.Tick, Recorded: tick.Orders, Replayed: got})
+		}
+		previous = tick
+		sent = orders
+		if tick.Skipped {
+			sent = nil
+		}
+	}
+	return diffs
+}
+
+func print_orders(label string, orders []PluginOrder) {
+	parts := make([]string, 0, len(orders))
+	for _, o := range orders {
+		part := fmt.Sprintf("%s %d %s", o.Type, o.Actor, o.Direction)
+		if o.Reason != "" {
+			part += " (" + o.Reason + ")"
+		}
+		parts = append(parts, part)
+	}
+	fmt.Printf("  %-9s %s\n", label, strings.Join(parts, ", "))
+}
+
+// replay_command replays recordings made with -record and reports every
+// tick on which the strategy now decides differently than during the game.
+// It exits with status 1 when any tick differs, so it can guard refactors.
+func replay_command(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	strategy_name := flags.String("strategy", "", "strategy to replay with, the recorded one if empty")
+	reasons := flags.Bool("reasons", false, "also compare the reasons and priorities of the orders")
+	limit := flags.Int("show", 10, "differing ticks printed per recording, 0 for all")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s replay [flags] recording.jsonl...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	differ := false
+	for _, path := range flags.Args() {
+		header, ticks, err := read_recording(path)
+		if err != nil {
+			log.Fatalf("reading %s failed: %v", path, err)
+		}
+		name := header.Strategy
+		if *strategy_name != "" {
+			name = *strategy_name
+		}
+		strategy, err := lookup_strategy(name)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		diffs := replay_game(header, ticks, strategy, *reasons)
+		fmt.Printf("%s: %s, %d ticks, seed %d, %d ticks differ\n", path, name, len(ticks), header.Seed, len(diffs))
+		for i, d := range diffs {
+			if *limit > 0 && i >= *limit {
+				fmt.Printf("  ... %d more\n", len(diffs)-i)
+				break
+			}
+			fmt.Printf(" tick %d\n", d.Tick)
+			print_orders("recorded", d.Recorded)
+			print_orders("replayed", d.Replayed)
+		}
+		if len(diffs) > 0 {
+			differ = true
+		}
+	}
+	if differ {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// The self-service registration routes. The server does not offer them
+// yet, register checks for them before calling.
+const (
+	register_route = "/teams/register"
+	whoami_route   = "/teams/me"
+)
+
+type Registration struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// registration_routes tells which of the registration routes the server
+// describes.
+func registration_routes() (bool, bool, error) {
+	routes, err := server_routes()
+	if err != nil {
+		return false, false, err
+	}
+	return routes[register_route], routes[whoami_route], nil
+}
+
+// verify_login checks the credentials with an authenticated request that
+// has no effect on the game.
+func verify_login() error {
+	return api_request(context.Background(), "GET", whoami_route[1:], nil, nil, true, nil)
+}
+
+// register creates the team on the server, stores the returned credentials
+// as a profile of the configuration file and verifies that they work.
+func register_command(args []string) {
+	flags := flag.NewFlagSet("register", flag.ExitOnError)
+	flags.StringVar(&ServerUrl, "server", ServerUrl, "base url of the game server")
+	name := flags.String("team", "", "name of the team to register")
+	config_file := flags.String("config", default_config_file, "configuration file to store the credentials in")
+	profile := flags.String("profile", "registered", "profile of the configuration file to store the server and credentials as")
+	flags.Parse(args)
+	normalize_server_url()
+	if *name == "" {
+		fmt.Fprintln(os.Stderr, "register needs -team")
+		os.Exit(2)
+	}
+
+	can_register, can_verify, err := registration_routes()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if !can_register {
+		fmt.Fprintf(os.Stderr, "the server at %s does not offer team registration, ask the organizers for credentials\n", Servers.Active())
+		os.Exit(1)
+	}
+	var registration Registration
+	if err := api_request(context.Background(), "POST", register_route[1:], nil, Registration{Name: *name}, false, &registration); err != nil {
+		fmt.Fprintf(os.Stderr, "registering %s failed: %v\n", *name, err)
+		os.Exit(1)
+	}
+
+	config, err := load_config(*config_file)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]Profile)
+	}
+	config.Profiles[*profile] = Profile{"server": Servers.Active(), "team": registration.Name, "password": registration.Password}
+	if err := write_config(*config_file, config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("registered %s, credentials stored as profile %s in %s\n", registration.Name, *profile, *config_file)
+
+	if !can_verify {
+		fmt.Println("the server offers no login check, the credentials are verified with the first order")
+		return
+	}
+	Team, Password = registration.Name, registration.Password
+	if err := verify_login(); err != nil {
+		fmt.Fprintf(os.Stderr, "logging in as %s failed: %v\n", registration.Name, err)
+		os.Exit(1)
+	}
+	fmt.Printf("logged in as %s, play with -profile %s\n", registration.Name, *profile)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DecisionPercentiles summarizes how long the strategy took per tick.
+type DecisionPercentiles struct {
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P99 float64 `json:"p99_ms"`
+	Max float64 `json:"max_ms"`
+}
+
+// GameReport is the structured summary of a finished game.
+type GameReport struct {
+	Team        string              `json:"team"`
+	Strategy    string              `json:"strategy"`
+	Started     time.Time           `json:"started"`
+	Ended       time.Time           `json:"ended"`
+	Ticks       int                 `json:"ticks"`
+	MissedTicks int                 `json:"missed_ticks"`
+	Partial     bool                `json:"partial"`
+	FinalScores Scores              `json:"final_scores"`
+	Winner      string              `json:"winner"`
+	Timeline    []ScorePoint        `json:"timeline"`
+	Events      []GameEvent         `json:"events"`
+	Decisions   DecisionPercentiles `json:"decisions"`
+	Actors      map[int]*ActorStats `json:"actors"`
+}
+
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p * float64(len(sorted)-1))
+	return float64(sorted[i]) / float64(time.Millisecond)
+}
+
+func decision_percentiles(times []time.Duration) DecisionPercentiles {
+	sorted := append([]time.Duration(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return DecisionPercentiles{
+		P50: percentile(sorted, 0.5),
+		P90: percentile(sorted, 0.9),
+		P99: percentile(sorted, 0.99),
+		Max: percentile(sorted, 1),
+	}
+}
+
+func new_game_report(s *GameStats) GameReport {
+	return GameReport{
+		Team:        s.Team,
+		Strategy:    s.Strategy,
+		Started:     s.Started,
+		Ended:       s.Ended,
+		Ticks:       s.Ticks,
+		MissedTicks: s.MissedTicks,
+		Partial:     s.Partial(),
+		FinalScores: s.FinalScores,
+		Winner:      leader(s.FinalScores),
+		Timeline:    s.Timeline,
+		Events:      s.Events,
+		Decisions:   decision_percentiles(s.decision_times),
+		Actors:      s.Actors,
+	}
+}
+
+// key_event tells whether an event belongs into the text report, kills and
+// deaths are only counted.
+func key_event(e GameEvent) bool {
+	return e.Kind == "capture" || e.Kind == "steal" || e.Kind == "grab" || e.Kind == "missed"
+}
+
+func (r GameReport) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "game report for %s playing %s\n", r.Team, r.Strategy)
+	fmt.Fprintf(&b, "%d ticks from %s to %s", r.Ticks, r.Started.Format(time.RFC3339), r.Ended.Format(time.RFC3339))
+	if r.Partial {
+		b.WriteString(", joined late")
+	}
+	if r.MissedTicks > 0 {
+		fmt.Fprintf(&b, ", %d missed", r.MissedTicks)
+	}
+	b.WriteString("\n")
+	winner := r.Winner
+	if winner == "" {
+		winner = "nobody"
+	}
+	fmt.Fprintf(&b, "final score: %s, %s wins\n", format_scores(r.FinalScores), winner)
+	b.WriteString("\nscore timeline:\n")
+	for _, point := range r.Timeline {
+		fmt.Fprintf(&b, "  tick %4d  %s\n", point.Tick, format_scores(point.Scores))
+	}
+	b.WriteString("\nkey events:\n")
+	for _, e := range r.Events {
+		if key_event(e) {
+			fmt.Fprintf(&b, "  %s\n", e)
+		}
+	}
+	fmt.Fprintf(&b, "\ndecision time: p50 %.2fms, p90 %.2fms, p99 %.2fms, max %.2fms\n", r.Decisions.P50, r.Decisions.P90, r.Decisions.P99, r.Decisions.Max)
+	fmt.Fprintf(&b, "\n%5s %-10s %5s %8s %5s %6s %8s %6s %8s\n", "actor", "type", "grabs", "captures", "kills", "deaths", "distance", "orders", "rejected")
+	idents := make([]int, 0, len(r.Actors))
+	for ident := range r.Actors {
+		idents = append(idents, ident)
+	}
+	sort.Ints(idents)
+	for _, ident := range idents {
+		a := r.Actors[ident]
+		fmt.Fprintf(&b, "%5d %-10s %5d %8d %5d %6d %8d %6d %8d\n", ident, a.Type, a.Grabs, a.Captures, a.Kills, a.Deaths, a.Distance, a.SubmittedOrders, a.RejectedOrders)
+	}
+	return b.String()
+}
+
+// write_report stores the report of a game as report_<time>.json and .txt
+// next to the stats files.
+func write_report(r GameReport, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("report_%s", r.Ended.Format("20060102_150405"))
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), data, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name+".txt"), []byte(r.Text()), 0644)
+}
@@ -0,0 +1,34 @@
+package main
+
+import "math"
+
+// risk_levels are the names -params accepts for the risk parameter.
+var risk_levels = map[string]float64{"careful": 0, "balanced": 0.5, "reckless": 1}
+
+// risk_threat_steps is the detour a careful actor takes at most to stay out
+// of reach of an enemy attacker for a step.
+const risk_threat_steps = 3
+
+// caution is how much danger weighs for our actors compared to a balanced
+// risk tolerance: 0 for reckless, 1 for balanced and 2 for careful. It
+// scales the heat carriers avoid, the detours around enemy attackers, the
+// odds of a flag run getting through its guards and the fights worth
+// picking.
+func caution() float64 {
+	return 2 * (1 - math.Max(0, math.Min(1, Params.Risk)))
+}
+
+// threat_costs are the extra steps the cells next to enemy attackers cost
+// for the path search. Only actors more careful than balanced make detours
+// for them.
+func threat_costs(threats map[Coordinates]bool) map[Coordinates]int {
+	costs := make(map[Coordinates]int)
+	cost := int(math.Round((caution() - 1) * risk_threat_steps))
+	if cost <= 0 {
+		return costs
+	}
+	for c := range threats {
+		costs[c] = cost
+	}
+	return costs
+}
@@ -0,0 +1,74 @@
+package main
+
+import "math"
+
+// Rival is how one enemy team stands in a game of three or more teams,
+// where the enemy is not a single opponent.
+type Rival struct {
+	Team  string
+	Score int
+	// Threat is the share of its actors closer to our bases than to its own
+	// or carrying one of our flags.
+	Threat float64
+	// Standing ranks it among the enemies from 0 for the weakest to 1 for
+	// the leader, 0.5 while they are level.
+	Standing float64
+}
+
+// assess_rivals rates every enemy team by its score and, among teams with
+// the same score, by its threat to us. With a single enemy there is nobody
+// to choose between and it returns nil.
+func assess_rivals(state GameState) map[string]Rival {
+	teams := make([]string, 0, len(state.Teams))
+	for _, team := range state.Teams {
+		if team != Team {
+			teams = append(teams, team)
+		}
+	}
+	if len(teams) < 2 {
+		return nil
+	}
+	rivals := make(map[string]Rival, len(teams))
+	for _, team := range teams {
+		actors, threats := 0, 0
+		for _, actor := range state.Actors {
+			if actor.Team != team {
+				continue
+			}
+			actors++
+			home, has_home := closest_base(state.Bases, team, actor.Coordinates)
+			ours, has_ours := closest_base(state.Bases, Team, actor.Coordinates)
+			advanced := has_home && has_ours && distance(actor.Coordinates, ours.Coordinates) < distance(actor.Coordinates, home.Coordinates)
+			if actor.Flag == Team || advanced {
+				threats++
+			}
+		}
+		rival := Rival{Team: team, Score: state.Scores[team]}
+		if actors > 0 {
+			rival.Threat = float64(threats) / float64(actors)
+		}
+		rivals[team] = rival
+	}
+	// the threat is below one and only breaks ties of the score
+	low, high, mean := math.Inf(1), math.Inf(-1), 0.0
+	strength := func(r Rival) float64 { return float64(r.Score) + r.Threat*0.99 }
+	for _, rival := range rivals {
+		s := strength(rival)
+		low, high, mean = math.Min(low, s), math.Max(high, s), mean+s/float64(len(rivals))
+	}
+	for team, rival := range rivals {
+		rival.Standing = math.Max(0, math.Min(1, 0.5+(strength(rival)-mean)/(2*math.Max(1, high-low))))
+		rivals[team] = rival
+	}
+	return rivals
+}
+
+// rival_bias is how much more than usual going after team is worth, from
+// -0.5 for the weakest enemy to 0.5 for the leader and 0 with a single
+// enemy.
+func rival_bias(rivals map[string]Rival, team string) float64 {
+	if rival, ok := rivals[team]; ok {
+		return rival.Standing - 0.5
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RulesCache holds the rules of the running game. They only change when a
+// new game starts, so they are fetched once per game instead of per tick.
+type RulesCache struct {
+	mutex  sync.Mutex
+	rules  Rules
+	loaded bool
+	failed time.Time
+	err    error
+}
+
+var GameRules = &RulesCache{}
+
+// rules_backoff is how long a failed fetch is remembered. Meanwhile the
+// accessors get empty rules and the error instead of asking the server
+// again on every call.
+const rules_backoff = 5 * time.Second
+
+// Get returns the cached rules, fetching them first if needed. The fetch
+// runs without holding the lock, so that a slow server does not stall
+// every other reader.
+func (c *RulesCache) Get() (Rules, error) {
+	c.mutex.Lock()
+	if c.loaded {
+		defer c.mutex.Unlock()
+		return c.rules, nil
+	}
+	if !c.failed.IsZero() && time.Since(c.failed) < rules_backoff {
+		defer c.mutex.Unlock()
+		return Rules{}, c.err
+	}
+	c.mutex.Unlock()
+	// the accessor that asked waits for the request, so it must not hang
+	ctx, cancel := context.WithTimeout(context.Background(), fetch_timeout)
+	defer cancel()
+	r, err := DefaultClient.Rules(ctx)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err != nil {
+		c.failed = time.Now()
+		c.err = err
+		return Rules{}, err
+	}
+	c.rules = r
+	c.loaded = true
+	c.failed = time.Time{}
+	Capabilities.Rebuild(r.ActorProperties)
+	return r, nil
+}
+
+func (c *RulesCache) Set(r Rules) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.rules = r
+	c.loaded = true
+	c.failed = time.Time{}
+	Capabilities.Rebuild(r.ActorProperties)
+}
+
+func (c *RulesCache) Loaded() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.loaded
+}
+
+// Invalidate drops the rules when a new game started.
+func (c *RulesCache) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.loaded = false
+	c.failed = time.Time{}
+	Capabilities.Rebuild(nil)
+}
+
+// MapSize is the board length, or 0 while the rules are unknown.
+func (c *RulesCache) MapSize() int {
+	r, _ := c.Get()
+	return r.MapSize
+}
+
+// MaxTicks is the game length, or 0 while the rules are unknown.
+func (c *RulesCache) MaxTicks() int {
+	r, _ := c.Get()
+	return r.MaxTicks
+}
+
+// ActorProperty returns the properties of an actor type.
+func (c *RulesCache) ActorProperty(actor_type string) (ActorProperty, bool) {
+	r, _ := c.Get()
+	for _, p := range r.ActorProperties {
+		if p.Type == actor_type {
+			return p, true
+		}
+	}
+	return ActorProperty{}, false
+}
+
+// MaxScore is the score that ends the game, or 0 while the rules are
+// unknown.
+func (c *RulesCache) MaxScore() int {
+	r, _ := c.Get()
+	return r.MaxScore
+}
+
+// CaptureScore is what a capture is worth, or 0 while the rules are unknown.
+func (c *RulesCache) CaptureScore() int {
+	r, _ := c.Get()
+	return r.CaptureScore
+}
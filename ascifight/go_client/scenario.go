@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Scenario is a hand written board situation that loads as a GameState,
+// so that tricky situations can be fed to the strategies reproducibly.
+//
+// Board holds one line per row, the top line being the highest y like in
+// render_board. Cells are separated by blanks:
+//
+//	.       empty
+//	#       wall
+//	B1      base of team 1
+//	F1      flag of team 1 lying on the cell
+//	0R2     actor 2 of team 0, a Runner
+//	0R2*1   the same actor carrying the flag of team 1
+//
+// Teams are given by their index in Teams. Actor letters are looked up in
+// Legend, which defaults to R, A, G, B and D for the runner, attacker,
+// guardian, builder and destroyer. Row and column labels of a pasted
+// render_board are ignored. Objects the board cannot express can be listed
+// in Actors, Flags, Bases and Walls.
+type Scenario struct {
+	// Path is the file the scenario was loaded from.
+	Path        string `json:"-"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Team is the team the strategies play, the first one if empty.
+	Team   string            `json:"team,omitempty"`
+	Teams  []string          `json:"teams"`
+	Tick   int               `json:"tick,omitempty"`
+	Scores Scores            `json:"scores,omitempty"`
+	Board  []string          `json:"board"`
+	Legend map[string]string `json:"legend,omitempty"`
+	// Rules override the defaults, the map size is taken from the board.
+	Rules  *Rules  `json:"rules,omitempty"`
+	Actors []Actor `json:"actors,omitempty"`
+	Flags  []Flag  `json:"flags,omitempty"`
+	Bases  []Base  `json:"bases,omitempty"`
+	Walls  []Wall  `json:"walls,omitempty"`
+}
+
+var scenario_legend = map[string]string{
+	"R": "Runner",
+	"A": "Attacker",
+	"G": "Guardian",
+	"B": "Builder",
+	"D": "Destroyer",
+}
+
+func load_scenario(path string) (Scenario, error) {
+	var s Scenario
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	s.Path = path
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return s, nil
+}
+
+// load_scenarios loads a scenario file, or every .json file below a
+// directory in name order.
+func load_scenarios(path string) ([]Scenario, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		s, err := load_scenario(path)
+		return []Scenario{s}, err
+	}
+	paths := make([]string, 0)
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && filepath.Ext(p) == ".json" {
+			paths = append(paths, p)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	scenarios := make([]Scenario, 0, len(paths))
+	for _, p := range paths {
+		s, err := load_scenario(p)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, s)
+	}
+	return scenarios, nil
+}
+
+func is_number(field string) bool {
+	_, err := strconv.Atoi(field)
+	return err == nil
+}
+
+// rows splits the board into cells, dropping render_board labels.
+func (s Scenario) rows() [][]string {
+	rows := make([][]string, 0, len(s.Board))
+	for _, line := range s.Board {
+		fields := strings.Fields(line)
+		labels := len(fields) > 0
+		for _, field := range fields {
+			labels = labels && is_number(field)
+		}
+		if labels {
+			continue
+		}
+		if len(fields) > 0 && is_number(fields[0]) {
+			fields = fields[1:]
+		}
+		rows = append(rows, fields)
+	}
+	return rows
+}
+
+// Size is the board length, the larger of its rows and columns.
+func (s Scenario) Size() int {
+	rows := s.rows()
+	size := len(rows)
+	for _, row := range rows {
+		size = max_int(size, len(row))
+	}
+	return size
+}
+
+func (s Scenario) team(field string, index string) (string, error) {
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(s.Teams) {
+		return "", fmt.Errorf("cell %q: no team %s", field, index)
+	}
+	return s.Teams[i], nil
+}
+
+// cell adds the object described by field at c to state.
+func (s Scenario) cell(state *GameState, field string, c Coordinates) error {
+	switch {
+	case field == "." || field == "..":
+		return nil
+	case field == "#" || field == "##":
+		state.Walls = append(state.Walls, Wall{X: c.X, Y: c.Y})
+		return nil
+	case field[0] == 'B' || field[0] == 'F':
+		team, err := s.team(field, field[1:])
+		if err != nil {
+			return err
+		}
+		object := OwnedObjectImpl{Team: team, Coordinates: c}
+		if field[0] == 'B' {
+			state.Bases = append(state.Bases, Base{object})
+		} else {
+			state.Flags = append(state.Flags, Flag{object})
+		}
+		return nil
+	}
+	actor, carried, _ := strings.Cut(field, "*")
+	digits := strings.IndexFunc(actor, func(r rune) bool { return !unicode.IsDigit(r) })
+	if digits <= 0 || digits == len(actor) {
+		return fmt.Errorf("cell %q at %v is not understood", field, c)
+	}
+	team, err := s.team(field, actor[:digits])
+	if err != nil {
+		return err
+	}
+	letter := actor[digits : digits+1]
+	actor_type, ok := s.Legend[letter]
+	if !ok {
+		actor_type, ok = scenario_legend[letter]
+	}
+	if !ok {
+		return fmt.Errorf("cell %q: no actor type for %s in the legend", field, letter)
+	}
+	ident, err := strconv.Atoi(actor[digits+1:])
+	if err != nil {
+		return fmt.Errorf("cell %q: invalid actor ident", field)
+	}
+	a := Actor{Type: actor_type, Ident: ident, OwnedObjectImpl: OwnedObjectImpl{Team: team, Coordinates: c}}
+	if carried != "" {
+		if a.Flag, err = s.team(field, carried); err != nil {
+			return err
+		}
+	}
+	state.Actors = append(state.Actors, a)
+	return nil
+}
+
+// State builds the game state of the scenario. A carried flag that is not
+// on the board is placed on its carrier, like the server reports it.
+func (s Scenario) State() (GameState, error) {
+	state := GameState{Teams: s.Teams, Tick: s.Tick, Scores: Scores{}}
+	if len(s.Teams) == 0 {
+		return state, fmt.Errorf("scenario %s has no teams", s.Name)
+	}
+	rows := s.rows()
+	for i, row := range rows {
+		y := len(rows) - 1 - i
+		for x, field := range row {
+			if err := s.cell(&state, field, Coordinates{x, y}); err != nil {
+				return state, fmt.Errorf("scenario %s: %w", s.Name, err)
+			}
+		}
+	}
+	state.Actors = append(state.Actors, s.Actors...)
+	state.Flags = append(state.Flags, s.Flags...)
+	state.Bases = append(state.Bases, s.Bases...)
+	state.Walls = append(state.Walls, s.Walls...)
+	for _, actor := range state.Actors {
+		if actor.Flag == "" {
+			continue
+		}
+		lying := false
+		for _, flag := range state.Flags {
+			lying = lying || (flag.Team == actor.Flag && flag.Coordinates == actor.Coordinates)
+		}
+		if !lying {
+			state.Flags = append(state.Flags, Flag{OwnedObjectImpl{Team: actor.Flag, Coordinates: actor.Coordinates}})
+		}
+	}
+	for _, team := range s.Teams {
+		state.Scores[team] = s.Scores[team]
+	}
+	return state, nil
+}
+
+// GameRules returns the rules the scenario is played with.
+func (s Scenario) GameRules() Rules {
+	rules := Rules{MaxTicks: 200, MaxScore: 3, CaptureScore: 5, KillScore: 1, WinningBonus: 10}
+	if s.Rules != nil {
+		rules = *s.Rules
+	}
+	if rules.MapSize == 0 {
+		rules.MapSize = s.Size()
+	}
+	if len(rules.ActorProperties) == 0 {
+		rules.ActorProperties = bench_properties()
+	}
+	return rules
+}
+
+// Load makes the scenario the current game: its team plays, its rules
+// apply and everything remembered from earlier states is forgotten.
+func (s Scenario) Load() (GameState, error) {
+	state, err := s.State()
+	if err != nil {
+		return state, err
+	}
+	if s.Team != "" {
+		Team = s.Team
+	} else {
+		Team = s.Teams[0]
+	}
+	reset_memory()
+	rules := s.GameRules()
+	GameRules.Set(rules)
+	if err := validate_state(state, rules.MapSize); err != nil {
+		return state, fmt.Errorf("scenario %s: %w", s.Name, err)
+	}
+	return state, nil
+}
+
+// Orders are the validated orders strategy gives in the scenario, decided
+// from a fresh memory with seed.
+func (s Scenario) Orders(strategy Strategy, seed int64) ([]Order, error) {
+	state, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	Random.Seed(seed)
+	orders := validate_orders(decide(context.Background(), strategy, state), state)
+	prioritize_orders(orders)
+	return orders, nil
+}
+
+// scenario_command shows scenarios and the orders strategies give in them.
+func scenario_command(args []string) {
+	flags := flag.NewFlagSet("scenario", flag.ExitOnError)
+	strategy_list := flags.String("strategies", "", "comma separated strategies whose orders are shown")
+	seed := flags.Int64("seed", 1, "seed of all random choices, fixed so that the orders are the same every run")
+	config_flags := add_config_flags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s scenario [flags] scenario.json|directory...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := config_flags.Apply(); err != nil {
+		log.Fatalln(err)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	strategies := []Strategy{}
+	if *strategy_list != "" {
+		var err error
+		if strategies, err = lookup_strategies(*strategy_list); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	for _, path := range flags.Args() {
+		scenarios, err := load_scenarios(path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, s := range scenarios {
+			state, err := s.Load()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			fmt.Printf("%s: %s\n", s.Name, s.Description)
+			fmt.Print(render_board(state, GameRules.MapSize()))
+			fmt.Println(render_scores(state))
+			for _, strategy := range strategies {
+				orders, err := s.Orders(strategy, *seed)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				fmt.Printf("%s:\n", strategy.Name())
+				for _, order := range orders {
+					fmt.Printf("  %v\n", order)
+				}
+			}
+			fmt.Println()
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StrictSchema makes decoding fail on fields the client does not know
+// instead of only warning about them.
+var StrictSchema bool
+
+var (
+	schema_mutex  sync.Mutex
+	schema_warned = make(map[string]bool)
+)
+
+// json_fields collects the JSON names of a struct's fields, promoting the
+// fields of untagged embedded structs like encoding/json does.
+func json_fields(t reflect.Type, fields map[string]reflect.StructField) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			json_fields(field.Type, fields)
+			continue
+		}
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+}
+
+// schema_differences compares decoded JSON against the Go type it is
+// decoded into and lists the paths of unknown and missing fields.
+func schema_differences(t reflect.Type, value interface{}, path string, unknown *[]string, missing *[]string) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface {
+		if t.Kind() == reflect.Interface {
+			return
+		}
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := make(map[string]reflect.StructField)
+		json_fields(t, fields)
+		for name, field := range fields {
+			child, present := object[name]
+			if !present {
+				if !strings.Contains(field.Tag.Get("json"), "omitempty") {
+					*missing = append(*missing, path+"."+name)
+				}
+				continue
+			}
+			schema_differences(field.Type, child, path+"."+name, unknown, missing)
+		}
+		for name := range object {
+			if _, ok := fields[name]; !ok {
+				*unknown = append(*unknown, path+"."+name)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for _, element := range list {
+			schema_differences(t.Elem(), element, path+"[]", unknown, missing)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, element := range object {
+			schema_differences(t.Elem(), element, path+"{}", unknown, missing)
+		}
+	}
+}
+
+func unique_sorted(paths []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !seen[path] {
+			seen[path] = true
+			result = append(result, path)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// decode_response decodes a server response into v. Missing fields keep
+// their zero value and unknown fields are ignored, each being warned about
+// once. In strict mode unknown fields are an error.
+func decode_response(endpoint string, data []byte, v any) error {
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return fmt.Errorf("decoding %s: %w", endpoint, err)
+	}
+	var unknown, missing []string
+	schema_differences(reflect.TypeOf(v), generic, endpoint, &unknown, &missing)
+	unknown = unique_sorted(unknown)
+	missing = unique_sorted(missing)
+	if StrictSchema && len(unknown) > 0 {
+		return fmt.Errorf("decoding %s: unknown fields %s, the server API changed", endpoint, strings.Join(unknown, ", "))
+	}
+	schema_mutex.Lock()
+	for _, path := range unknown {
+		if !schema_warned[path] {
+			schema_warned[path] = true
+			log.Printf("warning: ignoring unknown field %s in server response", path)
+		}
+	}
+	for _, path := range missing {
+		if !schema_warned[path] {
+			schema_warned[path] = true
+			log.Printf("warning: server response lacks field %s, using its default", path)
+		}
+	}
+	schema_mutex.Unlock()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if StrictSchema {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("decoding %s: %w", endpoint, err)
+	}
+	return nil
+}
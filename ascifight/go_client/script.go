@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/d5/tengo/v2"
+	"github.com/d5/tengo/v2/stdlib"
+)
+
+// ScriptStrategy runs a Tengo script every tick. The script sees the decoded
+// game state as `state`, our team name as `team` and returns its orders by
+// assigning a list of {type, actor, direction, reason} maps to `orders`,
+// reason being optional. The file is recompiled whenever it changes on disk.
+type ScriptStrategy struct {
+	path     string
+	mutex    sync.Mutex
+	modified time.Time
+	compiled *tengo.Compiled
+}
+
+func new_script_strategy(path string) (*ScriptStrategy, error) {
+	s := &ScriptStrategy{path: path}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *ScriptStrategy) Name() string {
+	return "script:" + s.path
+}
+
+func coordinates_value(c Coordinates) map[string]interface{} {
+	return map[string]interface{}{"x": c.X, "y": c.Y}
+}
+
+func state_value(state GameState) map[string]interface{} {
+	teams := make([]interface{}, 0, len(state.Teams))
+	for _, team := range state.Teams {
+		teams = append(teams, team)
+	}
+	actors := make([]interface{}, 0, len(state.Actors))
+	for _, actor := range state.Actors {
+		actors = append(actors, map[string]interface{}{
+			"type":        actor.Type,
+			"team":        actor.Team,
+			"ident":       actor.Ident,
+			"flag":        actor.Flag,
+			"coordinates": coordinates_value(actor.Coordinates),
+		})
+	}
+	flags := make([]interface{}, 0, len(state.Flags))
+	for _, flag := range state.Flags {
+		flags = append(flags, map[string]interface{}{"team": flag.Team, "coordinates": coordinates_value(flag.Coordinates)})
+	}
+	bases := make([]interface{}, 0, len(state.Bases))
+	for _, base := range state.Bases {
+		bases = append(bases, map[string]interface{}{"team": base.Team, "coordinates": coordinates_value(base.Coordinates)})
+	}
+	walls := make([]interface{}, 0, len(state.Walls))
+	for _, wall := range state.Walls {
+		walls = append(walls, map[string]interface{}{"x": wall.X, "y": wall.Y})
+	}
+	scores := make(map[string]interface{}, len(state.Scores))
+	for team, score := range state.Scores {
+		scores[team] = score
+	}
+	return map[string]interface{}{
+		"teams":  teams,
+		"actors": actors,
+		"flags":  flags,
+		"bases":  bases,
+		"walls":  walls,
+		"scores": scores,
+		"tick":   state.Tick,
+	}
+}
+
+func script_coordinates(o tengo.Object) (Coordinates, error) {
+	m, ok := tengo.ToInterface(o).(map[string]interface{})
+	if !ok {
+		return Coordinates{}, fmt.Errorf("expected coordinates map, got %s", o.TypeName())
+	}
+	x, x_ok := m["x"].(int64)
+	y, y_ok := m["y"].(int64)
+	if !x_ok || !y_ok {
+		return Coordinates{}, fmt.Errorf("coordinates need integer x and y")
+	}
+	return Coordinates{int(x), int(y)}, nil
+}
+
+// script_helpers exposes the pathing helpers of the client to scripts.
+func script_helpers() map[string]tengo.Object {
+	two_coordinates := func(args []tengo.Object) (Coordinates, Coordinates, error) {
+		if len(args) != 2 {
+			return Coordinates{}, Coordinates{}, tengo.ErrWrongNumArguments
+		}
+		a, err := script_coordinates(args[0])
+		if err != nil {
+			return a, Coordinates{}, err
+		}
+		b, err := script_coordinates(args[1])
+		return a, b, err
+	}
+	return map[string]tengo.Object{
+		"distance": &tengo.UserFunction{Name: "distance", Value: func(args ...tengo.Object) (tengo.Object, error) {
+			a, b, err := two_coordinates(args)
+			if err != nil {
+				return nil, err
+			}
+			return &tengo.Int{Value: int64(distance(a, b))}, nil
+		}},
+		"direction": &tengo.UserFunction{Name: "direction", Value: func(args ...tengo.Object) (tengo.Object, error) {
+			a, b, err := two_coordinates(args)
+			if err != nil {
+				return nil, err
+			}
+			return &tengo.String{Value: find_path(a, b)}, nil
+		}},
+	}
+}
+
+func (s *ScriptStrategy) reload() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	if s.compiled != nil && !info.ModTime().After(s.modified) {
+		return nil
+	}
+	source, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	script := tengo.NewScript(source)
+	script.SetImports(stdlib.GetModuleMap("math", "text", "fmt", "enum", "rand"))
+	script.Add("state", map[string]interface{}{})
+	script.Add("team", Team)
+	script.Add("orders", []interface{}{})
+	for name, helper := range script_helpers() {
+		script.Add(name, helper)
+	}
+	compiled, err := script.Compile()
+	if err != nil {
+		return fmt.Errorf("compiling %s: %w", s.path, err)
+	}
+	if s.compiled != nil {
+		logf(VerbosityNormal, "reloaded strategy script %s", s.path)
+	}
+	s.compiled = compiled
+	s.modified = info.ModTime()
+	return nil
+}
+
+func script_orders(value []interface{}) ([]Order, error) {
+	orders := make([]Order, 0, len(value))
+	for i, entry := range value {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("order %d is not a map", i)
+		}
+		order_type, type_ok := m["type"].(string)
+		actor, actor_ok := m["actor"].(int64)
+		direction, direction_ok := m["direction"].(string)
+		if !type_ok || !actor_ok || !direction_ok {
+			return nil, fmt.Errorf("order %d needs string type, integer actor and string direction", i)
+		}
+		reason, _ := m["reason"].(string)
+		priority, _ := m["priority"].(int64)
+		orders = append(orders, Order{order_type, int(actor), direction, reason, int(priority)})
+	}
+	return orders, nil
+}
+
+func (s *ScriptStrategy) GenerateOrders(state GameState) []Order {
+	return s.GenerateOrdersContext(context.Background(), state)
+}
+
+func (s *ScriptStrategy) GenerateOrdersContext(ctx context.Context, state GameState) []Order {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.reload(); err != nil {
+		log.Printf("strategy script not reloaded: %v", err)
+	}
+	compiled := s.compiled.Clone()
+	if err := compiled.Set("state", state_value(state)); err != nil {
+		log.Printf("strategy script: %v", err)
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := compiled.RunContext(ctx); err != nil {
+		log.Printf("strategy script failed: %v", err)
+		return nil
+	}
+	orders, err := script_orders(compiled.Get("orders").Array())
+	if err != nil {
+		log.Printf("strategy script returned invalid orders: %v", err)
+		return nil
+	}
+	return orders
+}
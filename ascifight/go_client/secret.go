@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keychain_service is the service name the team passwords are stored
+// under in the OS credential store, one entry per team.
+const keychain_service = "ascifight"
+
+// PasswordSource tells where the password of the team comes from: the
+// -password flag, the environment, a prompt at startup or the OS keychain.
+var PasswordSource = "flag"
+
+// password_env carries the password for -password-source env. The arena
+// hands it to the bots it starts this way, since their command lines can
+// be read by every user of the machine.
+const password_env = "ASCIFIGHT_PASSWORD"
+
+// keychain_lookup reads the password of team from the macOS keychain or the
+// Secret Service on Linux, the two stores with a command line client.
+func keychain_lookup(team string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychain_service, "-a", team, "-w")
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", keychain_service, "account", team)
+	default:
+		return "", fmt.Errorf("no keychain support on %s", runtime.GOOS)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("no password for %s in the keychain: %w", team, err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// keychain_store saves the password of team in the OS keychain. The
+// password never goes on a command line, where other users could read it:
+// security prompts for it itself and secret-tool gets it on stdin.
+func keychain_store(team string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		if !is_terminal(os.Stdin) {
+			return fmt.Errorf("cannot prompt for the password of %s, stdin is no terminal", team)
+		}
+		// -w without a value makes security ask for the password
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", keychain_service, "-a", team, "-w")
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stderr, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("storing the password of %s: %v", team, err)
+		}
+		return nil
+	case "linux", "freebsd", "openbsd":
+		password, err := prompt_password(team)
+		if err != nil {
+			return err
+		}
+		cmd = exec.Command("secret-tool", "store", "--label", "ascifight password of "+team, "service", keychain_service, "account", team)
+		cmd.Stdin = strings.NewReader(password)
+	default:
+		return fmt.Errorf("no keychain support on %s", runtime.GOOS)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("storing the password of %s: %v %s", team, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// prompt_password asks for the password on the terminal. Echo is turned off
+// with stty where there is one.
+func prompt_password(team string) (string, error) {
+	if !is_terminal(os.Stdin) {
+		return "", fmt.Errorf("cannot prompt for the password of %s, stdin is no terminal", team)
+	}
+	fmt.Fprintf(os.Stderr, "password of %s: ", team)
+	if err := stty("-echo"); err == nil {
+		defer stty("echo")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading the password of %s: %w", team, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func stty(mode string) error {
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+// resolve_password replaces the -password value according to
+// -password-source. A team missing from the keychain is prompted for when
+// there is a terminal to ask on.
+func resolve_password() error {
+	var err error
+	switch PasswordSource {
+	case "flag":
+		return nil
+	case "env":
+		if Password = os.Getenv(password_env); Password == "" {
+			err = fmt.Errorf("no password for %s in $%s", Team, password_env)
+		}
+	case "prompt":
+		Password, err = prompt_password(Team)
+	case "keychain":
+		var password string
+		if password, err = keychain_lookup(Team); err == nil {
+			Password = password
+		} else if is_terminal(os.Stdin) {
+			log.Printf("%v, asking instead", err)
+			Password, err = prompt_password(Team)
+		}
+	default:
+		err = fmt.Errorf("unknown password source %q, use flag, env, prompt or keychain", PasswordSource)
+	}
+	return err
+}
+
+// keychain_command stores the password of a team in the OS keychain for
+// -password-source keychain.
+func keychain_command(args []string) {
+	flags := flag.NewFlagSet("keychain", flag.ExitOnError)
+	flags.StringVar(&Team, "team", Team, "name of the team whose password is stored")
+	flags.Parse(args)
+
+	if err := keychain_store(Team); err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Printf("stored the password of %s, play with -password-source keychain\n", Team)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const snapshot_cell = 20
+
+var snapshot_palette = color.Palette{
+	color.RGBA{0x11, 0x11, 0x11, 0xff}, // background
+	color.RGBA{0x22, 0x22, 0x22, 0xff}, // grid
+	color.RGBA{0x77, 0x77, 0x77, 0xff}, // wall
+	color.RGBA{0xff, 0xff, 0xff, 0xff}, // carried flag marker
+	color.RGBA{0x33, 0x99, 0xff, 0xff}, // team colors from here on
+	color.RGBA{0xff, 0x44, 0x44, 0xff},
+	color.RGBA{0x44, 0xcc, 0x44, 0xff},
+	color.RGBA{0xff, 0xcc, 0x22, 0xff},
+	color.RGBA{0xcc, 0x55, 0xff, 0xff},
+	color.RGBA{0x22, 0xdd, 0xdd, 0xff},
+}
+
+const (
+	snapshot_background = 0
+	snapshot_grid       = 1
+	snapshot_wall       = 2
+	snapshot_marker     = 3
+	snapshot_teams      = 4
+)
+
+func team_color(state GameState, team string) uint8 {
+	return uint8(snapshot_teams + team_index(state, team)%(len(snapshot_palette)-snapshot_teams))
+}
+
+// render_image draws the board like render_board, y growing upwards. Bases
+// are hollow squares, flags small squares and actors filled squares in
+// their team color, with a white dot when they carry a flag.
+func render_image(state GameState, size int) *image.Paletted {
+	size = board_size(state, size)
+	img := image.NewPaletted(image.Rect(0, 0, size*snapshot_cell, size*snapshot_cell), snapshot_palette)
+	fill := func(c Coordinates, inset int, index uint8) {
+		top := (size - 1 - c.Y) * snapshot_cell
+		left := c.X * snapshot_cell
+		for y := top + inset; y < top+snapshot_cell-inset; y++ {
+			for x := left + inset; x < left+snapshot_cell-inset; x++ {
+				img.SetColorIndex(x, y, index)
+			}
+		}
+	}
+	inside := func(c Coordinates) bool {
+		return c.X >= 0 && c.Y >= 0 && c.X < size && c.Y < size
+	}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			fill(Coordinates{x, y}, 0, snapshot_grid)
+			fill(Coordinates{x, y}, 1, snapshot_background)
+		}
+	}
+	for _, wall := range state.Walls {
+		if c := (Coordinates{wall.X, wall.Y}); inside(c) {
+			fill(c, 0, snapshot_wall)
+		}
+	}
+	for _, base := range state.Bases {
+		if inside(base.Coordinates) {
+			fill(base.Coordinates, 1, team_color(state, base.Team))
+			fill(base.Coordinates, 4, snapshot_background)
+		}
+	}
+	for _, flag := range state.Flags {
+		if inside(flag.Coordinates) {
+			fill(flag.Coordinates, 6, team_color(state, flag.Team))
+		}
+	}
+	for _, actor := range state.Actors {
+		if !inside(actor.Coordinates) {
+			continue
+		}
+		fill(actor.Coordinates, 3, team_color(state, actor.Team))
+		if actor.Flag != "" {
+			fill(actor.Coordinates, 8, snapshot_marker)
+		}
+	}
+	return img
+}
+
+// SnapshotRecorder writes one PNG per tick and an animated GIF per game
+// into a directory of its own for every game.
+type SnapshotRecorder struct {
+	dir    string
+	game   string
+	frames []*image.Paletted
+}
+
+func new_snapshot_recorder(dir string) *SnapshotRecorder {
+	return &SnapshotRecorder{dir: dir}
+}
+
+func (r *SnapshotRecorder) Record(state GameState) error {
+	if r.game == "" {
+		r.game = filepath.Join(r.dir, "game_"+time.Now().Format("20060102_150405"))
+		if err := os.MkdirAll(r.game, 0755); err != nil {
+			return err
+		}
+	}
+	img := render_image(state, GameRules.MapSize())
+	r.frames = append(r.frames, img)
+	out, err := os.Create(filepath.Join(r.game, fmt.Sprintf("tick_%04d.png", state.Tick)))
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return png.Encode(out, img)
+}
+
+// Finish assembles the frames of the game that just ended into game.gif.
+func (r *SnapshotRecorder) Finish() error {
+	if len(r.frames) == 0 {
+		return nil
+	}
+	animation := &gif.GIF{Image: r.frames, Delay: make([]int, len(r.frames))}
+	for i := range animation.Delay {
+		animation.Delay[i] = 20
+	}
+	animation.Delay[len(animation.Delay)-1] = 300
+	path := filepath.Join(r.game, "game.gif")
+	r.game = ""
+	r.frames = nil
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return gif.EncodeAll(out, animation)
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GameStats accumulates what happened to our team during a single game.
+type GameStats struct {
+	Team              string    `json:"team"`
+	Strategy          string    `json:"strategy"`
+	Started           time.Time `json:"started"`
+	Ended             time.Time `json:"ended"`
+	FirstTick         int       `json:"first_tick"`
+	Ticks             int       `json:"ticks"`
+	Grabs             int       `json:"grabs"`
+	Captures          int       `json:"captures"`
+	Kills             int       `json:"kills"`
+	Deaths            int       `json:"deaths"`
+	SubmittedOrders   int       `json:"submitted_orders"`
+	RejectedOrders    int       `json:"rejected_orders"`
+	AverageDecisionMs float64   `json:"average_decision_ms"`
+	MissedTicks       int       `json:"missed_ticks"`
+	FinalScores       Scores    `json:"final_scores"`
+	// Actors breaks the counts down by actor ident.
+	Actors map[int]*ActorStats `json:"actors"`
+	// Timeline holds the scores of every tick they changed in.
+	Timeline []ScorePoint `json:"timeline"`
+	Events   []GameEvent  `json:"events"`
+
+	decisions      int
+	decision_time  time.Duration
+	decision_times []time.Duration
+}
+
+type ScorePoint struct {
+	Tick   int    `json:"tick"`
+	Scores Scores `json:"scores"`
+}
+
+// GameEvent is something notable that happened in a tick: a grab or
+// capture by any team, a steal of our flag, a kill by or a death of one of
+// our actors, or an order the server could not carry out.
+type GameEvent struct {
+	Tick  int    `json:"tick"`
+	Kind  string `json:"kind"`
+	Team  string `json:"team"`
+	Actor int    `json:"actor"`
+	Flag  string `json:"flag,omitempty"`
+	// Target is the actor that got killed.
+	Target string `json:"target,omitempty"`
+	// Detail is the reason the server gave for a failed order.
+	Detail string `json:"detail,omitempty"`
+}
+
+func (e GameEvent) String() string {
+	switch e.Kind {
+	case "grab", "steal":
+		return fmt.Sprintf("tick %d: %s actor %d grabbed the flag of %s", e.Tick, e.Team, e.Actor, e.Flag)
+	case "capture":
+		return fmt.Sprintf("tick %d: %s actor %d captured the flag of %s", e.Tick, e.Team, e.Actor, e.Flag)
+	case "kill":
+		return fmt.Sprintf("tick %d: %s actor %d killed %s", e.Tick, e.Team, e.Actor, e.Target)
+	case "missed":
+		return fmt.Sprintf("tick %d: missed %s", e.Tick, e.Detail)
+	case "failed":
+		return fmt.Sprintf("tick %d: order of %s actor %d failed: %s", e.Tick, e.Team, e.Actor, e.Detail)
+	default:
+		return fmt.Sprintf("tick %d: %s actor %d %s", e.Tick, e.Team, e.Actor, e.Kind)
+	}
+}
+
+// ActorStats is what a single actor of our team did during a game.
+type ActorStats struct {
+	Type            string `json:"type"`
+	Grabs           int    `json:"grabs"`
+	Captures        int    `json:"captures"`
+	Kills           int    `json:"kills"`
+	Deaths          int    `json:"deaths"`
+	Distance        int    `json:"distance"`
+	SubmittedOrders int    `json:"submitted_orders"`
+	RejectedOrders  int    `json:"rejected_orders"`
+}
+
+func (s *GameStats) actor(ident int) *ActorStats {
+	a, ok := s.Actors[ident]
+	if !ok {
+		a = &ActorStats{}
+		s.Actors[ident] = a
+	}
+	return a
+}
+
+// Partial reports whether we joined the game after it had already begun.
+func (s *GameStats) Partial() bool {
+	return s.FirstTick > 1
+}
+
+func new_game_stats(strategy string) *GameStats {
+	return &GameStats{Team: Team, Strategy: strategy, Started: time.Now(), Actors: make(map[int]*ActorStats)}
+}
+
+func find_actor(actors []Actor, team string, ident int) (Actor, bool) {
+	for _, actor := range actors {
+		if actor.Team == team && actor.Ident == ident {
+			return actor, true
+		}
+	}
+	return Actor{}, false
+}
+
+func same_scores(a Scores, b Scores) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for team, score := range a {
+		if b[team] != score {
+			return false
+		}
+	}
+	return true
+}
+
+// respawned reports whether an actor jumped further than a single move
+// between two ticks, which only happens when it got killed.
+func respawned(before Coordinates, after Coordinates) bool {
+	return distance(before, after) > 1
+}
+
+func actor_event(tick int, kind string, actor Actor, flag string) GameEvent {
+	return GameEvent{Tick: tick, Kind: kind, Team: actor.Team, Actor: actor.Ident, Flag: flag}
+}
+
+// enemy_events infers the grabs and captures of an enemy actor.
+func enemy_events(tick int, before Actor, after Actor, previous GameState, current GameState) []GameEvent {
+	events := make([]GameEvent, 0)
+	if respawned(before.Coordinates, after.Coordinates) {
+		return events
+	}
+	if before.Flag == "" && after.Flag != "" {
+		kind := "grab"
+		if after.Flag == Team {
+			kind = "steal"
+		}
+		events = append(events, actor_event(tick, kind, after, after.Flag))
+	}
+	if before.Flag != "" && after.Flag == "" && flag_returned(previous, current, before.Flag) {
+		events = append(events, actor_event(tick, "capture", after, before.Flag))
+	}
+	return events
+}
+
+// infer_events reconstructs grabs, captures, kills and deaths from the
+// transition between two consecutive states and the orders we sent in
+// between, for servers that publish no event log.
+func infer_events(previous GameState, current GameState, orders []Order) []GameEvent {
+	events := make([]GameEvent, 0)
+	for _, before := range filter_objects(previous.Actors, false) {
+		if after, ok := find_actor(current.Actors, before.Team, before.Ident); ok {
+			events = append(events, enemy_events(current.Tick, before, after, previous, current)...)
+		}
+	}
+	for _, before := range filter_objects(previous.Actors, true) {
+		after, ok := find_actor(current.Actors, before.Team, before.Ident)
+		if !ok {
+			continue
+		}
+		died := respawned(before.Coordinates, after.Coordinates)
+		if died {
+			events = append(events, actor_event(current.Tick, "death", after, ""))
+		}
+		if before.Flag == "" && after.Flag != "" && after.Flag != Team {
+			events = append(events, actor_event(current.Tick, "grab", after, after.Flag))
+		}
+		if before.Flag != "" && before.Flag != Team && after.Flag == "" && !died && flag_returned(previous, current, before.Flag) {
+			events = append(events, actor_event(current.Tick, "capture", after, before.Flag))
+		}
+	}
+	for _, order := range orders {
+		if order.order_type != "attack" {
+			continue
+		}
+		attacker, ok := find_actor(previous.Actors, Team, order.actor)
+		if !ok {
+			continue
+		}
+		target := predicted_position(attacker.Coordinates, order.direction)
+		for _, enemy := range filter_objects(previous.Actors, false) {
+			if enemy.Coordinates != target {
+				continue
+			}
+			after, ok := find_actor(current.Actors, enemy.Team, enemy.Ident)
+			if ok && respawned(enemy.Coordinates, after.Coordinates) {
+				target := fmt.Sprintf("%s actor %d", enemy.Team, enemy.Ident)
+				events = append(events, GameEvent{Tick: current.Tick, Kind: "kill", Team: Team, Actor: attacker.Ident, Target: target})
+			}
+		}
+	}
+	return events
+}
+
+// record counts an event of our team and keeps it for the report.
+func (s *GameStats) record(e GameEvent) {
+	s.Events = append(s.Events, e)
+	if e.Team != Team {
+		return
+	}
+	switch e.Kind {
+	case "grab":
+		s.Grabs++
+		s.actor(e.Actor).Grabs++
+	case "capture":
+		s.Captures++
+		s.actor(e.Actor).Captures++
+	case "kill":
+		s.Kills++
+		s.actor(e.Actor).Kills++
+	case "death":
+		s.Deaths++
+		s.actor(e.Actor).Deaths++
+	}
+}
+
+// Observe accounts for the transition between two consecutive states. The
+// events are those the server reported for the tick, when it reports none
+// they are inferred from the states and the orders we sent in between.
+func (s *GameStats) Observe(previous GameState, current GameState, orders []Order, events []GameEvent) {
+	s.Ticks++
+	s.FinalScores = current.Scores
+	if len(s.Timeline) == 0 || !same_scores(s.Timeline[len(s.Timeline)-1].Scores, current.Scores) {
+		s.Timeline = append(s.Timeline, ScorePoint{current.Tick, current.Scores})
+	}
+	for _, before := range filter_objects(previous.Actors, true) {
+		after, ok := find_actor(current.Actors, before.Team, before.Ident)
+		if !ok {
+			continue
+		}
+		actor := s.actor(before.Ident)
+		actor.Type = after.Type
+		if !respawned(before.Coordinates, after.Coordinates) {
+			actor.Distance += distance(before.Coordinates, after.Coordinates)
+		}
+	}
+	if events == nil {
+		events = infer_events(previous, current, orders)
+	}
+	for _, e := range events {
+		s.record(e)
+	}
+}
+
+func (s *GameStats) RecordDecision(d time.Duration) {
+	s.decisions++
+	s.decision_time += d
+	s.decision_times = append(s.decision_times, d)
+	s.AverageDecisionMs = float64(s.decision_time) / float64(s.decisions) / float64(time.Millisecond)
+}
+
+func (s *GameStats) RecordOrders(submitted []Order, rejected []Order) {
+	s.SubmittedOrders += len(submitted)
+	s.RejectedOrders += len(rejected)
+	for _, order := range submitted {
+		s.actor(order.actor).SubmittedOrders++
+	}
+	for _, order := range rejected {
+		s.actor(order.actor).RejectedOrders++
+	}
+}
+
+// ActorIdents lists the idents of the actors with stats in order.
+func (s *GameStats) ActorIdents() []int {
+	idents := make([]int, 0, len(s.Actors))
+	for ident := range s.Actors {
+		idents = append(idents, ident)
+	}
+	sort.Ints(idents)
+	return idents
+}
+
+var stats_csv_header = []string{
+	"team", "strategy", "started", "ended", "ticks", "grabs", "captures", "kills", "deaths",
+	"submitted_orders", "rejected_orders", "average_decision_ms", "final_score", "missed_ticks",
+}
+
+func (s *GameStats) csv_record() []string {
+	return []string{
+		s.Team,
+		s.Strategy,
+		s.Started.Format(time.RFC3339),
+		s.Ended.Format(time.RFC3339),
+		strconv.Itoa(s.Ticks),
+		strconv.Itoa(s.Grabs),
+		strconv.Itoa(s.Captures),
+		strconv.Itoa(s.Kills),
+		strconv.Itoa(s.Deaths),
+		strconv.Itoa(s.SubmittedOrders),
+		strconv.Itoa(s.RejectedOrders),
+		strconv.FormatFloat(s.AverageDecisionMs, 'f', 3, 64),
+		strconv.Itoa(s.FinalScores[s.Team]),
+		strconv.Itoa(s.MissedTicks),
+	}
+}
+
+func write_stats_json(s *GameStats, dir string) error {
+	name := fmt.Sprintf("stats_%s.json", s.Ended.Format("20060102_150405"))
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0644)
+}
+
+// write_stats_csv appends one row per game to stats.csv so a whole
+// session ends up in a single spreadsheet.
+func write_stats_csv(s *GameStats, dir string) error {
+	path := filepath.Join(dir, "stats.csv")
+	if err := set_aside_csv(path, stats_csv_header); err != nil {
+		return err
+	}
+	_, err := os.Stat(path)
+	is_new := os.IsNotExist(err)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if is_new {
+		writer.Write(stats_csv_header)
+	}
+	writer.Write(s.csv_record())
+	writer.Flush()
+	return writer.Error()
+}
+
+// set_aside_csv moves a file at path whose header is not header out of the
+// way, to a name with its modification time, so the rows appended after a
+// change of the columns start a new file instead of mixing with the old.
+func set_aside_csv(path string, header []string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	existing, err := csv.NewReader(file).Read()
+	info, stat_err := file.Stat()
+	file.Close()
+	if err == io.EOF || (err == nil && strings.Join(existing, ",") == strings.Join(header, ",")) {
+		return nil
+	}
+	if stat_err != nil {
+		return stat_err
+	}
+	aside := strings.TrimSuffix(path, ".csv") + "_" + info.ModTime().Format("20060102_150405") + ".csv"
+	log.Printf("%s has other columns than this version writes, moving it to %s", path, aside)
+	return os.Rename(path, aside)
+}
+
+var actor_stats_csv_header = []string{
+	"team", "strategy", "ended", "actor", "type", "grabs", "captures", "kills", "deaths",
+	"distance", "submitted_orders", "rejected_orders",
+}
+
+// write_actor_stats_csv appends one row per actor and game to actors.csv.
+func write_actor_stats_csv(s *GameStats, dir string) error {
+	path := filepath.Join(dir, "actors.csv")
+	if err := set_aside_csv(path, actor_stats_csv_header); err != nil {
+		return err
+	}
+	_, err := os.Stat(path)
+	is_new := os.IsNotExist(err)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if is_new {
+		writer.Write(actor_stats_csv_header)
+	}
+	for _, ident := range s.ActorIdents() {
+		a := s.Actors[ident]
+		writer.Write([]string{
+			s.Team,
+			s.Strategy,
+			s.Ended.Format(time.RFC3339),
+			strconv.Itoa(ident),
+			a.Type,
+			strconv.Itoa(a.Grabs),
+			strconv.Itoa(a.Captures),
+			strconv.Itoa(a.Kills),
+			strconv.Itoa(a.Deaths),
+			strconv.Itoa(a.Distance),
+			strconv.Itoa(a.SubmittedOrders),
+			strconv.Itoa(a.RejectedOrders),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func write_stats(s *GameStats, dir string, formats string) error {
+	if dir == "" {
+		return nil
+	}
+	s.Ended = time.Now()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, format := range strings.Split(formats, ",") {
+		var err error
+		switch strings.TrimSpace(format) {
+		case "json":
+			err = write_stats_json(s, dir)
+		case "csv":
+			err = write_stats_csv(s, dir)
+			if err == nil {
+				err = write_actor_stats_csv(s, dir)
+			}
+		case "":
+		default:
+			err = fmt.Errorf("unknown stats format %q", format)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
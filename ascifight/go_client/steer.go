@@ -0,0 +1,57 @@
+package main
+
+// threat_cells are the cells next to an enemy able to attack, where an actor
+// can be attacked in the next tick.
+func threat_cells(state GameState) map[Coordinates]bool {
+	threats := make(map[Coordinates]bool)
+	for _, enemy := range filter_objects(state.Actors, false) {
+		if capabilities(enemy).Attack == 0 {
+			continue
+		}
+		for _, direction := range plan_directions {
+			threats[predicted_position(enemy.Coordinates, direction)] = true
+		}
+	}
+	return threats
+}
+
+// Steer checks the move of a flag carrier on its way next to target. A move
+// that ends next to an enemy attacker is swapped for an equally short one
+// that does not. Without one the carrier stalls while it stands safe, which
+// Steer reports as false, and walks on when it is in reach already anyway or
+// reckless.
+func (p *Planner) Steer(actor Actor, target Coordinates, direction string) (string, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	next := predicted_position(actor.Coordinates, direction)
+	if actor.Flag == "" || p.obstacles == nil || !p.threats[next] {
+		return direction, true
+	}
+	remaining := PathMemo.base_distances(p.size, target, p.walls)
+	left := func(c Coordinates) int {
+		if !p.inside(c) || remaining[c.Y][c.X] < 0 {
+			return dstar_infinity
+		}
+		return remaining[c.Y][c.X]
+	}
+	for _, alternative := range plan_directions {
+		c := predicted_position(actor.Coordinates, alternative)
+		if alternative == direction || !p.inside(c) || p.obstacles[c] || p.threats[c] || left(c) > left(next) {
+			continue
+		}
+		logf(VerbosityDebug, "flag carrier %d steers %s instead of %s next to an enemy attacker", actor.Ident, alternative, direction)
+		p.queues[actor.Ident] = &PlanQueue{Target: target, Heading: alternative, Position: c}
+		return alternative, true
+	}
+	if p.threats[actor.Coordinates] || caution() == 0 {
+		// a reckless carrier would rather take the hit than the time
+		return direction, true
+	}
+	logf(VerbosityDebug, "flag carrier %d waits instead of moving %s next to an enemy attacker", actor.Ident, direction)
+	// the path stays planned for when the way is clear
+	if q, ok := p.queues[actor.Ident]; ok && q.Position == next {
+		q.Steps = append([]string{direction}, q.Steps...)
+		q.Position = actor.Coordinates
+	}
+	return direction, false
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const stepper_help = "enter submit and step, c submit and continue, s skip this tick's orders, b board, q quit"
+
+// Stepper pauses the tick loop after orders have been computed so they can
+// be inspected before submission. While running, a line containing p on
+// stdin pauses the loop again at the next tick.
+type Stepper struct {
+	paused bool
+	lines  chan string
+	out    io.Writer
+}
+
+func new_stepper(in io.Reader, out io.Writer, paused bool) *Stepper {
+	s := &Stepper{paused: paused, lines: make(chan string), out: out}
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			s.lines <- strings.TrimSpace(scanner.Text())
+		}
+		close(s.lines)
+	}()
+	return s
+}
+
+func (s *Stepper) poll() {
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				s.paused = false
+				s.lines = nil
+				return
+			}
+			if line == "p" {
+				s.paused = true
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *Stepper) show(state GameState, orders []Order) {
+	fmt.Fprintf(s.out, "paused at tick %d, %d orders:\n", state.Tick, len(orders))
+	targets := Plans.Targets()
+	queued := Plans.Queued()
+	for _, order := range orders {
+		if actor, ok := find_actor(state.Actors, Team, order.actor); ok {
+			fmt.Fprintf(s.out, "  %s %d at (%d,%d): %s\n", actor.Type, actor.Ident, actor.Coordinates.X, actor.Coordinates.Y, order)
+			// only actors the planner moved this tick have a target
+			if target, ok := targets[actor.Ident]; ok {
+				plan := fmt.Sprintf("    target (%d,%d)", target.X, target.Y)
+				if steps := queued[actor.Ident]; len(steps) > 0 {
+					plan += ", queued " + strings.Join(steps, " ")
+				}
+				fmt.Fprintln(s.out, plan)
+			}
+		} else {
+			fmt.Fprintf(s.out, "  %s\n", order)
+		}
+	}
+}
+
+// Review decides whether the orders computed for state are submitted. It
+// returns immediately while the loop is running and otherwise blocks until
+// the user has made a choice. quit is set when the user wants the bot to
+// stop, which the loop does after writing what it has of the game.
+func (s *Stepper) Review(state GameState, orders []Order) (submit bool, quit bool) {
+	s.poll()
+	if !s.paused {
+		return true, false
+	}
+	s.show(state, orders)
+	submit, quit = s.decide(state)
+	if t, err := DefaultClient.Timing(context.Background()); err == nil && t.Tick != state.Tick && !quit {
+		fmt.Fprintf(s.out, "warning: orders were computed for tick %d, the server is at tick %d\n", state.Tick, t.Tick)
+	}
+	return submit, quit
+}
+
+// decide waits for the user's choice. The server keeps ticking meanwhile, so
+// it polls the timing and tells once when the orders became stale; b shows
+// the board as the server has it now.
+func (s *Stepper) decide(state GameState) (bool, bool) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	stale := false
+	fmt.Fprintf(s.out, "[%s] > ", stepper_help)
+	for {
+		var line string
+		select {
+		case <-ticker.C:
+			if t, err := DefaultClient.Timing(context.Background()); err == nil && t.Tick != state.Tick && !stale {
+				stale = true
+				fmt.Fprintf(s.out, "\nthe server moved on to tick %d\n[%s] > ", t.Tick, stepper_help)
+			}
+			continue
+		case next, ok := <-s.lines:
+			if !ok {
+				s.paused = false
+				return true, false
+			}
+			line = next
+		}
+		switch line {
+		case "":
+			return true, false
+		case "c":
+			s.paused = false
+			return true, false
+		case "s":
+			return false, false
+		case "b":
+			current, err := DefaultClient.GameState(context.Background())
+			if err != nil {
+				fmt.Fprintf(s.out, "fetching the board failed, showing tick %d: %v\n", state.Tick, err)
+				current = state
+			}
+			fmt.Fprint(s.out, render_board(current, 0))
+		case "q":
+			return false, true
+		}
+		fmt.Fprintf(s.out, "[%s] > ", stepper_help)
+	}
+}
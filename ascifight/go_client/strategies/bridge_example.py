@@ -0,0 +1,50 @@
+"""Example external strategy for the client's bridge mode.
+
+Run the client with -bridge "python3 strategies/bridge_example.py". Every
+tick a JSON-RPC request arrives on stdin and the orders go back on stdout.
+"""
+import json
+import sys
+
+
+def direction(origin, target):
+    dx = target["x"] - origin["x"]
+    dy = target["y"] - origin["y"]
+    if abs(dx) > abs(dy):
+        return "right" if dx > 0 else "left"
+    return "up" if dy > 0 else "down"
+
+
+def distance(origin, target):
+    return abs(target["x"] - origin["x"]) + abs(target["y"] - origin["y"])
+
+
+def generate_orders(team, state):
+    orders = []
+    base = next(b for b in state["bases"] if b["team"] == team)
+    enemy_flags = [f for f in state["flags"] if f["team"] != team]
+    for actor in state["actors"]:
+        if actor["team"] != team:
+            continue
+        if actor["flag"]:
+            target = base["coordinates"]
+        elif enemy_flags:
+            target = min(enemy_flags, key=lambda f: distance(actor["coordinates"], f["coordinates"]))["coordinates"]
+        else:
+            continue
+        order_type = "grabput" if distance(actor["coordinates"], target) == 1 else "move"
+        orders.append(
+            {"type": order_type, "actor": actor["ident"], "direction": direction(actor["coordinates"], target)}
+        )
+    return orders
+
+
+for line in sys.stdin:
+    request = json.loads(line)
+    response = {"jsonrpc": "2.0", "id": request["id"]}
+    if request["method"] == "generate_orders":
+        params = request["params"]
+        response["result"] = generate_orders(params["team"], params["state"])
+    else:
+        response["error"] = {"code": -32601, "message": "Method not found"}
+    print(json.dumps(response), flush=True)
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Strategy turns a game state into the orders for our actors.
+type Strategy interface {
+	Name() string
+	GenerateOrders(state GameState) []Order
+}
+
+// ContextStrategy is a Strategy that can cut its work short once ctx is
+// done, typically because the tick deadline is near.
+type ContextStrategy interface {
+	Strategy
+	GenerateOrdersContext(ctx context.Context, state GameState) []Order
+}
+
+type FuncStrategy struct {
+	name     string
+	generate func(state GameState) []Order
+}
+
+func (s FuncStrategy) Name() string {
+	return s.name
+}
+
+func (s FuncStrategy) GenerateOrders(state GameState) []Order {
+	return s.generate(state)
+}
+
+// ContextFuncStrategy is a FuncStrategy whose function stops at the
+// deadline with the best orders found so far.
+type ContextFuncStrategy struct {
+	name     string
+	generate func(ctx context.Context, state GameState) []Order
+}
+
+func (s ContextFuncStrategy) Name() string {
+	return s.name
+}
+
+func (s ContextFuncStrategy) GenerateOrders(state GameState) []Order {
+	return s.generate(context.Background(), state)
+}
+
+func (s ContextFuncStrategy) GenerateOrdersContext(ctx context.Context, state GameState) []Order {
+	return s.generate(ctx, state)
+}
+
+// CoordinatedStrategy times the attacks of a built-in strategy into pincers
+// and keeps its movers in formation. Both go by what the strategy told the
+// pincer board and the planner this tick, which script, plugin and bridge
+// strategies never do, so their orders are left as they are.
+type CoordinatedStrategy struct {
+	Strategy
+}
+
+func (s CoordinatedStrategy) GenerateOrders(state GameState) []Order {
+	return s.GenerateOrdersContext(context.Background(), state)
+}
+
+func (s CoordinatedStrategy) GenerateOrdersContext(ctx context.Context, state GameState) []Order {
+	Pincers.Observe(state)
+	var orders []Order
+	if inner, ok := s.Strategy.(ContextStrategy); ok {
+		orders = inner.GenerateOrdersContext(ctx, state)
+	} else {
+		orders = s.Strategy.GenerateOrders(state)
+	}
+	return Formations.Arrange(state, Pincers.Synchronize(state, orders))
+}
+
+var strategies = map[string]Strategy{
+	"greedy":  CoordinatedStrategy{FuncStrategy{"greedy", generate_orders}},
+	"utility": CoordinatedStrategy{ContextFuncStrategy{"utility", generate_utility_orders_context}},
+	"actors":  CoordinatedStrategy{new_coordinator("actors", bait_options(utility_options))},
+}
+
+func strategy_names() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup_strategy resolves a built-in strategy by name. Names of the form
+// script:<path>, plugin:<path> or bridge:<command or socket> load a strategy
+// script, a plugin or connect to an external strategy process instead.
+func lookup_strategy(name string) (Strategy, error) {
+	if path, ok := strings.CutPrefix(name, "script:"); ok {
+		return new_script_strategy(path)
+	}
+	if path, ok := strings.CutPrefix(name, "plugin:"); ok {
+		return load_strategy_plugin(path)
+	}
+	if spec, ok := strings.CutPrefix(name, "bridge:"); ok {
+		return new_bridge_strategy(spec)
+	}
+	strategy, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q, available: %s", name, strings.Join(strategy_names(), ", "))
+	}
+	return strategy, nil
+}
+
+func lookup_strategies(names string) ([]Strategy, error) {
+	result := make([]Strategy, 0)
+	for _, name := range strings.Split(names, ",") {
+		strategy, err := lookup_strategy(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, strategy)
+	}
+	return result, nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import "sync"
+
+// Territory partitions the board by which team's actors reach each cell
+// first, walking around walls and bases.
+type Territory struct {
+	Size int
+	// Distance holds the steps the nearest actor of each team needs per
+	// cell, -1 where it cannot get.
+	Distance map[string][][]int
+	// Owner is the team that reaches a cell first, empty for contested and
+	// unreachable cells.
+	Owner [][]string
+	Cells map[string]int
+	// Contested cells are reached by several teams at the same time.
+	Contested []Coordinates
+	// ChokePoints are the cells that split the walkable board when blocked.
+	ChokePoints []Coordinates
+	passable    [][]bool
+}
+
+func analyze_territory(state GameState, size int) *Territory {
+	size = board_size(state, size)
+	t := &Territory{Size: size, Distance: make(map[string][][]int), Cells: make(map[string]int)}
+	t.passable = make([][]bool, size)
+	for y := range t.passable {
+		t.passable[y] = make([]bool, size)
+		for x := range t.passable[y] {
+			t.passable[y][x] = true
+		}
+	}
+	block := func(c Coordinates) {
+		if t.inside(c) {
+			t.passable[c.Y][c.X] = false
+		}
+	}
+	for _, wall := range state.Walls {
+		block(wall)
+	}
+	for _, base := range state.Bases {
+		block(base.Coordinates)
+	}
+	for _, team := range state.Teams {
+		sources := make([]Coordinates, 0)
+		for _, actor := range state.Actors {
+			if actor.Team == team && t.inside(actor.Coordinates) {
+				sources = append(sources, actor.Coordinates)
+			}
+		}
+		t.Distance[team] = t.flood(sources)
+	}
+	t.Owner = make([][]string, size)
+	for y := 0; y < size; y++ {
+		t.Owner[y] = make([]string, size)
+		for x := 0; x < size; x++ {
+			best, owner, tied := -1, "", false
+			for _, team := range state.Teams {
+				d := t.Distance[team][y][x]
+				switch {
+				case d < 0:
+				case best < 0 || d < best:
+					best, owner, tied = d, team, false
+				case d == best:
+					tied = true
+				}
+			}
+			if tied {
+				t.Contested = append(t.Contested, Coordinates{X: x, Y: y})
+				owner = ""
+			}
+			t.Owner[y][x] = owner
+			if owner != "" {
+				t.Cells[owner]++
+			}
+		}
+	}
+	t.ChokePoints = t.articulation_points()
+	return t
+}
+
+func (t *Territory) inside(c Coordinates) bool {
+	return c.X >= 0 && c.Y >= 0 && c.X < t.Size && c.Y < t.Size
+}
+
+func (t *Territory) walkable(c Coordinates) bool {
+	return t.inside(c) && t.passable[c.Y][c.X]
+}
+
+// flood is a breadth first search from all sources at once.
+func (t *Territory) flood(sources []Coordinates) [][]int {
+	distance := make([][]int, t.Size)
+	for y := range distance {
+		distance[y] = make([]int, t.Size)
+		for x := range distance[y] {
+			distance[y][x] = -1
+		}
+	}
+	frontier := make([]Coordinates, 0, len(sources))
+	for _, source := range sources {
+		distance[source.Y][source.X] = 0
+		frontier = append(frontier, source)
+	}
+	for len(frontier) > 0 {
+		current := frontier[0]
+		frontier = frontier[1:]
+		for _, direction := range plan_directions {
+			next := predicted_position(current, direction)
+			if !t.walkable(next) || distance[next.Y][next.X] >= 0 {
+				continue
+			}
+			distance[next.Y][next.X] = distance[current.Y][current.X] + 1
+			frontier = append(frontier, next)
+		}
+	}
+	return distance
+}
+
+// articulation_points finds the cut vertices of the walkable grid with
+// Tarjan's depth first search.
+func (t *Territory) articulation_points() []Coordinates {
+	index := make(map[Coordinates]int)
+	low := make(map[Coordinates]int)
+	cut := make(map[Coordinates]bool)
+	counter := 0
+	var visit func(c Coordinates, parent Coordinates, root bool)
+	visit = func(c Coordinates, parent Coordinates, root bool) {
+		counter++
+		index[c], low[c] = counter, counter
+		children := 0
+		for _, direction := range plan_directions {
+			next := predicted_position(c, direction)
+			if !t.walkable(next) || (!root && next == parent) {
+				continue
+			}
+			if _, seen := index[next]; seen {
+				low[c] = min_int(low[c], index[next])
+				continue
+			}
+			children++
+			visit(next, c, false)
+			low[c] = min_int(low[c], low[next])
+			if !root && low[next] >= index[c] {
+				cut[c] = true
+			}
+		}
+		if root && children > 1 {
+			cut[c] = true
+		}
+	}
+	points := make([]Coordinates, 0)
+	for y := 0; y < t.Size; y++ {
+		for x := 0; x < t.Size; x++ {
+			c := Coordinates{X: x, Y: y}
+			if _, seen := index[c]; !seen && t.walkable(c) {
+				visit(c, c, true)
+			}
+		}
+	}
+	for y := 0; y < t.Size; y++ {
+		for x := 0; x < t.Size; x++ {
+			if c := (Coordinates{X: x, Y: y}); cut[c] {
+				points = append(points, c)
+			}
+		}
+	}
+	return points
+}
+
+// Lead is by how many steps team reaches c before the fastest other team,
+// negative when others are faster. Cells no other team can reach count as
+// a lead of Size.
+func (t *Territory) Lead(team string, c Coordinates) int {
+	if !t.inside(c) || t.Distance[team] == nil || t.Distance[team][c.Y][c.X] < 0 {
+		return -t.Size
+	}
+	own := t.Distance[team][c.Y][c.X]
+	enemy := -1
+	for other, distance := range t.Distance {
+		if d := distance[c.Y][c.X]; other != team && d >= 0 && (enemy < 0 || d < enemy) {
+			enemy = d
+		}
+	}
+	if enemy < 0 {
+		return t.Size
+	}
+	return enemy - own
+}
+
+// Staging picks the walkable cell closest to target that team holds with
+// a lead of at least margin steps and that is no choke point, so an actor
+// waiting there can neither be cut off nor reached first.
+func (t *Territory) Staging(team string, target Coordinates, margin int) (Coordinates, bool) {
+	chokes := make(map[Coordinates]bool, len(t.ChokePoints))
+	for _, c := range t.ChokePoints {
+		chokes[c] = true
+	}
+	best, found := Coordinates{}, false
+	for y := 0; y < t.Size; y++ {
+		for x := 0; x < t.Size; x++ {
+			c := Coordinates{X: x, Y: y}
+			if !t.walkable(c) || chokes[c] || t.Lead(team, c) < margin {
+				continue
+			}
+			if !found || distance(c, target) < distance(best, target) {
+				best, found = c, true
+			}
+		}
+	}
+	return best, found
+}
+
+var territory_cache struct {
+	mutex sync.Mutex
+	key   string
+	value *Territory
+}
+
+// current_territory analyzes a state once, however many actors ask.
+func current_territory(state GameState) *Territory {
+	territory_cache.mutex.Lock()
+	defer territory_cache.mutex.Unlock()
+	key := state.TimeOfNextExecution
+	if territory_cache.value == nil || territory_cache.key != key || key == "" {
+		territory_cache.value = analyze_territory(state, GameRules.MapSize())
+		territory_cache.key = key
+	}
+	return territory_cache.value
+}
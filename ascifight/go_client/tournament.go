@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+type StrategyReport struct {
+	Strategy          string  `json:"strategy"`
+	Games             int     `json:"games"`
+	Wins              int     `json:"wins"`
+	Draws             int     `json:"draws"`
+	Losses            int     `json:"losses"`
+	AverageScore      float64 `json:"average_score"`
+	AverageCaptures   float64 `json:"average_captures"`
+	AverageKills      float64 `json:"average_kills"`
+	AverageDeaths     float64 `json:"average_deaths"`
+	AverageDecisionMs float64 `json:"average_decision_ms"`
+}
+
+type TournamentReport struct {
+	Started    time.Time        `json:"started"`
+	Ended      time.Time        `json:"ended"`
+	Games      []*GameStats     `json:"games"`
+	Strategies []StrategyReport `json:"strategies"`
+}
+
+func build_tournament_report(games []*GameStats) []StrategyReport {
+	reports := make(map[string]*StrategyReport)
+	order := make([]string, 0)
+	for _, game := range games {
+		report, ok := reports[game.Strategy]
+		if !ok {
+			report = &StrategyReport{Strategy: game.Strategy}
+			reports[game.Strategy] = report
+			order = append(order, game.Strategy)
+		}
+		report.Games++
+		switch game_outcome(game.FinalScores, game.Team) {
+		case "win":
+			report.Wins++
+		case "draw":
+			report.Draws++
+		default:
+			report.Losses++
+		}
+		report.AverageScore += float64(game.FinalScores[game.Team])
+		report.AverageCaptures += float64(game.Captures)
+		report.AverageKills += float64(game.Kills)
+		report.AverageDeaths += float64(game.Deaths)
+		report.AverageDecisionMs += game.AverageDecisionMs
+	}
+	result := make([]StrategyReport, 0, len(order))
+	for _, name := range order {
+		report := reports[name]
+		n := float64(report.Games)
+		report.AverageScore /= n
+		report.AverageCaptures /= n
+		report.AverageKills /= n
+		report.AverageDeaths /= n
+		report.AverageDecisionMs /= n
+		result = append(result, *report)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return percentage(result[i].Wins, result[i].Games) > percentage(result[j].Wins, result[j].Games)
+	})
+	return result
+}
+
+func print_tournament_report(report TournamentReport) {
+	fmt.Printf("%d games from %s to %s\n\n", len(report.Games),
+		report.Started.Format(time.RFC3339), report.Ended.Format(time.RFC3339))
+	fmt.Printf("%-20s %5s %5s %5s %5s %8s %7s %8s %7s %7s %9s\n",
+		"strategy", "games", "wins", "draws", "loss", "winrate", "score", "captures", "kills", "deaths", "decide ms")
+	for _, s := range report.Strategies {
+		fmt.Printf("%-20s %5d %5d %5d %5d %7.1f%% %7.2f %8.2f %7.2f %7.2f %9.3f\n",
+			s.Strategy, s.Games, s.Wins, s.Draws, s.Losses, percentage(s.Wins, s.Games),
+			s.AverageScore, s.AverageCaptures, s.AverageKills, s.AverageDeaths, s.AverageDecisionMs)
+	}
+}
+
+func write_tournament_report(report TournamentReport, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return write_json_report(report, dir, "tournament", report.Ended)
+}
+
+// tournament_command plays a number of complete games in a row. A game we
+// joined after it had started is played but not counted, so every counted
+// game covers the full length of the match.
+func tournament_command(args []string) {
+	flags := flag.NewFlagSet("tournament", flag.ExitOnError)
+	games := flags.Int("games", 10, "number of complete games to play")
+	strategy_list := flags.String("strategies", "greedy", "comma separated strategies, rotated after every game")
+	stats_dir := flags.String("stats-dir", "stats", "directory for per-game stats, the results ledger and the tournament report")
+	stats_format := flags.String("stats-format", "json,csv", "comma separated list of stats formats (json, csv)")
+	seed := add_seed_flag(flags)
+	add_connection_flags(flags)
+	config_flags := add_config_flags(flags)
+	flags.Parse(args)
+	Random.Seed(*seed)
+	logf(VerbosityNormal, "random seed %d", Random.Current())
+	if err := config_flags.Apply(); err != nil {
+		log.Fatalln(err)
+	}
+	normalize_server_url()
+	if err := resolve_password(); err != nil {
+		log.Fatalln(err)
+	}
+
+	rotation, err := lookup_strategies(*strategy_list)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	report := TournamentReport{Started: time.Now(), Games: make([]*GameStats, 0)}
+	next := 0
+	options := BotOptions{StatsDir: *stats_dir, StatsFormat: *stats_format}
+	play(rotation[next], options, func(stats *GameStats) (Strategy, bool) {
+		if stats.Partial() {
+			log.Printf("game joined at tick %d, not counted", stats.FirstTick)
+			return rotation[next], true
+		}
+		report.Games = append(report.Games, stats)
+		log.Printf("tournament game %d/%d finished: %s", len(report.Games), *games, game_outcome(stats.FinalScores, stats.Team))
+		if len(report.Games) >= *games {
+			return nil, false
+		}
+		next = (next + 1) % len(rotation)
+		return rotation[next], true
+	})
+	report.Ended = time.Now()
+	report.Strategies = build_tournament_report(report.Games)
+	print_tournament_report(report)
+	if err := write_tournament_report(report, *stats_dir); err != nil {
+		log.Printf("writing tournament report failed: %v", err)
+	}
+}
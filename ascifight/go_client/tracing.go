@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracer records the tick pipeline as spans and exports them to an OTLP/HTTP
+// collector using the JSON encoding. A nil Tracer and the nil Spans it
+// returns are valid and record nothing, so the tick loop can trace
+// unconditionally.
+type Tracer struct {
+	endpoint string
+	service  string
+	client   *http.Client
+	mutex    sync.Mutex
+	finished []*Span
+}
+
+type Span struct {
+	tracer     *Tracer
+	trace_id   string
+	span_id    string
+	parent_id  string
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+}
+
+func random_id(bytes int) string {
+	id := make([]byte, bytes)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func new_tracer(endpoint string, service string) *Tracer {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+	return &Tracer{endpoint: endpoint, service: service, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Start opens a root span, or a child span when parent is given.
+func (t *Tracer) Start(name string, parent *Span) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{tracer: t, span_id: random_id(8), name: name, start: time.Now(), attributes: make(map[string]interface{})}
+	if parent != nil {
+		s.trace_id = parent.trace_id
+		s.parent_id = parent.span_id
+	} else {
+		s.trace_id = random_id(16)
+	}
+	return s
+}
+
+// StartedAt moves the start of the span back to when its work began.
+func (s *Span) StartedAt(start time.Time) {
+	if s != nil {
+		s.start = start
+	}
+}
+
+func (s *Span) Set(key string, value interface{}) {
+	if s != nil {
+		s.attributes[key] = value
+	}
+}
+
+// End finishes the span. Ending a root span exports it together with all
+// finished spans of the same trace.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	t := s.tracer
+	t.mutex.Lock()
+	t.finished = append(t.finished, s)
+	var batch []*Span
+	if s.parent_id == "" {
+		batch = t.finished
+		t.finished = nil
+	}
+	t.mutex.Unlock()
+	if batch != nil {
+		go t.export(batch)
+	}
+}
+
+func otlp_value(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case int:
+		return map[string]interface{}{"intValue": strconv.Itoa(v)}
+	case float64:
+		return map[string]interface{}{"doubleValue": v}
+	case bool:
+		return map[string]interface{}{"boolValue": v}
+	case string:
+		return map[string]interface{}{"stringValue": v}
+	default:
+		b, _ := json.Marshal(v)
+		return map[string]interface{}{"stringValue": string(b)}
+	}
+}
+
+func otlp_attributes(attributes map[string]interface{}) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(attributes))
+	for key, value := range attributes {
+		result = append(result, map[string]interface{}{"key": key, "value": otlp_value(value)})
+	}
+	return result
+}
+
+func (t *Tracer) export(batch []*Span) {
+	spans := make([]map[string]interface{}, 0, len(batch))
+	for _, s := range batch {
+		span := map[string]interface{}{
+			"traceId":           s.trace_id,
+			"spanId":            s.span_id,
+			"name":              s.name,
+			"kind":              1,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        otlp_attributes(s.attributes),
+		}
+		if s.parent_id != "" {
+			span["parentSpanId"] = s.parent_id
+		}
+		spans = append(spans, span)
+	}
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": otlp_attributes(map[string]interface{}{"service.name": t.service, "ascifight.team": Team}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "ascifight_client"},
+				"spans": spans,
+			}},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("trace export: %v", err)
+		return
+	}
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("trace export: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("trace export: %s", resp.Status)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type TuneCandidate struct {
+	Params  StrategyParams `json:"params"`
+	Fitness float64        `json:"fitness"`
+	Wins    int            `json:"wins"`
+	Draws   int            `json:"draws"`
+	Losses  int            `json:"losses"`
+}
+
+type TuneReport struct {
+	Started    time.Time       `json:"started"`
+	Ended      time.Time       `json:"ended"`
+	Mode       string          `json:"mode"`
+	Strategy   string          `json:"strategy"`
+	Opponent   string          `json:"opponent"`
+	Candidates []TuneCandidate `json:"candidates"`
+	Best       TuneCandidate   `json:"best"`
+}
+
+type Tuner struct {
+	strategy string
+	opponent ArenaBot
+	team     ArenaBot
+	games    int
+	dir      string
+	active   []bool
+	report   *TuneReport
+}
+
+// Evaluate plays the candidate against the opponent and scores it by the
+// average score margin over the games.
+func (t *Tuner) Evaluate(params StrategyParams) TuneCandidate {
+	candidate := TuneCandidate{Params: params}
+	bot := t.team
+	bot.Strategy = t.strategy
+	bot.Params = params.String()
+	dir := filepath.Join(t.dir, fmt.Sprintf("candidate_%03d", len(t.report.Candidates)))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatalln(err)
+	}
+	log.Printf("evaluating %s", bot.Params)
+	report, err := run_arena_games([]ArenaBot{bot, t.opponent}, t.games, dir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, scores := range report.Games {
+		candidate.Fitness += float64(scores[bot.Team] - scores[t.opponent.Team])
+		switch outcome_against(scores, bot.Team, t.opponent.Team) {
+		case 1:
+			candidate.Wins++
+		case 0:
+			candidate.Losses++
+		default:
+			candidate.Draws++
+		}
+	}
+	if len(report.Games) > 0 {
+		candidate.Fitness /= float64(len(report.Games))
+	}
+	log.Printf("fitness %.2f (%d/%d/%d)", candidate.Fitness, candidate.Wins, candidate.Draws, candidate.Losses)
+	t.report.Candidates = append(t.report.Candidates, candidate)
+	if len(t.report.Candidates) == 1 || candidate.Fitness > t.report.Best.Fitness {
+		t.report.Best = candidate
+	}
+	return candidate
+}
+
+// parse_active_params marks the parameters the tuner may change. All others
+// stay at the value of the starting configuration.
+func parse_active_params(names string) ([]bool, error) {
+	active := make([]bool, len(param_specs))
+	if names == "" {
+		for i := range active {
+			active[i] = true
+		}
+		return active, nil
+	}
+	for _, name := range strings.Split(names, ",") {
+		found := false
+		for i, spec := range param_specs {
+			if spec.Name == strings.TrimSpace(name) {
+				active[i] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown parameter %q, available: %s", name, strings.Join(param_names(), ", "))
+		}
+	}
+	return active, nil
+}
+
+func (t *Tuner) grid_points(index int, current []float64, visit func(vector []float64)) {
+	if index == len(param_specs) {
+		visit(append([]float64(nil), current...))
+		return
+	}
+	values := []float64{current[index]}
+	if t.active[index] {
+		values = param_specs[index].Values()
+	}
+	for _, value := range values {
+		current[index] = value
+		t.grid_points(index+1, current, visit)
+	}
+}
+
+func (t *Tuner) Grid(start StrategyParams) {
+	t.grid_points(0, start.Vector(), func(vector []float64) {
+		t.Evaluate(params_from_vector(vector))
+	})
+}
+
+func (t *Tuner) mutate(rng *rand.Rand, vector []float64, rate float64) []float64 {
+	result := make([]float64, len(vector))
+	for i, spec := range param_specs {
+		result[i] = vector[i]
+		if t.active[i] && rng.Float64() < rate {
+			result[i] += rng.NormFloat64() * (spec.Max - spec.Min) / 4
+		}
+		result[i] = spec.Clamp(result[i])
+	}
+	return result
+}
+
+func crossover(rng *rand.Rand, a []float64, b []float64) []float64 {
+	result := make([]float64, len(a))
+	for i := range a {
+		if rng.Intn(2) == 0 {
+			result[i] = a[i]
+		} else {
+			result[i] = b[i]
+		}
+	}
+	return result
+}
+
+// Evolve runs a simple elitist genetic search around the start parameters.
+func (t *Tuner) Evolve(rng *rand.Rand, start StrategyParams, population int, generations int, rate float64) {
+	current := make([]TuneCandidate, 0, population)
+	current = append(current, t.Evaluate(start))
+	for len(current) < population {
+		current = append(current, t.Evaluate(params_from_vector(t.mutate(rng, start.Vector(), 1))))
+	}
+	for generation := 1; generation < generations; generation++ {
+		sort.SliceStable(current, func(i, j int) bool { return current[i].Fitness > current[j].Fitness })
+		elite := current[:(population+1)/2]
+		next := append([]TuneCandidate(nil), elite...)
+		for len(next) < population {
+			a := elite[rng.Intn(len(elite))].Params.Vector()
+			b := elite[rng.Intn(len(elite))].Params.Vector()
+			next = append(next, t.Evaluate(params_from_vector(t.mutate(rng, crossover(rng, a, b), rate))))
+		}
+		current = next
+		log.Printf("generation %d best fitness %.2f", generation, t.report.Best.Fitness)
+	}
+}
+
+func tune_command(args []string) {
+	flags := flag.NewFlagSet("tune", flag.ExitOnError)
+	server_cmd := flags.String("server-cmd", "uvicorn ascifight.main:app --port 8000", "command that starts the game server")
+	server_dir := flags.String("server-dir", ".", "working directory for the server command")
+	mode := flags.String("mode", "grid", "search mode, grid or evolve")
+	strategy := flags.String("strategy", "greedy", "strategy whose parameters are tuned")
+	team := flags.String("team", "Team 1:1", "team:password of the tuned bot")
+	opponent := flags.String("opponent", "Team 2:2:greedy", "team:password:strategy of the fixed opponent")
+	games := flags.Int("games", 3, "games played per candidate")
+	population := flags.Int("population", 6, "candidates per generation in evolve mode")
+	generations := flags.Int("generations", 5, "generations in evolve mode")
+	rate := flags.Float64("mutation-rate", 0.3, "probability to mutate each parameter in evolve mode")
+	dir := flags.String("dir", "tune", "directory for logs and the tuning report")
+	startup := flags.Duration("startup-timeout", 30*time.Second, "how long to wait for the server to come up")
+	only := flags.String("only", "", "comma separated parameters to search, all if empty")
+	flags.StringVar(&ServerUrl, "server", ServerUrl, "base url the started server listens on")
+	seed := add_seed_flag(flags)
+	config_flags := add_config_flags(flags)
+	flags.Parse(args)
+	Random.Seed(*seed)
+	logf(VerbosityNormal, "random seed %d", Random.Current())
+	if err := config_flags.Apply(); err != nil {
+		log.Fatalln(err)
+	}
+	normalize_server_url()
+	active, err := parse_active_params(*only)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if _, err := lookup_strategy(*strategy); err != nil {
+		log.Fatalln(err)
+	}
+	bots, err := parse_arena_bots(*team + ":" + *strategy + "," + *opponent)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	server, err := start_arena_server(*server_cmd, *server_dir, *dir, *startup)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer server.Stop()
+
+	report := &TuneReport{Started: time.Now(), Mode: *mode, Strategy: *strategy, Opponent: bots[1].Strategy}
+	tuner := &Tuner{strategy: *strategy, team: bots[0], opponent: bots[1], games: *games, dir: *dir, active: active, report: report}
+	switch *mode {
+	case "grid":
+		tuner.Grid(Params)
+	case "evolve":
+		tuner.Evolve(Random.Stream(), Params, *population, *generations, *rate)
+	default:
+		log.Printf("unknown tuning mode %q", *mode)
+		return
+	}
+	report.Ended = time.Now()
+	fmt.Printf("best parameters after %d candidates: %s\n", len(report.Candidates), report.Best.Params)
+	fmt.Printf("fitness %.2f, %d wins, %d draws, %d losses\n", report.Best.Fitness, report.Best.Wins, report.Best.Draws, report.Best.Losses)
+	if err := write_json_report(report, *dir, "tune", report.Ended); err != nil {
+		log.Printf("writing tuning report failed: %v", err)
+	}
+	best := filepath.Join(*dir, "best_"+default_config_file)
+	if err := write_config(best, ClientConfig{Params: report.Best.Params}); err != nil {
+		log.Printf("writing best configuration failed: %v", err)
+	} else {
+		fmt.Printf("configuration written to %s, use it with -config\n", best)
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ActorCapabilities are the probabilities with which an actor type can
+// perform the non-move orders.
+type ActorCapabilities struct {
+	Grab    float64
+	Attack  float64
+	Build   float64
+	Destroy float64
+}
+
+// default_capabilities mirrors the actor types shipped with the server. The
+// rules of the running game take precedence, see CapabilityRegistry.
+var default_capabilities = map[string]ActorCapabilities{
+	"Generalist": {Grab: 1, Attack: 1},
+	"Runner":     {Grab: 1},
+	"Attacker":   {Attack: 1},
+	"Guardian":   {},
+	"Builder":    {Build: 0.2},
+	"Destroyer":  {Destroy: 0.25},
+}
+
+func capabilities(actor Actor) ActorCapabilities {
+	return Capabilities.Get(actor.Type)
+}
+
+// UtilityOption is one thing an actor could do this tick. Options sharing a
+// non-empty Claim are mutually exclusive within a joint assignment.
+type UtilityOption struct {
+	Kind    string
+	Label   string
+	Claim   string
+	Target  Coordinates
+	Action  string
+	Scores  map[string]float64
+	Utility float64
+}
+
+func proximity(a Coordinates, b Coordinates) float64 {
+	return 1 / (1 + float64(distance(a, b)))
+}
+
+func (o *UtilityOption) consider(name string, weight float64, value float64) {
+	o.Scores[name] = weight * value
+	o.Utility += weight * value
+}
+
+func (o UtilityOption) Explain() string {
+	names := make([]string, 0, len(o.Scores))
+	for name := range o.Scores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%.2f", name, o.Scores[name]))
+	}
+	return fmt.Sprintf("%s %.2f (%s)", o.Label, o.Utility, strings.Join(parts, " "))
+}
+
+func new_option(kind string, label string, claim string, target Coordinates, action string) UtilityOption {
+	return UtilityOption{Kind: kind, Label: label, Claim: claim, Target: target, Action: action, Scores: make(map[string]float64)}
+}
+
+// utility_options scores every candidate action of a single actor.
+func utility_options(actor Actor, state GameState, base Base) []UtilityOption {
+	caps := capabilities(actor)
+	options := make([]UtilityOption, 0)
+	if actor.Flag != "" {
+		option := new_option("return", "return flag to base", "", base.Coordinates, "grabput")
+		option.consider("carrying", Params.UtilityReturn, 1)
+		option.consider("proximity", Params.UtilityProximity, proximity(actor.Coordinates, base.Coordinates))
+		return append(options, option)
+	}
+	endgame := current_endgame(state)
+	enemy_bases := filter_objects(state.Bases, false)
+	rivals := assess_rivals(state)
+	camped := make(map[Coordinates]bool)
+	for _, flag := range filter_objects(state.Flags, false) {
+		if caps.Grab == 0 {
+			break
+		}
+		// the run ends at whichever of our bases is closest to the flag
+		home := nearest_base(state, flag.Coordinates)
+		if !endgame.RunFeasible(actor, flag.Coordinates, home.Coordinates) {
+			// the game ends before the flag would be home
+			continue
+		}
+		if other, carried := carrier(state, flag); carried && other.Team == Team {
+			continue
+		}
+		option := new_option("grab", "grab flag of "+flag.Team, flag_claim(flag), flag.Coordinates, "grabput")
+		option.consider("grab", Params.UtilityGrab, caps.Grab)
+		// with three or more teams flags of the leader first
+		if rivals != nil {
+			option.consider("rival", Params.UtilityRival, rival_bias(rivals, flag.Team)*caps.Grab)
+		}
+		if endgame.AllIn {
+			option.consider("endgame", Params.UtilityGrab, caps.Grab)
+		}
+		// weighed by the expected ticks per capture rather than the distance
+		option.consider("expected", Params.UtilityProximity, estimate_capture(state, actor, flag.Coordinates, home.Coordinates).Value())
+		options = append(options, option)
+
+		// a flag away from home comes back to a base of its team without one
+		if at_home(state, flag) {
+			continue
+		}
+		for _, enemy_base := range enemy_bases {
+			if enemy_base.Team != flag.Team || camped[enemy_base.Coordinates] || flag_on(state, enemy_base.Coordinates) {
+				continue
+			}
+			camped[enemy_base.Coordinates] = true
+			// wait on the closest cell we control rather than walking into theirs
+			spot := enemy_base.Coordinates
+			if staging, ok := current_territory(state).Staging(Team, spot, 1); ok {
+				spot = staging
+			}
+			camp := new_option("camp", "camp at base of "+flag.Team, "camp:"+flag.Team, spot, "")
+			camp.consider("camp", Params.UtilityCamp, caps.Grab)
+			camp.consider("proximity", Params.UtilityProximity, proximity(actor.Coordinates, spot))
+			options = append(options, camp)
+		}
+	}
+	if caps.Attack > 0 {
+		for _, enemy := range filter_objects(state.Actors, false) {
+			// the threat is to whichever of our bases the enemy is closest to
+			threatened := nearest_base(state, enemy.Coordinates)
+			threat := proximity(enemy.Coordinates, threatened.Coordinates)
+			target := enemy.Coordinates
+			if record, ok := Enemies.Get(enemy.Team, enemy.Ident); ok {
+				if record.Approaching(threatened.Coordinates) {
+					threat = math.Min(1, threat+0.25)
+				}
+				// head for where it will be rather than where it was
+				if d := distance(actor.Coordinates, enemy.Coordinates); d > 2 {
+					target = record.Predict(1, GameRules.MapSize())
+				}
+			}
+			if enemy.Flag == Team {
+				threat = 1
+			}
+			label := fmt.Sprintf("intercept %s actor %d", enemy.Team, enemy.Ident)
+			option := new_option("intercept", label, fmt.Sprintf("actor:%s:%d", enemy.Team, enemy.Ident), target, "attack")
+			option.consider("threat", Params.UtilityIntercept, threat*caps.Attack)
+			// the weakest team is left alone while it does not threaten us
+			if rivals != nil && enemy.Flag != Team {
+				option.consider("rival", Params.UtilityRival, rival_bias(rivals, enemy.Team)*(1-threat)*caps.Attack)
+			}
+			// and so are the ones fighting the leader, whose captures are
+			// worth denying whatever flag it carries
+			if leader := Alliances.Leader(); leader != "" && enemy.Flag != Team {
+				value := -Alliances.Ally(enemy.Team) * (1 - threat)
+				if enemy.Team == leader && enemy.Flag != "" {
+					value = 1
+				}
+				option.consider("alliance", Params.UtilityAlliance, value*caps.Attack)
+			}
+			// a careful actor shies away from enemies that strike back, a
+			// reckless one goes for them
+			if c := caution(); c != 1 {
+				option.consider("risk", Params.UtilityIntercept, (1-c)/2*capabilities(enemy).Attack)
+			}
+			if enemy.Flag == Team && endgame.Deny {
+				option.consider("endgame", Params.UtilityIntercept, caps.Attack)
+			}
+			option.consider("proximity", Params.UtilityProximity, proximity(actor.Coordinates, enemy.Coordinates))
+			options = append(options, option)
+		}
+	}
+	if caps.Build > 0 {
+		candidates := defensive_walls(state, GameRules.MapSize(), base)
+		for i := 0; i < len(candidates) && i < 3; i++ {
+			c := candidates[i]
+			option := new_option("build", c.String(), wall_claim(c.Cell), c.Cell, "build")
+			option.consider("build", Params.UtilityBuild, c.Value/(c.Value+1))
+			option.consider("proximity", Params.UtilityProximity, proximity(actor.Coordinates, c.Cell))
+			options = append(options, option)
+		}
+	}
+	if caps.Destroy > 0 {
+		demolitions := shortcut_walls(state, GameRules.MapSize(), base, actor)
+		for i := 0; i < len(demolitions) && i < 3; i++ {
+			d := demolitions[i]
+			wall := d.nearest_wall(actor)
+			option := new_option("destroy", d.String(), demolish_claim(wall), wall, "destroy")
+			gain := float64(d.Saving) - d.Cost
+			option.consider("destroy", Params.UtilityDestroy, gain/(gain+2))
+			option.consider("proximity", Params.UtilityProximity, proximity(actor.Coordinates, wall))
+			options = append(options, option)
+		}
+	}
+	defend := new_option("defend", "defend base", "", base.Coordinates, "")
+	danger := 0.0
+	for _, enemy := range filter_objects(state.Actors, false) {
+		for _, ours := range filter_objects(state.Bases, true) {
+			if float64(distance(enemy.Coordinates, ours.Coordinates)) <= Params.GuardRadius {
+				danger = 1
+			}
+		}
+	}
+	if endgame.AllIn {
+		// leave the base open when only a capture of ours can decide
+		danger = -1
+	}
+	defend.consider("defend", Params.UtilityDefend, 0.5+0.5*danger)
+	defend.consider("proximity", Params.UtilityProximity, proximity(actor.Coordinates, base.Coordinates))
+	options = append(options, defend)
+	// the controllers of the actors run concurrently, each draws from its own stream
+	r := Random.Stream(actor.Ident)
+	for i := range options {
+		options[i].Utility = jitter_with(r, options[i].Utility)
+	}
+	return options
+}
+
+// JointSearch is the branch and bound below one choice of the first actor.
+// It only keeps assignments better than best_utility it started with.
+type JointSearch struct {
+	search       *AnytimeSearch
+	options      [][]UtilityOption
+	bound        []float64
+	current      []int
+	claimed      map[string]bool
+	best         []int
+	best_utility float64
+}
+
+// Run searches the assignments giving the first actor option j, or none
+// for -1.
+func (s *JointSearch) Run(j int) {
+	s.current = make([]int, len(s.options))
+	s.claimed = make(map[string]bool)
+	s.current[0] = j
+	if j < 0 {
+		s.visit(1, 0)
+		return
+	}
+	option := s.options[0][j]
+	if option.Claim != "" {
+		s.claimed[option.Claim] = true
+	}
+	s.visit(1, option.Utility)
+}
+
+func (s *JointSearch) visit(i int, utility float64) {
+	if s.search.Stop() || utility+s.bound[i] <= s.best_utility {
+		return
+	}
+	if i == len(s.options) {
+		s.best_utility = utility
+		s.best = append(s.best[:0], s.current...)
+		return
+	}
+	for j, option := range s.options[i] {
+		if option.Claim != "" && s.claimed[option.Claim] {
+			continue
+		}
+		if option.Claim != "" {
+			s.claimed[option.Claim] = true
+		}
+		s.current[i] = j
+		s.visit(i+1, utility+option.Utility)
+		if option.Claim != "" {
+			delete(s.claimed, option.Claim)
+		}
+	}
+	s.current[i] = -1
+	s.visit(i+1, utility)
+}
+
+type UtilityAssignment struct {
+	Actor  Actor
+	Option UtilityOption
+}
+
+// assignment_nodes bounds the search for the best joint assignment when
+// there is no deadline to stop it. It is cut while the server is slow.
+const assignment_nodes = 2000000
+
+// best_joint_assignment returns the combination of one option per actor with
+// the highest summed utility that respects the exclusive claims.
+func best_joint_assignment(actors []Actor, options [][]UtilityOption) []UtilityAssignment {
+	return best_joint_assignment_context(context.Background(), actors, options)
+}
+
+// best_joint_assignment_context is an anytime search: it starts from the
+// greedy assignment and improves on it by branch and bound until it proved
+// the best one or ctx is done, and then returns the best found so far. The
+// subtrees of the choices of the first actor are searched in parallel.
+func best_joint_assignment_context(ctx context.Context, actors []Actor, options [][]UtilityOption) []UtilityAssignment {
+	best := greedy_choice(options)
+	best_utility := 0.0
+	for i, j := range best {
+		if j >= 0 {
+			best_utility += options[i][j].Utility
+		}
+	}
+	// the most the actors from i on can still add, for pruning
+	bound := make([]float64, len(actors)+1)
+	for i := len(actors) - 1; i >= 0; i-- {
+		top := 0.0
+		for _, option := range options[i] {
+			top = math.Max(top, option.Utility)
+		}
+		bound[i] = bound[i+1] + top
+	}
+	if len(actors) > 0 {
+		// every option of the first actor and leaving it without one
+		choices := make([]int, 0, len(options[0])+1)
+		for j := range options[0] {
+			choices = append(choices, j)
+		}
+		choices = append(choices, -1)
+		nodes := max_int(1, Pressure.Nodes(assignment_nodes)/len(choices))
+		subtrees := parallel_map(choices, func(j int) JointSearch {
+			s := JointSearch{search: new_anytime_search(ctx, nodes), options: options, bound: bound, best_utility: best_utility}
+			s.Run(j)
+			return s
+		})
+		// merged in the order a single search would have visited them, the
+		// first of equally good assignments wins like there
+		complete, searched := true, 0
+		for _, s := range subtrees {
+			if s.best != nil && s.best_utility > best_utility {
+				best, best_utility = s.best, s.best_utility
+			}
+			complete = complete && s.search.Complete()
+			searched += s.search.nodes
+		}
+		if !complete {
+			logf(VerbosityDebug, "joint assignment search stopped after %d nodes", searched)
+		}
+	}
+	result := make([]UtilityAssignment, 0, len(actors))
+	for i, j := range best {
+		if j >= 0 {
+			result = append(result, UtilityAssignment{actors[i], options[i][j]})
+		}
+	}
+	return result
+}
+
+// greedy_choice picks the option index of every actor, -1 for none, by
+// handing out the options in the order of their utility.
+func greedy_choice(options [][]UtilityOption) []int {
+	type pair struct {
+		actor  int
+		option int
+	}
+	pairs := make([]pair, 0)
+	for i := range options {
+		for j := range options[i] {
+			pairs = append(pairs, pair{i, j})
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		return options[pairs[a].actor][pairs[a].option].Utility > options[pairs[b].actor][pairs[b].option].Utility
+	})
+	choice := make([]int, len(options))
+	for i := range choice {
+		choice[i] = -1
+	}
+	claimed := make(map[string]bool)
+	for _, p := range pairs {
+		option := options[p.actor][p.option]
+		if choice[p.actor] >= 0 || (option.Claim != "" && claimed[option.Claim]) {
+			continue
+		}
+		choice[p.actor] = p.option
+		if option.Claim != "" {
+			claimed[option.Claim] = true
+		}
+	}
+	return choice
+}
+
+func position_target(c Coordinates) OwnedObjectImpl {
+	return OwnedObjectImpl{Coordinates: c}
+}
+
+func utility_orders(assignment UtilityAssignment, state GameState, base Base, orders []Order) []Order {
+	actor := assignment.Actor
+	option := assignment.Option
+	switch option.Kind {
+	case "defend":
+		return guard_base(actor, base, state, orders)
+	case "camp":
+		if distance(actor.Coordinates, option.Target) > 1 {
+			orders = append(orders, Order{"move", actor.Ident, Plans.Direction(actor, option.Target), option.Explain(), PriorityOpportunistic})
+		}
+		return orders
+	case "intercept":
+		for _, enemy := range filter_objects(state.Actors, false) {
+			if option.Claim == fmt.Sprintf("actor:%s:%d", enemy.Team, enemy.Ident) {
+				Pincers.Attack(actor, enemy)
+			}
+		}
+		return seek_target(actor, position_target(option.Target), option.Action, option.Explain(), orders)
+	default:
+		return seek_target(actor, position_target(option.Target), option.Action, option.Explain(), orders)
+	}
+}
+
+func generate_utility_orders(state GameState) []Order {
+	return generate_utility_orders_context(context.Background(), state)
+}
+
+func generate_utility_orders_context(ctx context.Context, state GameState) []Order {
+	Plans.Observe(state)
+	orders := make([]Order, 0)
+	if len(filter_objects(state.Bases, true)) == 0 {
+		return orders
+	}
+	actors := filter_objects(state.Actors, true)
+	options := parallel_map(actors, func(actor Actor) []UtilityOption {
+		return utility_options(actor, state, nearest_base(state, actor.Coordinates))
+	})
+	search_ctx, cancel := planning_context(ctx)
+	defer cancel()
+	assignments := best_joint_assignment_context(search_ctx, actors, options)
+	assign_zones(state, assignments)
+	for _, assignment := range assignments {
+		orders = utility_orders(assignment, state, nearest_base(state, assignment.Actor.Coordinates), orders)
+	}
+	return orders
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// StateProblems lists everything validate_state found wrong with a state.
+type StateProblems []string
+
+func (p StateProblems) Error() string {
+	return "invalid game state: " + strings.Join(p, "; ")
+}
+
+// validate_state checks a fetched state for the gaps and inconsistencies the
+// strategies rely on not being there, such as our base missing while the
+// server is still setting up the board. A nil result means the state is
+// safe to play on.
+func validate_state(state GameState, size int) error {
+	problems := StateProblems{}
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	teams := make(map[string]bool, len(state.Teams))
+	for _, team := range state.Teams {
+		teams[team] = true
+	}
+	if !teams[Team] {
+		add("team %q is not playing", Team)
+	}
+	inside := func(c Coordinates) bool {
+		return c.X >= 0 && c.Y >= 0 && (size <= 0 || (c.X < size && c.Y < size))
+	}
+	bases := make(map[string]int)
+	for _, base := range state.Bases {
+		bases[base.Team]++
+		if !teams[base.Team] {
+			add("base of unknown team %q", base.Team)
+		}
+		if !inside(base.Coordinates) {
+			add("base of %s outside the board at %v", base.Team, base.Coordinates)
+		}
+	}
+	for team := range teams {
+		if bases[team] == 0 {
+			add("team %s has no base", team)
+		}
+	}
+	for _, flag := range state.Flags {
+		if !teams[flag.Team] {
+			add("flag of unknown team %q", flag.Team)
+		}
+		if !inside(flag.Coordinates) {
+			add("flag of %s outside the board at %v", flag.Team, flag.Coordinates)
+		}
+	}
+	idents := make(map[string]bool)
+	occupied := make(map[Coordinates]bool)
+	for _, actor := range state.Actors {
+		key := fmt.Sprintf("%s:%d", actor.Team, actor.Ident)
+		if idents[key] {
+			add("actor %d of %s appears twice", actor.Ident, actor.Team)
+		}
+		idents[key] = true
+		if !teams[actor.Team] {
+			add("actor of unknown team %q", actor.Team)
+		}
+		if !inside(actor.Coordinates) {
+			add("actor %d of %s outside the board at %v", actor.Ident, actor.Team, actor.Coordinates)
+		}
+		if occupied[actor.Coordinates] {
+			add("two actors at %v", actor.Coordinates)
+		}
+		occupied[actor.Coordinates] = true
+		if actor.Flag != "" && !teams[actor.Flag] {
+			add("actor %d of %s carries flag of unknown team %q", actor.Ident, actor.Team, actor.Flag)
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+var order_directions = map[string]bool{
+	string(APIDirectionsLeft):  true,
+	string(APIDirectionsRight): true,
+	string(APIDirectionsDown):  true,
+	string(APIDirectionsUp):    true,
+}
+
+// can_perform checks the actor type's probability for an order, as far as
+// the rules tell.
+func can_perform(actor Actor, order_type string) bool {
+	// load the rules into the registry if they are not yet
+	GameRules.Get()
+	c, ok := Capabilities.Rules(actor.Type)
+	if !ok {
+		return true
+	}
+	switch order_type {
+	case "grabput":
+		return c.Grab > 0
+	case "attack":
+		return c.Attack > 0
+	case "build":
+		return c.Build > 0
+	case "destroy":
+		return c.Destroy > 0
+	}
+	return true
+}
+
+// validate_orders drops orders the server would reject or ignore before
+// they cost a request: unknown order types and directions, actors we do not
+// have, orders the actor type cannot perform and repeated orders. The
+// server tries a later order of the same type when an earlier one failed,
+// but our strategies plan one order per actor and type and send no
+// fallbacks, so a second one is a planning mistake and only the first
+// order per actor and type is kept.
+func validate_orders(orders []Order, state GameState) []Order {
+	actors := make(map[int]Actor)
+	for _, actor := range filter_objects(state.Actors, true) {
+		actors[actor.Ident] = actor
+	}
+	type slot struct {
+		actor      int
+		order_type string
+	}
+	seen := make(map[slot]bool)
+	valid := make([]Order, 0, len(orders))
+	for _, order := range orders {
+		actor, ok := actors[order.actor]
+		problem := ""
+		switch {
+		case order_bindings[order.order_type] == nil:
+			problem = "unknown order type"
+		case !order_directions[order.direction]:
+			problem = "unknown direction"
+		case !ok:
+			problem = "no such actor"
+		case !can_perform(actor, order.order_type):
+			problem = actor.Type + " actors cannot " + order.order_type
+		case seen[slot{order.actor, order.order_type}]:
+			problem = "actor already has a " + order.order_type + " order"
+		}
+		if problem != "" {
+			log.Printf("dropping order %v: %s", order, problem)
+			continue
+		}
+		seen[slot{order.actor, order.order_type}] = true
+		valid = append(valid, order)
+	}
+	return valid
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Verbosity selects which log lines are written. Errors, warnings and
+// scores are always logged, the other lines carry their level.
+type Verbosity int32
+
+const (
+	VerbosityQuiet Verbosity = iota
+	VerbosityNormal
+	// VerbosityDebug adds every order with the reason it was decided on.
+	VerbosityDebug
+	// VerbosityTrace adds the full game state of every tick.
+	VerbosityTrace
+)
+
+var verbosity_names = []string{"quiet", "normal", "debug", "trace"}
+
+// verbosity is read by every goroutine that logs and changed at runtime
+// through the health endpoint, so it is accessed atomically.
+var verbosity = int32(VerbosityNormal)
+
+func (v Verbosity) String() string {
+	if v < 0 || int(v) >= len(verbosity_names) {
+		return fmt.Sprintf("verbosity(%d)", int32(v))
+	}
+	return verbosity_names[v]
+}
+
+func parse_verbosity(name string) (Verbosity, error) {
+	for i, known := range verbosity_names {
+		if strings.EqualFold(name, known) {
+			return Verbosity(i), nil
+		}
+	}
+	return VerbosityNormal, fmt.Errorf("unknown log level %q, expected one of %s", name, strings.Join(verbosity_names, ", "))
+}
+
+func current_verbosity() Verbosity {
+	return Verbosity(atomic.LoadInt32(&verbosity))
+}
+
+func set_verbosity(v Verbosity) {
+	atomic.StoreInt32(&verbosity, int32(v))
+}
+
+// logf logs the line when the verbosity is at least level.
+func logf(level Verbosity, format string, args ...any) {
+	if current_verbosity() >= level {
+		log.Printf(format, args...)
+	}
+}
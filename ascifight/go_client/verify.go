@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Outcome compares what one of our orders should have done with what the
+// next state shows. Result is ok, or what went wrong: rejected by the
+// server, blocked, killed, missed, failed or missing when the actor is gone.
+type Outcome struct {
+	Tick     int
+	Order    Order
+	Expected Coordinates
+	Actual   Coordinates
+	Result   string
+}
+
+func (o Outcome) String() string {
+	return fmt.Sprintf("tick %d: %v %s, expected at (%d,%d), at (%d,%d)", o.Tick, o.Order, o.Result, o.Expected.X, o.Expected.Y, o.Actual.X, o.Actual.Y)
+}
+
+func (o Outcome) Ok() bool {
+	return o.Result == "ok"
+}
+
+func contains_order(orders []Order, order Order) bool {
+	for _, o := range orders {
+		if o.actor == order.actor && o.order_type == order.order_type && o.direction == order.direction {
+			return true
+		}
+	}
+	return false
+}
+
+func contains_wall(walls []Wall, c Coordinates) bool {
+	for _, wall := range walls {
+		if wall == c {
+			return true
+		}
+	}
+	return false
+}
+
+// verify_orders checks every order sent between two consecutive states. An
+// actor can move and act in the same tick, its other orders are expected to
+// be carried out from where its move should have taken it.
+func verify_orders(previous GameState, current GameState, orders []Order, rejected []Order) []Outcome {
+	outcomes := make([]Outcome, 0, len(orders))
+	planned := make(map[int]Coordinates)
+	for _, order := range orders {
+		before, ok := find_actor(previous.Actors, Team, order.actor)
+		if !ok {
+			continue
+		}
+		from, moved := planned[order.actor]
+		if !moved {
+			from = before.Coordinates
+		}
+		target := predicted_position(from, order.direction)
+		outcome := Outcome{Tick: current.Tick, Order: order, Expected: from, Result: "ok"}
+		if order.order_type == "move" {
+			outcome.Expected = target
+			planned[order.actor] = target
+		}
+		after, ok := find_actor(current.Actors, Team, order.actor)
+		outcome.Actual = after.Coordinates
+		switch {
+		case !ok:
+			outcome.Result = "missing"
+		case contains_order(rejected, order):
+			outcome.Result = "rejected"
+		case respawned(before.Coordinates, after.Coordinates):
+			outcome.Result = "killed"
+		case order.order_type == "move":
+			if after.Coordinates != outcome.Expected {
+				outcome.Result = "blocked"
+			}
+		case order.order_type == "grabput" && before.Flag == after.Flag:
+			outcome.Result = "failed"
+		case order.order_type == "build" && !contains_wall(current.Walls, target):
+			outcome.Result = "failed"
+		case order.order_type == "destroy" && contains_wall(current.Walls, target):
+			outcome.Result = "failed"
+		case order.order_type == "attack":
+			outcome.Result = "missed"
+			for _, enemy := range filter_objects(previous.Actors, false) {
+				hit, ok := find_actor(current.Actors, enemy.Team, enemy.Ident)
+				if enemy.Coordinates == target && ok && respawned(enemy.Coordinates, hit.Coordinates) {
+					outcome.Result = "ok"
+				}
+			}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+// OutcomeLog keeps the outcomes of the latest tick and how often our moves
+// were recently blocked on each cell, so the path search steers around the
+// cells the enemy keeps contesting.
+type OutcomeLog struct {
+	mutex   sync.Mutex
+	last    []Outcome
+	blocked map[Coordinates]float64
+}
+
+// Outcomes verifies the orders of every tick the bot plays.
+var Outcomes = &OutcomeLog{blocked: make(map[Coordinates]float64)}
+
+// blocked_decay is how much of a blocked move is still remembered a tick
+// later.
+const blocked_decay = 0.5
+
+func (l *OutcomeLog) Record(outcomes []Outcome) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for c, count := range l.blocked {
+		if count *= blocked_decay; count < 0.25 {
+			delete(l.blocked, c)
+		} else {
+			l.blocked[c] = count
+		}
+	}
+	for _, o := range outcomes {
+		if o.Result == "blocked" && o.Order.order_type == "move" {
+			l.blocked[o.Expected]++
+		}
+	}
+	l.last = outcomes
+}
+
+// Last returns the outcomes of the latest verified tick.
+func (l *OutcomeLog) Last() []Outcome {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.last
+}
+
+// Costs are the extra steps the path search charges for recently blocked
+// cells.
+func (l *OutcomeLog) Costs() map[Coordinates]int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	costs := make(map[Coordinates]int, len(l.blocked))
+	for c, count := range l.blocked {
+		if cost := int(math.Round(count * 2)); cost > 0 {
+			costs[c] = cost
+		}
+	}
+	return costs
+}
+
+func (l *OutcomeLog) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.last = nil
+	l.blocked = make(map[Coordinates]float64)
+}
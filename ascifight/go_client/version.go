@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ClientVersion identifies the build in the User-Agent, set it with
+// -ldflags "-X main.ClientVersion=...".
+var ClientVersion = "dev"
+
+func user_agent() string {
+	return fmt.Sprintf("ascifight-go-client/%s (api %s; team %s)", ClientVersion, APIVersion, Team)
+}
+
+// required_paths are the endpoints the bot cannot play without.
+var required_paths = []string{
+	"/states/timing",
+	"/states/game_state",
+	"/states/game_rules",
+	"/orders/move/{actor}",
+	"/orders/grabput/{actor}",
+	"/orders/attack/{actor}",
+}
+
+type ServerInfo struct {
+	Info struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Paths map[string]json.RawMessage `json:"paths"`
+}
+
+func parse_version(version string) []int {
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	numbers := make([]int, 3)
+	for i := 0; i < len(parts) && i < 3; i++ {
+		numbers[i], _ = strconv.Atoi(strings.TrimFunc(parts[i], func(r rune) bool { return r < '0' || r > '9' }))
+	}
+	return numbers
+}
+
+// compatible_versions follows semantic versioning: the major versions must
+// match, and while the major version is 0 so must the minor ones.
+func compatible_versions(client string, server string) bool {
+	c := parse_version(client)
+	s := parse_version(server)
+	if c[0] != s[0] {
+		return false
+	}
+	return c[0] != 0 || c[1] == s[1]
+}
+
+// check_server_version compares the server's OpenAPI metadata with the
+// APIVersion the bindings were generated from. Incompatible versions and
+// missing endpoints are errors, other differences only warnings.
+func check_server_version() error {
+	var data []byte
+	err := api_request(context.Background(), "GET", "openapi.json", nil, nil, false, &data)
+	// every failover endpoint gets its chance before giving up
+	for tries := 1; err != nil && tries < len(Servers.List()); tries++ {
+		err = api_request(context.Background(), "GET", "openapi.json", nil, nil, false, &data)
+	}
+	if err != nil {
+		return fmt.Errorf("querying the server version: %w", err)
+	}
+	var info ServerInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return fmt.Errorf("decoding the server version: %w", err)
+	}
+	missing := make([]string, 0)
+	for _, path := range required_paths {
+		if _, ok := info.Paths[path]; !ok {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the server lacks the endpoints %s", strings.Join(missing, ", "))
+	}
+	if _, ok := info.Paths[batch_route]; ok {
+		batch_offered = true
+		logf(VerbosityNormal, "server accepts orders in batches")
+	}
+	if _, ok := info.Paths[events_route]; ok {
+		events_offered = true
+		logf(VerbosityNormal, "server publishes event logs")
+	}
+	switch {
+	case !compatible_versions(APIVersion, info.Info.Version):
+		return fmt.Errorf("server runs API version %s, the client was built for %s", info.Info.Version, APIVersion)
+	case info.Info.Version != APIVersion:
+		log.Printf("warning: server runs API version %s, the client was built for %s", info.Info.Version, APIVersion)
+	default:
+		logf(VerbosityNormal, "server runs API version %s", info.Info.Version)
+	}
+	return nil
+}
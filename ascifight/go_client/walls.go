@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// wall_radius bounds how far from our base defensive walls are placed.
+const wall_radius = 4
+
+// WallCandidate is a cell where a wall would lengthen the enemy's way to
+// our base by Gain steps. On an open board single walls rarely delay the
+// nearest enemy, so Delay also counts the average detour they add to all
+// approaches of the base; together they make up Value.
+type WallCandidate struct {
+	Cell  Coordinates
+	Gain  int
+	Delay float64
+	Value float64
+}
+
+// base_distances floods the board from the cells next to base, walking
+// around blocked cells. Cells that cannot reach the base are -1.
+func base_distances(size int, base Coordinates, blocked map[Coordinates]bool) [][]int {
+	distance := make([][]int, size)
+	for y := range distance {
+		distance[y] = make([]int, size)
+		for x := range distance[y] {
+			distance[y][x] = -1
+		}
+	}
+	inside := func(c Coordinates) bool { return c.X >= 0 && c.Y >= 0 && c.X < size && c.Y < size }
+	frontier := make([]Coordinates, 0)
+	for _, direction := range plan_directions {
+		if c := predicted_position(base, direction); inside(c) && !blocked[c] {
+			distance[c.Y][c.X] = 0
+			frontier = append(frontier, c)
+		}
+	}
+	for len(frontier) > 0 {
+		current := frontier[0]
+		frontier = frontier[1:]
+		for _, direction := range plan_directions {
+			next := predicted_position(current, direction)
+			if !inside(next) || blocked[next] || distance[next.Y][next.X] >= 0 {
+				continue
+			}
+			distance[next.Y][next.X] = distance[current.Y][current.X] + 1
+			frontier = append(frontier, next)
+		}
+	}
+	return distance
+}
+
+// wall_layout is the part of the board the wall planner reasons about.
+type wall_layout struct {
+	size    int
+	base    Coordinates
+	blocked map[Coordinates]bool
+	ours    []Coordinates
+	enemies []Coordinates
+	flags   []Coordinates
+}
+
+// evaluate returns the enemy's shortest way next to our base with walls
+// added, the distances of all cells, and whether all our actors can still
+// get home and still reach every enemy flag.
+func (l wall_layout) evaluate(walls ...Coordinates) (int, [][]int, bool) {
+	for _, wall := range walls {
+		l.blocked[wall] = true
+		defer delete(l.blocked, wall)
+	}
+	distance := PathMemo.base_distances(l.size, l.base, l.blocked)
+	at := func(c Coordinates) int {
+		if c.X < 0 || c.Y < 0 || c.X >= l.size || c.Y >= l.size {
+			return -1
+		}
+		return distance[c.Y][c.X]
+	}
+	for _, c := range l.ours {
+		if at(c) < 0 {
+			return 0, nil, false
+		}
+	}
+	for _, flag := range l.flags {
+		reachable := false
+		for _, direction := range plan_directions {
+			if at(predicted_position(flag, direction)) >= 0 {
+				reachable = true
+			}
+		}
+		if !reachable {
+			return 0, nil, false
+		}
+	}
+	shortest := -1
+	for _, c := range l.enemies {
+		if d := at(c); d >= 0 && (shortest < 0 || d < shortest) {
+			shortest = d
+		}
+	}
+	return shortest, distance, true
+}
+
+// approach_delay is the average detour walls add for the cells around the
+// base that stay reachable.
+func (l wall_layout) approach_delay(before [][]int, after [][]int) float64 {
+	total, cells := 0, 0
+	for y := 0; y < l.size; y++ {
+		for x := 0; x < l.size; x++ {
+			if distance(Coordinates{X: x, Y: y}, l.base) > 2*wall_radius || before[y][x] < 0 || after[y][x] < 0 {
+				continue
+			}
+			total += after[y][x] - before[y][x]
+			cells++
+		}
+	}
+	if cells == 0 {
+		return 0
+	}
+	return float64(total) / float64(cells)
+}
+
+// defensive_walls ranks the free cells around our base by how many steps
+// a wall there adds to the enemy's shortest way to it. Cells that would
+// lock our own actors out of the base or away from the enemy flags are
+// left out, as are walls that gain nothing.
+func defensive_walls(state GameState, size int, base Base) []WallCandidate {
+	size = board_size(state, size)
+	l := wall_layout{size: size, base: base.Coordinates, blocked: make(map[Coordinates]bool)}
+	occupied := make(map[Coordinates]bool)
+	for _, wall := range state.Walls {
+		l.blocked[wall] = true
+	}
+	for _, b := range state.Bases {
+		l.blocked[b.Coordinates] = true
+	}
+	for _, actor := range state.Actors {
+		occupied[actor.Coordinates] = true
+		if actor.Team == Team {
+			l.ours = append(l.ours, actor.Coordinates)
+		} else {
+			l.enemies = append(l.enemies, actor.Coordinates)
+		}
+	}
+	for _, flag := range state.Flags {
+		occupied[flag.Coordinates] = true
+		if flag.Team != Team {
+			l.flags = append(l.flags, flag.Coordinates)
+		}
+	}
+	before, distances, ok := l.evaluate()
+	if !ok || before < 0 {
+		return nil
+	}
+	candidates := make([]WallCandidate, 0)
+	for y := base.Coordinates.Y - wall_radius; y <= base.Coordinates.Y+wall_radius; y++ {
+		for x := base.Coordinates.X - wall_radius; x <= base.Coordinates.X+wall_radius; x++ {
+			c := Coordinates{X: x, Y: y}
+			if x < 0 || y < 0 || x >= size || y >= size || distance(c, base.Coordinates) > wall_radius {
+				continue
+			}
+			if l.blocked[c] || occupied[c] {
+				continue
+			}
+			after, walled, ok := l.evaluate(c)
+			if !ok {
+				continue
+			}
+			gain := after - before
+			if after < 0 {
+				// the enemy is cut off entirely
+				gain = size * size
+			}
+			delay := l.approach_delay(distances, walled)
+			if gain > 0 || delay > 0 {
+				candidates = append(candidates, WallCandidate{c, gain, delay, float64(gain) + delay})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Value != candidates[j].Value {
+			return candidates[i].Value > candidates[j].Value
+		}
+		return distance(candidates[i].Cell, base.Coordinates) < distance(candidates[j].Cell, base.Coordinates)
+	})
+	return candidates
+}
+
+func (c WallCandidate) String() string {
+	return fmt.Sprintf("wall slowing the enemy by %d steps, approaches by %.2f", c.Gain, c.Delay)
+}
+
+func wall_claim(c Coordinates) string {
+	return fmt.Sprintf("wall:%d:%d", c.X, c.Y)
+}
+
+func can_build(ctx *BehaviorContext) bool {
+	return capabilities(ctx.Actor).Build > 0
+}
+
+// build_wall_action sends a builder to the best wall no other builder of
+// this tick took, building it once next to the cell. Builds can fail, so
+// the builder keeps at it over the following ticks until the wall stands.
+func build_wall_action(ctx *BehaviorContext) Status {
+	claimed, _ := ctx.Blackboard["walls"].(map[Coordinates]bool)
+	if claimed == nil {
+		claimed = make(map[Coordinates]bool)
+		ctx.Blackboard["walls"] = claimed
+	}
+	for _, candidate := range defensive_walls(ctx.State, GameRules.MapSize(), ctx.MyBase) {
+		if claimed[candidate.Cell] {
+			continue
+		}
+		claimed[candidate.Cell] = true
+		why := candidate.String()
+		ctx.Orders = seek_target(ctx.Actor, position_target(candidate.Cell), "build", why, ctx.Orders)
+		return Success
+	}
+	return Failure
+}
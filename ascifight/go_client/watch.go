@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// Watcher renders the game every tick for spectators. It only reads the
+// public state endpoints, so it needs no credentials and never submits an
+// order.
+type Watcher struct {
+	out   io.Writer
+	clear bool
+	tick  int
+}
+
+// render draws one tick, redrawing the screen in place when clear is set.
+func (w *Watcher) render(t Timing, state GameState) {
+	if w.clear {
+		fmt.Fprint(w.out, "\u001b[H\u001b[2J")
+	}
+	fmt.Fprintf(w.out, "tick %d, next in %.1fs\n", state.Tick, t.TimeToNextExecution)
+	fmt.Fprintf(w.out, "%s\n\n", render_scores(state))
+	fmt.Fprint(w.out, render_board(state, GameRules.MapSize()))
+	for _, actor := range state.Actors {
+		if actor.Flag != "" {
+			fmt.Fprintf(w.out, "%s actor %d carries the flag of %s\n", actor.Team, actor.Ident, actor.Flag)
+		}
+	}
+}
+
+func watch_command(args []string) {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	flags.StringVar(&ServerUrl, "server", ServerUrl, "base url of the game server, or a comma separated list to fail over to")
+	flags.StringVar(&Team, "highlight", "", "team whose actors are drawn in upper case")
+	clear := flags.Bool("clear", is_terminal(os.Stdout), "redraw the board in place instead of printing one board per tick")
+	color := flags.String("color", "auto", "color the board with the server's team colors: auto, always or never")
+	flags.Parse(args)
+	normalize_server_url()
+	if err := set_color_mode(*color, !is_terminal(os.Stdout)); err != nil {
+		log.Fatalln(err)
+	}
+
+	w := &Watcher{out: os.Stdout, clear: *clear, tick: -1}
+	poller := new_poller(10 * time.Millisecond)
+	for {
+		t, err := DefaultClient.Timing(context.Background())
+		if err != nil {
+			log.Printf("fetching timing failed: %v", err)
+			time.Sleep(retry_delay(err))
+			continue
+		}
+		if t.Tick == w.tick {
+			time.Sleep(poller.Wait(t))
+			continue
+		}
+		poller.Ticked()
+		if t.Tick < w.tick {
+			// a new game may be played on another board
+			GameRules.Invalidate()
+		}
+		state, err := DefaultClient.GameState(context.Background())
+		if err != nil {
+			log.Printf("fetching game state failed: %v", err)
+			time.Sleep(retry_delay(err))
+			continue
+		}
+		w.tick = t.Tick
+		w.render(t, state)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// watchdog_grace is how long a cancelled loop gets to return before it is
+// abandoned and a new one started next to it.
+const watchdog_grace = 2 * time.Second
+
+// Watchdog notices a tick loop that stopped coming round, because it hangs
+// in a request without timeout or deadlocked, and has play restart it.
+type Watchdog struct {
+	mutex sync.Mutex
+	// Intervals is how many tick intervals the loop may go without a beat.
+	Intervals int
+	// Min is the shortest stall that counts, it covers the waits of the
+	// loop between games and while the server is unreachable.
+	Min      time.Duration
+	beat     time.Time
+	tick     int
+	tick_at  time.Time
+	interval time.Duration
+}
+
+func new_watchdog(intervals int, min time.Duration) *Watchdog {
+	return &Watchdog{Intervals: intervals, Min: min}
+}
+
+// Beat tells the watchdog that the loop came round, at tick.
+func (w *Watchdog) Beat(tick int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	now := time.Now()
+	w.beat = now
+	if tick == w.tick {
+		return
+	}
+	if w.tick > 0 && tick > w.tick {
+		w.interval = average(w.interval, now.Sub(w.tick_at)/time.Duration(tick-w.tick))
+	}
+	w.tick, w.tick_at = tick, now
+}
+
+// limit is how long the loop may go without a beat.
+func (w *Watchdog) limit() time.Duration {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return max_duration(w.Min, time.Duration(w.Intervals)*w.interval)
+}
+
+// Stalled reports whether the loop missed its beats for longer than the
+// limit, for how long and after which tick.
+func (w *Watchdog) Stalled() (time.Duration, int, bool) {
+	limit := w.limit()
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	since := time.Since(w.beat)
+	return since, w.tick, !w.beat.IsZero() && since > limit
+}
+
+// Wait blocks until done is closed, then it returns false, or until the
+// loop stalled.
+func (w *Watchdog) Wait(done <-chan struct{}) bool {
+	w.mutex.Lock()
+	w.beat = time.Now()
+	w.mutex.Unlock()
+	check := time.NewTicker(time.Second)
+	defer check.Stop()
+	for {
+		select {
+		case <-done:
+			return false
+		case <-check.C:
+			if since, tick, stalled := w.Stalled(); stalled {
+				log.Printf("warning: tick loop stalled for %v after tick %d, restarting it", since.Round(time.Second), tick)
+				return true
+			}
+		}
+	}
+}
+
+// supervise runs play_loop and starts it anew whenever options.Watchdog
+// finds it stalled. The stalled loop is cancelled, which ends the requests
+// it waits for, and abandoned if it still does not return. The new loop
+// plays the strategy the last game ended with and rebuilds its memory from
+// the state of the running game.
+func supervise(strategy Strategy, options BotOptions, on_game_end func(stats *GameStats) (Strategy, bool)) {
+	var mutex sync.Mutex
+	baseline := Params
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		ended := func(stats *GameStats) (Strategy, bool) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			// an abandoned loop that comes back must not play on
+			if ctx.Err() != nil {
+				return nil, false
+			}
+			next, ok := on_game_end(stats)
+			if ok {
+				strategy = next
+			}
+			return next, ok
+		}
+		mutex.Lock()
+		current := strategy
+		mutex.Unlock()
+		done := make(chan struct{})
+		go func(options BotOptions) {
+			defer close(done)
+			play_loop(ctx, current, options, ended)
+		}(options)
+		stalled := options.Watchdog.Wait(done)
+		cancel()
+		if !stalled {
+			return
+		}
+		select {
+		case <-done:
+			// output of a game cut short is started afresh
+			if options.Recorder != nil {
+				if err := options.Recorder.Finish(); err != nil {
+					log.Printf("writing game recording failed: %v", err)
+				}
+			}
+		case <-time.After(watchdog_grace):
+			log.Printf("warning: stalled tick loop did not stop, abandoning it")
+			// the abandoned loop may still be writing them
+			options.Recorder, options.Snapshots, options.Journal = nil, nil, nil
+		}
+		set_params(baseline)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Notifier posts match events to a Discord or Slack compatible webhook. The
+// message is sent both as `content` (Discord) and `text` (Slack).
+type Notifier struct {
+	url      string
+	client   *http.Client
+	previous *GameState
+	leader   string
+}
+
+func new_notifier(url string) *Notifier {
+	return &Notifier{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (n *Notifier) send(message string) {
+	body, err := json.Marshal(map[string]string{"content": message, "text": message})
+	if err != nil {
+		log.Printf("webhook: %v", err)
+		return
+	}
+	go func() {
+		resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("webhook: %v", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("webhook: %s", resp.Status)
+		}
+	}()
+}
+
+func format_scores(scores Scores) string {
+	teams := make([]string, 0, len(scores))
+	for team := range scores {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		if scores[teams[i]] != scores[teams[j]] {
+			return scores[teams[i]] > scores[teams[j]]
+		}
+		return teams[i] < teams[j]
+	})
+	parts := make([]string, 0, len(teams))
+	for _, team := range teams {
+		parts = append(parts, fmt.Sprintf("%s %d", team, scores[team]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// leader returns the team with the highest score, or "" while tied.
+func leader(scores Scores) string {
+	best := ""
+	tied := false
+	for team, score := range scores {
+		switch {
+		case best == "" || score > scores[best]:
+			best = team
+			tied = false
+		case score == scores[best]:
+			tied = true
+		}
+	}
+	if tied {
+		return ""
+	}
+	return best
+}
+
+// captures lists the flags that were brought home between two states, as
+// capturing team and flag team pairs.
+func captures(previous GameState, current GameState) [][2]string {
+	result := make([][2]string, 0)
+	for _, before := range previous.Actors {
+		if before.Flag == "" || before.Flag == before.Team {
+			continue
+		}
+		after, ok := find_actor(current.Actors, before.Team, before.Ident)
+		if !ok || after.Flag != "" || respawned(before.Coordinates, after.Coordinates) {
+			continue
+		}
+		if flag_returned(previous, current, before.Flag) {
+			result = append(result, [2]string{before.Team, before.Flag})
+		}
+	}
+	return result
+}
+
+func (n *Notifier) Observe(state GameState) {
+	if n.previous == nil {
+		event := "game started"
+		if state.Tick > 1 {
+			event = fmt.Sprintf("joined game at tick %d", state.Tick)
+		}
+		n.send(fmt.Sprintf("%s: %s", event, strings.Join(state.Teams, " vs ")))
+	} else {
+		for _, capture := range captures(*n.previous, state) {
+			n.send(fmt.Sprintf("%s captured the flag of %s at tick %d (%s)", capture[0], capture[1], state.Tick, format_scores(state.Scores)))
+		}
+	}
+	if current := leader(state.Scores); current != "" && current != n.leader {
+		if n.leader != "" || n.previous != nil {
+			n.send(fmt.Sprintf("%s takes the lead at tick %d (%s)", current, state.Tick, format_scores(state.Scores)))
+		}
+		n.leader = current
+	}
+	n.previous = &state
+}
+
+// Alert passes on a warning about how the bot plays.
+func (n *Notifier) Alert(message string) {
+	n.send("warning: " + message)
+}
+
+func (n *Notifier) Finish(stats *GameStats) {
+	if n.previous == nil {
+		return
+	}
+	message := fmt.Sprintf("game over after %d ticks: %s", stats.Ticks, format_scores(stats.FinalScores))
+	if winner := leader(stats.FinalScores); winner != "" {
+		message += ", " + winner + " wins"
+	}
+	n.send(message)
+	n.previous = nil
+	n.leader = ""
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// DefenseZone is a sector of our half of the board around one of our
+// bases, held by one defender from its post.
+type DefenseZone struct {
+	Index int
+	Post  Coordinates
+	base  Coordinates
+	cells map[Coordinates]bool
+}
+
+// defense_zones splits the cells closer to base than to any enemy base and
+// to our other bases into n sectors of equal size by their angle around
+// base. The sectors start
+// behind the base, so that none is cut in two by the wrap of the angle.
+// Each post is the cell of its sector at half the guard radius from base
+// closest to the middle of the sector.
+func defense_zones(state GameState, size int, base Base, n int) []DefenseZone {
+	blocked := make(map[Coordinates]bool)
+	for _, wall := range state.Walls {
+		blocked[wall] = true
+	}
+	for _, b := range state.Bases {
+		blocked[b.Coordinates] = true
+	}
+	enemy_bases := filter_objects(state.Bases, false)
+	other_bases := make([]Base, 0)
+	for _, b := range filter_objects(state.Bases, true) {
+		if b.Coordinates != base.Coordinates {
+			other_bases = append(other_bases, b)
+		}
+	}
+	cells := make([]Coordinates, 0)
+	var mx, my float64
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := Coordinates{x, y}
+			if blocked[c] {
+				continue
+			}
+			ours := true
+			for _, enemy := range enemy_bases {
+				if distance(c, enemy.Coordinates) <= distance(c, base.Coordinates) {
+					ours = false
+				}
+			}
+			for _, other := range other_bases {
+				if distance(c, other.Coordinates) < distance(c, base.Coordinates) {
+					ours = false
+				}
+			}
+			if ours {
+				cells = append(cells, c)
+				mx += float64(c.X - base.Coordinates.X)
+				my += float64(c.Y - base.Coordinates.Y)
+			}
+		}
+	}
+	if len(cells) < n {
+		return nil
+	}
+	cut := math.Atan2(-my, -mx)
+	angle := func(c Coordinates) float64 {
+		a := math.Atan2(float64(c.Y-base.Coordinates.Y), float64(c.X-base.Coordinates.X)) - cut
+		for a < 0 {
+			a += 2 * math.Pi
+		}
+		return math.Mod(a, 2*math.Pi)
+	}
+	sort.SliceStable(cells, func(i, j int) bool { return angle(cells[i]) < angle(cells[j]) })
+	radius := max_int(1, int(math.Round(Params.GuardRadius/2)))
+	zones := make([]DefenseZone, n)
+	for i := range zones {
+		sector := cells[i*len(cells)/n : (i+1)*len(cells)/n]
+		zone := DefenseZone{Index: i, Post: sector[0], base: base.Coordinates, cells: make(map[Coordinates]bool, len(sector))}
+		middle := angle(sector[len(sector)/2])
+		best := math.Inf(1)
+		for _, c := range sector {
+			zone.cells[c] = true
+			// cells at the post radius win over all others, then the middle
+			score := math.Abs(angle(c)-middle) + 10*float64(abs_diff(distance(c, base.Coordinates), radius))
+			if score < best {
+				zone.Post, best = c, score
+			}
+		}
+		zones[i] = zone
+	}
+	return zones
+}
+
+// ZoneDefense holds the bases and zones of the defenders of the current
+// tick. A defender keeps its zone while the number of defenders of every
+// base stays the same.
+type ZoneDefense struct {
+	mutex  sync.Mutex
+	zones  []DefenseZone
+	owners map[int]int
+	// bases are the bases the defenders are split among and layout the
+	// number of defenders of each.
+	bases  map[int]Base
+	layout string
+	// targets are the enemies the defenders went for at the last tick.
+	targets map[int]pincer_key
+}
+
+func new_zone_defense() *ZoneDefense {
+	return &ZoneDefense{owners: make(map[int]int), bases: make(map[int]Base), targets: make(map[int]pincer_key)}
+}
+
+// Zones are assigned by the strategies once they know their defenders and
+// used by guard_base.
+var Zones = new_zone_defense()
+
+// Assign splits defenders among our bases and divides the half of each
+// base among its defenders. A base with less than two defenders has nothing
+// to divide and guard_base guards the base itself.
+func (z *ZoneDefense) Assign(state GameState, defenders []Actor) {
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+	z.bases = split_defenders(state, defenders)
+	size := board_size(state, GameRules.MapSize())
+	counts := make([]int, 0)
+	zones := make([]DefenseZone, 0)
+	for _, base := range filter_objects(state.Bases, true) {
+		group := 0
+		for _, actor := range defenders {
+			if z.bases[actor.Ident] == base {
+				group++
+			}
+		}
+		counts = append(counts, group)
+		if group < 2 {
+			continue
+		}
+		for _, zone := range defense_zones(state, size, base, group) {
+			zone.Index = len(zones)
+			zones = append(zones, zone)
+		}
+	}
+	if layout := fmt.Sprint(counts); layout != z.layout {
+		z.layout = layout
+		z.owners = make(map[int]int)
+	}
+	z.zones = zones
+	owners := make(map[int]int, len(defenders))
+	taken := make(map[int]bool, len(defenders))
+	for _, actor := range defenders {
+		if zone, ok := z.owners[actor.Ident]; ok && zone < len(zones) && zones[zone].base == z.bases[actor.Ident].Coordinates {
+			owners[actor.Ident], taken[zone] = zone, true
+		}
+	}
+	for _, actor := range defenders {
+		if _, ok := owners[actor.Ident]; ok {
+			continue
+		}
+		best := -1
+		for _, zone := range z.zones {
+			if zone.base != z.bases[actor.Ident].Coordinates || taken[zone.Index] {
+				continue
+			}
+			if best < 0 || distance(actor.Coordinates, zone.Post) < distance(actor.Coordinates, z.zones[best].Post) {
+				best = zone.Index
+			}
+		}
+		if best >= 0 {
+			owners[actor.Ident], taken[best] = best, true
+		}
+	}
+	z.owners = owners
+}
+
+// Base is the base the defender actor guards, false for actors that do
+// not defend.
+func (z *ZoneDefense) Base(actor Actor) (Base, bool) {
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+	base, ok := z.bases[actor.Ident]
+	return base, ok
+}
+
+// Guard tells the defender actor what to do in its zone: go for the enemy
+// closest to it in the zone, or else return to the post. An enemy it went
+// for leaves the zone only once it is out of reach, then the defender of the
+// zone it crossed into takes over. It reports false for actors without a
+// zone.
+func (z *ZoneDefense) Guard(actor Actor, state GameState) (DefenseZone, Actor, bool, bool) {
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+	index, ok := z.owners[actor.Ident]
+	if !ok {
+		return DefenseZone{}, Actor{}, false, false
+	}
+	zone := z.zones[index]
+	previous, chasing := z.targets[actor.Ident]
+	delete(z.targets, actor.Ident)
+	var target Actor
+	found := false
+	for _, enemy := range filter_objects(state.Actors, false) {
+		d := distance(actor.Coordinates, enemy.Coordinates)
+		held := chasing && previous == pincer_key{enemy.Team, enemy.Ident} && d <= 2
+		if !held && (!zone.cells[enemy.Coordinates] || float64(distance(enemy.Coordinates, zone.Post)) > Params.GuardRadius) {
+			continue
+		}
+		if !found || d < distance(actor.Coordinates, target.Coordinates) {
+			target, found = enemy, true
+		}
+	}
+	if found {
+		z.targets[actor.Ident] = pincer_key{target.Team, target.Ident}
+	}
+	return zone, target, found, true
+}
+
+// assign_zones divides our half among the actors assigned to defend.
+func assign_zones(state GameState, assignments []UtilityAssignment) {
+	defenders := make([]Actor, 0)
+	for _, assignment := range assignments {
+		if assignment.Option.Kind == "defend" {
+			defenders = append(defenders, assignment.Actor)
+		}
+	}
+	Zones.Assign(state, defenders)
+}
+
+// guard_zone is guard_base for a defender with a zone.
+func guard_zone(actor Actor, zone DefenseZone, intruder Actor, engage bool, orders []Order) []Order {
+	if engage {
+		why := fmt.Sprintf("intruder %s actor %d in defense zone %d", intruder.Team, intruder.Ident, zone.Index)
+		return seek_target(actor, intruder, "attack", why, orders)
+	}
+	d := distance(actor.Coordinates, zone.Post)
+	if d == 0 {
+		return orders
+	}
+	direction := find_path(actor.Coordinates, zone.Post)
+	if d > 1 {
+		direction = Plans.Direction(actor, zone.Post)
+	}
+	reason := describe_position(fmt.Sprintf("returning to post of defense zone %d", zone.Index), zone.Post, d)
+	return append(orders, Order{"move", actor.Ident, direction, reason, PriorityOpportunistic})
+}
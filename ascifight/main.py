@@ -2,7 +2,8 @@ import logging, logging.config, logging.handlers
 import asyncio
 import os
 
-from fastapi import FastAPI
+from fastapi import FastAPI, Request
+from fastapi.middleware.gzip import GZipMiddleware
 from fastapi.staticfiles import StaticFiles
 
 import structlog
@@ -80,6 +81,25 @@ app = FastAPI(
     },
 )
 
+app.add_middleware(GZipMiddleware, minimum_size=1000)
+
+
+@app.middleware("http")
+async def correlate_requests(request: Request, call_next):
+    request_id = request.headers.get("X-Request-ID")
+    response = await call_next(request)
+    if request_id is not None:
+        response.headers["X-Request-ID"] = request_id
+        if request.method == "POST":
+            logger.info(
+                "Order request.",
+                path=request.url.path,
+                status=response.status_code,
+                request_id=request_id,
+                user_agent=request.headers.get("User-Agent"),
+            )
+    return response
+
 app.include_router(orders.router)
 app.include_router(states.router)
 app.include_router(other.router)
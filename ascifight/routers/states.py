@@ -1,6 +1,7 @@
 import datetime
+import hashlib
 
-from fastapi import APIRouter
+from fastapi import APIRouter, Request, Response
 from pydantic import BaseModel, Field
 
 import ascifight.config as config
@@ -109,9 +110,13 @@ router = APIRouter(
 
 
 @router.get("/game_state")
-async def get_game_state() -> StateResponse:
-    """Get the current state of the game including locations of all actors, flags, bases and walls."""
-    return StateResponse(
+async def get_game_state(request: Request, response: Response) -> StateResponse:
+    """Get the current state of the game including locations of all actors, flags, bases and walls.
+
+    The response carries an ETag over the board, excluding tick and timing. Send it back as
+    If-None-Match to get an empty 304 response while nothing on the board changed.
+    """
+    state = StateResponse(
         teams=[team.name for team in globals.my_game.board.teams],
         actors=[
             ActorDescription(
@@ -136,6 +141,12 @@ async def get_game_state() -> StateResponse:
         tick=globals.my_game.tick,
         time_of_next_execution=globals.time_of_next_execution,
     )
+    board = state.json(exclude={"tick", "time_of_next_execution"})
+    etag = '"' + hashlib.sha1(board.encode("utf8")).hexdigest() + '"'
+    if request.headers.get("if-none-match") == etag:
+        return Response(status_code=304, headers={"ETag": etag})  # type: ignore
+    response.headers["ETag"] = etag
+    return state
 
 
 @router.get("/scores")